@@ -0,0 +1,74 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"sort"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// ProcessedKey is the field key used to list the processing layers that
+// modified an entry.
+const ProcessedKey = "_processed"
+
+// The names of the processing layers that annotate entries.
+const (
+	ProcessedSampled   = "sampled"
+	ProcessedTruncated = "truncated"
+)
+
+// Annotate returns a copy of fields with a marker recording that the named
+// processing layer acted on the entry. Markers are collected by Core.Write and
+// encoded as a single ProcessedKey field listing the layers; cores that do not
+// understand the markers skip them.
+func Annotate(fields []zapcore.Field, layer string) []zapcore.Field {
+	marker := zapcore.Field{Key: ProcessedKey, Type: zapcore.SkipType, String: layer}
+	return append(fields[:len(fields):len(fields)], marker)
+}
+
+func isAnnotation(f zapcore.Field) bool {
+	return f.Type == zapcore.SkipType && f.Key == ProcessedKey
+}
+
+// extractAnnotations removes the annotation markers from fields and returns the
+// remaining fields and the annotated layer names. The fields are returned as is
+// when they contain no markers.
+func extractAnnotations(fields []zapcore.Field) ([]zapcore.Field, []string) {
+	i := 0
+	for i < len(fields) && !isAnnotation(fields[i]) {
+		i++
+	}
+	if i == len(fields) {
+		return fields, nil
+	}
+
+	remaining := append(make([]zapcore.Field, 0, len(fields)), fields[:i]...)
+	var layers []string
+	for _, f := range fields[i:] {
+		if isAnnotation(f) {
+			layers = append(layers, f.String)
+			continue
+		}
+		remaining = append(remaining, f)
+	}
+	return remaining, layers
+}
+
+// processedField returns the field that lists the unique layers in sorted
+// order.
+func processedField(layers []string) zapcore.Field {
+	sort.Strings(layers)
+	unique := layers[:0]
+	for i, l := range layers {
+		if i == 0 || l != layers[i-1] {
+			unique = append(unique, l)
+		}
+	}
+	return zap.Strings(ProcessedKey, unique)
+}
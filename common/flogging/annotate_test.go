@@ -0,0 +1,96 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/common/flogging/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestAnnotate(t *testing.T) {
+	fields := make([]zapcore.Field, 1, 4)
+	fields[0] = zap.String("key", "value")
+
+	annotated := flogging.Annotate(fields, "redacted")
+	assert.Len(t, annotated, 2)
+	assert.Equal(t, zapcore.SkipType, annotated[1].Type)
+
+	// the caller's backing array must not be modified
+	annotated = flogging.Annotate(fields, "sampled")
+	assert.Equal(t, "sampled", annotated[1].String)
+	assert.Equal(t, []zapcore.Field{zap.String("key", "value")}, fields[:1])
+	assert.Equal(t, zapcore.Field{}, fields[:2][1])
+}
+
+func TestCoreAnnotations(t *testing.T) {
+	output := &sw{}
+	observer := &mock.Observer{}
+	core := newTestConsoleCore(t, "debug", output)
+	core.Observer = observer
+	core.MaxFields = 2
+	core.Annotate = true
+	sampler := flogging.NewSamplingCore(core, "reqid", 1)
+	sampler.Annotate = true
+	logger := zap.New(sampler).Named("annotated")
+
+	var tests = []struct {
+		name     string
+		fields   []zapcore.Field
+		expected string
+	}{
+		{
+			name:     "Untouched",
+			fields:   []zapcore.Field{zap.Int("a", 1)},
+			expected: "[annotated] INFO message a=1\n",
+		},
+		{
+			name:     "Sampled",
+			fields:   []zapcore.Field{zap.String("reqid", "request-1")},
+			expected: "[annotated] INFO message reqid=request-1 _processed=[sampled]\n",
+		},
+		{
+			name:     "Truncated",
+			fields:   []zapcore.Field{zap.Int("a", 1), zap.Int("b", 2), zap.Int("c", 3)},
+			expected: "[annotated] INFO message a=1 b=2 fields_truncated=1 _processed=[truncated]\n",
+		},
+		{
+			name:     "SampledAndTruncated",
+			fields:   []zapcore.Field{zap.String("reqid", "request-1"), zap.Int("a", 1), zap.Int("b", 2)},
+			expected: "[annotated] INFO message reqid=request-1 a=1 fields_truncated=1 _processed=[sampled,truncated]\n",
+		},
+		{
+			name:     "Custom",
+			fields:   flogging.Annotate(flogging.Annotate([]zapcore.Field{zap.Int("a", 1)}, "redacted"), "redacted"),
+			expected: "[annotated] INFO message a=1 _processed=[redacted]\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			output.Reset()
+			logger.Info("message", tc.fields...)
+			assert.Equal(t, tc.expected, output.String())
+		})
+	}
+	assert.Equal(t, len(tests), observer.WriteEntryCallCount())
+}
+
+func TestCoreAnnotationsDisabled(t *testing.T) {
+	output := &sw{}
+	core := newTestConsoleCore(t, "debug", output)
+	core.MaxFields = 1
+	sampler := flogging.NewSamplingCore(core, "reqid", 1)
+	logger := zap.New(sampler).Named("plain")
+
+	logger.Info("message", zap.String("reqid", "request-1"), zap.Int("a", 1))
+	assert.Equal(t, "[plain] INFO message reqid=request-1 fields_truncated=1\n", output.String())
+}
@@ -0,0 +1,239 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// A BudgetCore is a zapcore.Core that limits the number of bytes written by a
+// Core over a rolling window of time, such as a day. Once the bytes written in
+// the window reach the budget, entries below ErrorLevel are dropped until
+// enough of the window has elapsed for the oldest bytes to expire. The window
+// is tracked in budgetBuckets increments so bytes expire up to one increment
+// early. Entries at ErrorLevel and above are always written. When the budget
+// is exhausted, a warning entry is written, subject to the levels of the core,
+// to record that entries are being dropped.
+//
+// Cores derived with With share the budget of the core they were derived from.
+type BudgetCore struct {
+	*Core
+
+	budget *logBudget
+}
+
+// NewBudgetCore creates a BudgetCore that allows budget bytes to be written
+// by a copy of the provided core in any window. The bytes written to the
+// core's Output, or to the outputs of its Sinks, are counted against the
+// budget. The output of a Delegate cannot be observed so the size of the
+// entries written to it, encoded as JSON, is counted instead.
+func NewBudgetCore(core *Core, budget int64, window time.Duration) *BudgetCore {
+	b := &logBudget{
+		limit:  budget,
+		window: window,
+		now:    time.Now,
+	}
+
+	metered := *core
+	if metered.Delegate != nil {
+		metered.Delegate = &meteredCore{
+			Core:   metered.Delegate,
+			enc:    zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()),
+			budget: b,
+		}
+	}
+	if metered.Output != nil {
+		metered.Output = &meteredSyncer{WriteSyncer: metered.Output, budget: b}
+	}
+	if len(metered.Sinks) > 0 {
+		metered.Sinks = make([]Sink, len(core.Sinks))
+		for i, sink := range core.Sinks {
+			metered.Sinks[i] = Sink{
+				Encoding: sink.Encoding,
+				Output:   &meteredSyncer{WriteSyncer: sink.Output, budget: b},
+			}
+		}
+	}
+
+	return &BudgetCore{Core: &metered, budget: b}
+}
+
+func (b *BudgetCore) With(fields []zapcore.Field) zapcore.Core {
+	return &BudgetCore{
+		Core:   b.Core.With(fields).(*Core),
+		budget: b.budget,
+	}
+}
+
+func (b *BudgetCore) Check(e zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return checkWrapped(b.Core, e, ce, b, b.write)
+}
+
+func (b *BudgetCore) Write(e zapcore.Entry, fields []zapcore.Field) error {
+	return b.write(e, fields, b.Core.Write)
+}
+
+func (b *BudgetCore) write(e zapcore.Entry, fields []zapcore.Field, next writeFunc) error {
+	if e.Level >= zapcore.ErrorLevel {
+		return next(e, fields)
+	}
+
+	exhausted, warn := b.budget.exhausted()
+	if !exhausted {
+		return next(e, fields)
+	}
+	atomic.AddUint64(&b.budget.dropped, 1)
+	if !warn {
+		return nil
+	}
+	warning := zapcore.Entry{
+		Level:      zapcore.WarnLevel,
+		Time:       e.Time,
+		LoggerName: e.LoggerName,
+		Message:    "Log budget exhausted; dropping entries below ERROR until the budget window ends",
+	}
+	return writeChecked(b.Core.Check(warning, nil), zap.Int64("budget_bytes", b.budget.limit))
+}
+
+// Dropped returns the number of entries that have been dropped because the
+// budget was exhausted.
+func (b *BudgetCore) Dropped() uint64 {
+	return atomic.LoadUint64(&b.budget.dropped)
+}
+
+// Spent returns the number of bytes written in the current window.
+func (b *BudgetCore) Spent() int64 {
+	b.budget.mutex.Lock()
+	defer b.budget.mutex.Unlock()
+	b.budget.roll()
+	return b.budget.spent
+}
+
+// SetClock sets the function used to obtain the current time.
+func (b *BudgetCore) SetClock(now func() time.Time) {
+	b.budget.mutex.Lock()
+	b.budget.now = now
+	b.budget.current = time.Time{}
+	b.budget.mutex.Unlock()
+}
+
+// budgetBuckets is the number of increments the budget window is divided
+// into.
+const budgetBuckets = 24
+
+type logBudget struct {
+	dropped uint64 // accessed atomically
+
+	limit  int64
+	window time.Duration
+
+	mutex   sync.Mutex
+	now     func() time.Time
+	buckets [budgetBuckets]int64 // bytes written in each increment
+	index   int                  // the bucket of the current increment
+	current time.Time            // start of the current increment
+	spent   int64                // sum of the buckets
+	warned  bool
+}
+
+// roll expires the increments that have left the window. It must be called
+// with the mutex held.
+func (l *logBudget) roll() {
+	width := l.window / budgetBuckets
+	if width <= 0 {
+		width = 1
+	}
+	start := l.now().Truncate(width)
+	if l.current.IsZero() {
+		l.current = start
+		return
+	}
+
+	elapsed := int64(start.Sub(l.current) / width)
+	if elapsed <= 0 {
+		return
+	}
+	if elapsed > budgetBuckets {
+		elapsed = budgetBuckets
+	}
+	for i := int64(0); i < elapsed; i++ {
+		l.index = (l.index + 1) % budgetBuckets
+		l.spent -= l.buckets[l.index]
+		l.buckets[l.index] = 0
+	}
+	l.current = start
+	if l.spent < l.limit {
+		l.warned = false
+	}
+}
+
+// exhausted returns true when the budget for the current window has been
+// spent. The second return value is true the first time this happens since
+// the budget was last available.
+func (l *logBudget) exhausted() (exhausted, warn bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.roll()
+	if l.spent < l.limit {
+		return false, false
+	}
+	warn = !l.warned
+	l.warned = true
+	return true, warn
+}
+
+func (l *logBudget) spend(n int) {
+	l.mutex.Lock()
+	l.roll()
+	l.buckets[l.index] += int64(n)
+	l.spent += int64(n)
+	l.mutex.Unlock()
+}
+
+// A meteredCore counts the size of the entries written to a core, encoded as
+// JSON, against a budget.
+type meteredCore struct {
+	zapcore.Core
+	enc    zapcore.Encoder
+	budget *logBudget
+}
+
+func (m *meteredCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := m.enc.Clone()
+	addFields(enc, fields)
+	return &meteredCore{
+		Core:   m.Core.With(fields),
+		enc:    enc,
+		budget: m.budget,
+	}
+}
+
+func (m *meteredCore) Write(e zapcore.Entry, fields []zapcore.Field) error {
+	if buf, err := m.enc.EncodeEntry(e, fields); err == nil {
+		m.budget.spend(buf.Len())
+		buf.Free()
+	}
+	return m.Core.Write(e, fields)
+}
+
+// A meteredSyncer counts the bytes written to a WriteSyncer against a budget.
+type meteredSyncer struct {
+	zapcore.WriteSyncer
+	budget *logBudget
+}
+
+func (m *meteredSyncer) Write(b []byte) (int, error) {
+	n, err := m.WriteSyncer.Write(b)
+	m.budget.spend(n)
+	return n, err
+}
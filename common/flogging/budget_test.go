@@ -0,0 +1,156 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestBudgetCore(t *testing.T) {
+	output := &sw{}
+	core := flogging.NewBudgetCore(newTestConsoleCore(t, "info", output), 100, 24*time.Hour)
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	core.SetClock(clock.Now)
+	logger := zap.New(core).Named("budget")
+
+	// each line is 22 bytes so five lines fit in the budget
+	for i := 0; i < 10; i++ {
+		logger.Info(fmt.Sprintf("line-%02d", i))
+	}
+	logger.Error("critical")
+	logger.Info("dropped")
+
+	lines := strings.Split(strings.TrimSuffix(output.String(), "\n"), "\n")
+	assert.Equal(t, []string{
+		"[budget] INFO line-00",
+		"[budget] INFO line-01",
+		"[budget] INFO line-02",
+		"[budget] INFO line-03",
+		"[budget] INFO line-04",
+		"[budget] WARN Log budget exhausted; dropping entries below ERROR until the budget window ends budget_bytes=100",
+		"[budget] ERROR critical",
+	}, lines)
+	assert.Equal(t, uint64(6), core.Dropped())
+
+	// the budget does not reset before the window ends
+	clock.Advance(23 * time.Hour)
+	output.Reset()
+	logger.Warn("dropped")
+	assert.Empty(t, output.String())
+	assert.Equal(t, uint64(7), core.Dropped())
+
+	// entries are written again once the window rolls
+	clock.Advance(time.Hour)
+	assert.Equal(t, int64(0), core.Spent())
+	logger.Info("line-10")
+	assert.Equal(t, "[budget] INFO line-10\n", output.String())
+	assert.Equal(t, int64(22), core.Spent())
+	for i := 0; i < 10; i++ {
+		logger.Info("line-11")
+	}
+	assert.Equal(t, 1, strings.Count(output.String(), "budget exhausted"), "the warning is repeated once per window")
+}
+
+func TestBudgetCoreRollingWindow(t *testing.T) {
+	output := &sw{}
+	core := flogging.NewBudgetCore(newTestConsoleCore(t, "info", output), 66, 24*time.Hour)
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	core.SetClock(clock.Now)
+	logger := zap.New(core).Named("budget")
+
+	// each line is 22 bytes
+	logger.Info("line-00")
+	clock.Advance(12 * time.Hour)
+	logger.Info("line-01")
+	logger.Info("line-02")
+	assert.Equal(t, int64(66), core.Spent())
+
+	// only the bytes written more than a window ago expire
+	clock.Advance(12 * time.Hour)
+	assert.Equal(t, int64(44), core.Spent())
+	logger.Info("line-03")
+	assert.Equal(t, int64(66), core.Spent())
+	clock.Advance(12 * time.Hour)
+	assert.Equal(t, int64(22), core.Spent())
+
+	assert.Equal(t, "[budget] INFO line-00\n[budget] INFO line-01\n[budget] INFO line-02\n[budget] INFO line-03\n", output.String())
+	assert.Equal(t, uint64(0), core.Dropped())
+}
+
+func TestBudgetCoreWarningLevels(t *testing.T) {
+	output := &sw{}
+	base := newTestConsoleCore(t, "info", output)
+	base.LevelEnabler = zap.LevelEnablerFunc(func(l zapcore.Level) bool { return l != zapcore.WarnLevel })
+	core := flogging.NewBudgetCore(base, 16, time.Hour)
+	core.SetClock((&fakeClock{now: time.Unix(1000, 0)}).Now)
+
+	logger := zap.New(core)
+	logger.Info("message")
+	logger.Info("dropped")
+	assert.Equal(t, "[] INFO message\n", output.String(), "the warning must not be written when WARN is disabled")
+	assert.Equal(t, uint64(1), core.Dropped())
+}
+
+func TestBudgetCoreDelegate(t *testing.T) {
+	levels := &flogging.LoggerLevels{}
+	err := levels.ActivateSpec("info")
+	assert.NoError(t, err)
+	delegate, logs := observer.New(zapcore.DebugLevel)
+	core := flogging.NewBudgetCore(&flogging.Core{
+		LevelEnabler: levels,
+		Levels:       levels,
+		Delegate:     delegate,
+	}, 1000, time.Hour)
+
+	logger := zap.New(core).With(zap.String("key", "value"))
+	logger.Info("message")
+	assert.Equal(t, 1, logs.Len())
+	assert.True(t, core.Spent() > int64(len("message")), "entries written to the delegate must be counted")
+}
+
+func TestBudgetCoreWith(t *testing.T) {
+	output := &sw{}
+	core := flogging.NewBudgetCore(newTestConsoleCore(t, "info", output), 30, time.Hour)
+	core.SetClock((&fakeClock{now: time.Unix(1000, 0)}).Now)
+
+	logger := zap.New(core)
+	derived := logger.With(zap.String("key", "value"))
+	derived.Info("derived")
+	logger.Info("parent")
+	derived.Info("dropped")
+	logger.Debug("disabled")
+
+	assert.Equal(t, "[] INFO derived key=value\n"+
+		"[] INFO parent\n"+
+		"[] WARN Log budget exhausted; dropping entries below ERROR until the budget window ends key=value budget_bytes=30\n",
+		output.String(),
+	)
+	assert.Equal(t, uint64(1), core.Dropped())
+}
+
+func TestBudgetCoreSinks(t *testing.T) {
+	first, second := &sw{}, &sw{}
+	base := newTestConsoleCore(t, "info", first)
+	base.Sinks = []flogging.Sink{
+		{Encoding: flogging.CONSOLE, Output: first},
+		{Encoding: flogging.CONSOLE, Output: second},
+	}
+	core := flogging.NewBudgetCore(base, 1000, time.Hour)
+
+	zap.New(core).Info("message")
+	assert.Equal(t, int64(2*len("[] INFO message\n")), core.Spent())
+	assert.Equal(t, first.String(), second.String())
+}
@@ -0,0 +1,97 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap/zapcore"
+)
+
+// A writeFunc writes an entry and its fields.
+type writeFunc func(zapcore.Entry, []zapcore.Field) error
+
+// A wrappedWrite is the Write implementation of a core that wraps another
+// core. It decides whether and how the entry is written and calls next to
+// write it to the wrapped core.
+type wrappedWrite func(e zapcore.Entry, fields []zapcore.Field, next writeFunc) error
+
+// checkWrapped is used by the Check methods of cores that wrap another core
+// and need the entry fields, which are not available until Write, to decide
+// whether the entry is written. The entry is checked by the wrapped core and,
+// when the wrapped core would write it, a core that calls write with the
+// entry and its fields is added to ce.
+//
+// The entry checked by the wrapped core is retained so next writes the entry
+// to the cores selected by the wrapped core, such as the enabled children of a
+// tee, at the level they accepted it.
+func checkWrapped(wrapped zapcore.Core, e zapcore.Entry, ce *zapcore.CheckedEntry, wrapper zapcore.Core, write wrappedWrite) *zapcore.CheckedEntry {
+	if !wrapped.Enabled(e.Level) {
+		return ce
+	}
+	checked := wrapped.Check(e, nil)
+	if checked == nil {
+		return ce
+	}
+	return ce.AddCore(e, &checkedCore{Core: wrapper, checked: checked, write: write})
+}
+
+// A checkedCore holds an entry checked by a wrapped core until the entry is
+// written. It is only used for a single entry.
+type checkedCore struct {
+	zapcore.Core
+	checked *zapcore.CheckedEntry
+	write   wrappedWrite
+}
+
+// Write provides the entry accepted by the wrapped core to the wrapper.
+func (c *checkedCore) Write(_ zapcore.Entry, fields []zapcore.Field) error {
+	if c.checked == nil {
+		return nil
+	}
+	return c.write(c.checked.Entry, fields, c.next)
+}
+
+// next writes the entry to the cores of the checked entry.
+func (c *checkedCore) next(e zapcore.Entry, fields []zapcore.Field) error {
+	checked := c.checked
+	if checked == nil {
+		return nil
+	}
+	c.checked = nil
+
+	checked.Entry = e
+	return writeChecked(checked, fields...)
+}
+
+// writeChecked writes a checked entry that is not associated with a logger.
+// The checked entry reports write errors to its ErrorOutput, which is
+// captured so the error is returned instead.
+func writeChecked(ce *zapcore.CheckedEntry, fields ...zapcore.Field) error {
+	if ce == nil {
+		return nil
+	}
+	errs := &writeErrors{}
+	ce.ErrorOutput = errs
+	ce.Write(fields...)
+	return errs.err
+}
+
+// writeErrors captures the write errors reported by a CheckedEntry.
+type writeErrors struct{ err error }
+
+func (w *writeErrors) Write(b []byte) (int, error) {
+	msg := strings.TrimSuffix(string(b), "\n")
+	if i := strings.Index(msg, " write error: "); i >= 0 {
+		msg = msg[i+len(" write error: "):]
+	}
+	w.err = errors.New(msg)
+	return len(b), nil
+}
+
+func (w *writeErrors) Sync() error { return nil }
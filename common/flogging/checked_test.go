@@ -0,0 +1,88 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestWrappedCoresPreserveCheckedCores(t *testing.T) {
+	tests := []struct {
+		name string
+		wrap func(zapcore.Core) zapcore.Core
+	}{
+		{name: "LineRateCore", wrap: func(c zapcore.Core) zapcore.Core {
+			return flogging.NewLineRateCore(c, 0, 1, flogging.DropExcessLines)
+		}},
+		{name: "SamplingCore", wrap: func(c zapcore.Core) zapcore.Core {
+			return flogging.NewSamplingCore(c, "request_id", 1)
+		}},
+		{name: "LevelSamplingCore", wrap: func(c zapcore.Core) zapcore.Core {
+			l := flogging.NewLevelSamplingCore(c, 1)
+			l.Level = zapcore.FatalLevel
+			return l
+		}},
+		{name: "EntryIDCore", wrap: func(c zapcore.Core) zapcore.Core {
+			e := flogging.NewEntryIDCore(c)
+			e.Keys = []string{"request_id"}
+			return e
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, warn, errorOutput := &sw{}, &sw{}, &sw{}
+			tee := zapcore.NewTee(newTestConsoleCore(t, "info", info), newTestConsoleCore(t, "warn", warn))
+			logger := zap.New(tt.wrap(tee), zap.ErrorOutput(errorOutput))
+
+			logger.Info("info", zap.String("request_id", "id"))
+			assert.Equal(t, "[] INFO info request_id=id\n", info.String())
+			assert.Empty(t, warn.String(), "the entry must only be written to the cores that enabled it")
+
+			// errors from the wrapped cores are returned to the logger
+			warn.writeErr = errors.New("broken")
+			logger.Warn("warn", zap.String("request_id", "id"))
+			assert.Contains(t, errorOutput.String(), " write error: broken\n")
+		})
+	}
+}
+
+func TestWrappedCoresPreserveCheckedLevel(t *testing.T) {
+	levels, err := flogging.NewMessageLevels(map[string]zapcore.Level{"WARNING:": zapcore.WarnLevel})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		wrap func(*flogging.Core) zapcore.Core
+	}{
+		{name: "BudgetCore", wrap: func(c *flogging.Core) zapcore.Core {
+			return flogging.NewBudgetCore(c, 1024, time.Hour)
+		}},
+		{name: "FieldRoutingCore", wrap: func(c *flogging.Core) zapcore.Core {
+			return flogging.NewFieldRoutingCore(c, "channel", nil)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := &sw{}
+			core := newTestConsoleCore(t, "info", output)
+			core.MessageLevels = levels
+
+			zap.New(tt.wrap(core)).Info("WARNING: reclassified")
+			assert.Equal(t, "[] WARN WARNING: reclassified\n", output.String())
+		})
+	}
+}
@@ -0,0 +1,49 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type loggerKey struct{}
+
+type channelKey struct{}
+
+// NewContext returns a copy of ctx that carries the logger. Loggers derived
+// with With for request scoped fields can be stored once and retrieved with
+// FromContext, which avoids cloning the encoders for every call.
+func NewContext(ctx context.Context, logger *FabricLogger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext returns the logger stored in ctx by NewContext. The returned
+// bool is false when ctx does not carry a logger.
+func FromContext(ctx context.Context) (*FabricLogger, bool) {
+	logger, ok := ctx.Value(loggerKey{}).(*FabricLogger)
+	return logger, ok && logger != nil
+}
+
+// NewChannelContext returns a copy of ctx that carries the id of the channel
+// an operation is scoped to.
+func NewChannelContext(ctx context.Context, channelID string) context.Context {
+	return context.WithValue(ctx, channelKey{}, channelID)
+}
+
+// WithChannel returns a channel field for the channel id stored in ctx by
+// NewChannelContext. No fields are returned when ctx does not carry a channel
+// id or the id is empty.
+func WithChannel(ctx context.Context) []zapcore.Field {
+	channelID, _ := ctx.Value(channelKey{}).(string)
+	if channelID == "" {
+		return nil
+	}
+	return []zapcore.Field{zap.String("channel", channelID)}
+}
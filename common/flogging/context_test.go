@@ -0,0 +1,88 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestContextLogger(t *testing.T) {
+	_, ok := flogging.FromContext(context.Background())
+	assert.False(t, ok)
+
+	buf := &bytes.Buffer{}
+	logging, err := flogging.New(flogging.Config{Format: "%{message}", Writer: buf})
+	require.NoError(t, err)
+	logger := logging.Logger("context").With("request", "r1")
+
+	ctx := flogging.NewContext(context.Background(), logger)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stored, ok := flogging.FromContext(ctx)
+	assert.True(t, ok)
+	assert.Same(t, logger, stored)
+
+	stored.Info("handled")
+	assert.Equal(t, "handled request=r1\n", buf.String())
+
+	_, ok = flogging.FromContext(flogging.NewContext(context.Background(), nil))
+	assert.False(t, ok)
+}
+
+func TestWithChannel(t *testing.T) {
+	assert.Empty(t, flogging.WithChannel(context.Background()))
+	assert.Empty(t, flogging.WithChannel(flogging.NewChannelContext(context.Background(), "")))
+
+	ctx := flogging.NewChannelContext(context.Background(), "testchannel")
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	assert.Equal(t, []zap.Field{zap.String("channel", "testchannel")}, flogging.WithChannel(ctx))
+
+	buf := &bytes.Buffer{}
+	logging, err := flogging.New(flogging.Config{Format: "%{message}", Writer: buf})
+	require.NoError(t, err)
+	logger := logging.Logger("context").Zap()
+
+	logger.With(flogging.WithChannel(ctx)...).Info("scoped")
+	logger.With(flogging.WithChannel(context.Background())...).Info("unscoped")
+	assert.Equal(t, "scoped channel=testchannel\nunscoped\n", buf.String())
+}
+
+func newContextBenchmarkLogger(b *testing.B) *flogging.FabricLogger {
+	logging, err := flogging.New(flogging.Config{Format: "json", Writer: ioutil.Discard})
+	require.NoError(b, err)
+	return logging.Logger("benchmark")
+}
+
+func BenchmarkContextLoggerWith(b *testing.B) {
+	logger := newContextBenchmarkLogger(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.With("request", "r1", "channel", "testchannel").Info("handled")
+	}
+}
+
+func BenchmarkContextLoggerCached(b *testing.B) {
+	logger := newContextBenchmarkLogger(b)
+	ctx := flogging.NewContext(context.Background(), logger.With("request", "r1", "channel", "testchannel"))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cached, _ := flogging.FromContext(ctx)
+		cached.Info("handled")
+	}
+}
@@ -7,6 +7,13 @@ SPDX-License-Identifier: Apache-2.0
 package flogging
 
 import (
+	"net"
+	"os"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
@@ -16,10 +23,15 @@ const (
 	CONSOLE = iota
 	JSON
 	LOGFMT
+	CBOR
+	DETERMINISTIC_JSON
+	ECS
+	MSGPACK
 )
 
-// EncodingSelector is used to determine whether log records are
-// encoded as JSON or in human readable CONSOLE or LOGFMT formats.
+// EncodingSelector is used to determine whether log records are encoded as
+// JSON, key ordered DETERMINISTIC_JSON, Elastic Common Schema ECS JSON, CBOR,
+// MSGPACK, or in human readable CONSOLE or LOGFMT formats.
 type EncodingSelector interface {
 	Encoding() Encoding
 }
@@ -27,7 +39,8 @@ type EncodingSelector interface {
 // Core is a custom implementation of a zapcore.Core. It's a terrible hack that
 // only exists to work around the intersection of state associated with
 // encoders, implementation hiding in zapcore, and implicit, ad-hoc logger
-// initialization within fabric.
+// initialization within fabric. Entries written before logging is configured
+// should use the core returned by BootstrapCore.
 //
 // In addition to encoding log entries and fields to a buffer, zap Encoder
 // implementations also need to maintain field state. When zapcore.Core.With is
@@ -51,8 +64,132 @@ type Core struct {
 	Selector EncodingSelector
 	Output   zapcore.WriteSyncer
 	Observer Observer
+
+	// ObserveEnabledOnly, when true, prevents the Observer from being
+	// consulted when an entry is checked and will not be written because
+	// its level is disabled.
+	ObserveEnabledOnly bool
+
+	// Transformers are applied in order to every entry written by the core
+	// before it is encoded. The transformed entry and fields are encoded and
+	// provided to the Observer. Fields added with With have already been
+	// encoded and are not provided to the transformers.
+	Transformers []EntryTransformer
+
+	// WithTransformers are applied in order to the fields added with With
+	// before they are encoded with the encoders of the derived core.
+	WithTransformers []FieldsTransformer
+
+	// Sinks, when set, replaces Selector and Output. Each entry is encoded
+	// once for every sink using the encoder for the sink's encoding and the
+	// result is written to the sink's output.
+	Sinks []Sink
+
+	// Delegate, when set, replaces Encoders, Selector, Output, and Sinks.
+	// Entries enabled by the core are written to the Delegate, which encodes
+	// and writes them. The Delegate's level is not consulted.
+	Delegate zapcore.Core
+
+	// Fallback, when set, receives a copy of entries at PanicLevel and above
+	// when Output fails to sync them. This provides a second chance for the
+	// entry to reach durable storage before the process terminates.
+	Fallback zapcore.WriteSyncer
+
+	// Sequence, when set, is used to add a strictly increasing sequence number
+	// to every entry written by the core and the cores derived from it. This
+	// allows consumers to detect entries lost in transit.
+	Sequence *SequenceCounter
+
+	// MaxFields, when greater than zero, limits the number of fields encoded
+	// with each entry, including the fields added with With. Fields beyond
+	// the limit are dropped and a FieldsTruncatedKey field recording the
+	// number of dropped fields is added in their place.
+	MaxFields int
+
+	// Annotate, when true, adds a ProcessedTruncated annotation to entries
+	// that exceeded MaxFields. Annotations added by other processing layers
+	// with the Annotate function are always encoded.
+	Annotate bool
+
+	// DuplicateKeys determines how fields with repeated keys are handled.
+	// Fields added with With have already been encoded so entry fields that
+	// repeat their keys are renamed under both LastKeyWins and
+	// RenameDuplicateKeys. The policy also applies to the fields added by the
+	// core, such as the sequence number.
+	DuplicateKeys DuplicateKeyPolicy
+
+	// MaxWithFields, when greater than zero, limits the number of fields that
+	// can be accumulated by a chain of cores derived with With. Fields beyond
+	// the limit are dropped from With and a warning entry is checked and
+	// written before the first entry written by a core of the chain that
+	// reached the limit, so the warning has the entry's logger name and is
+	// subject to its levels. The warning carries the accumulated fields to
+	// help identify the source. This protects against components that
+	// repeatedly call With on a derived logger.
+	MaxWithFields int
+
+	// OnPanic and OnFatal, when set, are called with entries at PanicLevel
+	// and FatalLevel after they have been written and before the logger
+	// panics or exits the process. A hook may flush external systems or, in
+	// tests, panic or call runtime.Goexit to prevent the process from
+	// exiting.
+	OnPanic func(zapcore.Entry)
+	OnFatal func(zapcore.Entry)
+
+	// MessageLevels, when set, reclassifies entries by the prefix of their
+	// message when they are checked. The reclassified level is used to
+	// determine whether the entry is enabled and is the level of the entry
+	// that is written. Entries provided directly to Write are not
+	// reclassified.
+	MessageLevels *MessageLevels
+
+	withFields    int          // fields added with With
+	withCapped    bool         // MaxWithFields has been reached
+	withWarning   *withWarning // warning for fields dropped from With
+	withTruncated int          // fields dropped from With
+	withKeys      keySet       // keys added with With when DuplicateKeys is set
 }
 
+// A withWarning records that fields were dropped from With because
+// MaxWithFields was reached and whether the warning is yet to be written.
+type withWarning struct {
+	pending int32 // accessed atomically
+	dropped int
+}
+
+// FieldsTruncatedKey is the field key used to report the number of fields
+// dropped from an entry when MaxFields is exceeded.
+const FieldsTruncatedKey = "fields_truncated"
+
+// An EntryTransformer modifies an entry and its fields before they are
+// encoded. Transformers are called concurrently and must not modify the fields
+// slice they are given; a new slice must be returned when the fields change.
+type EntryTransformer func(zapcore.Entry, []zapcore.Field) (zapcore.Entry, []zapcore.Field)
+
+// A FieldsTransformer modifies the fields added with With. Like an
+// EntryTransformer, it must not modify the fields slice it is given.
+type FieldsTransformer func([]zapcore.Field) []zapcore.Field
+
+// A Sink pairs an encoding with the output that receives entries in that
+// encoding.
+type Sink struct {
+	Encoding Encoding
+	Output   zapcore.WriteSyncer
+}
+
+// SequenceKey is the field key used for entry sequence numbers.
+const SequenceKey = "seq"
+
+// A SequenceCounter generates sequence numbers for log entries. It is safe
+// for concurrent use.
+type SequenceCounter struct{ value uint64 }
+
+// NewSequenceCounter creates a SequenceCounter that starts at one.
+func NewSequenceCounter() *SequenceCounter { return &SequenceCounter{} }
+
+// Next returns the next sequence number.
+func (s *SequenceCounter) Next() uint64 { return atomic.AddUint64(&s.value, 1) }
+
 //go:generate counterfeiter -o mock/observer.go -fake-name Observer . Observer
 
 type Observer interface {
@@ -61,65 +198,410 @@ type Observer interface {
 }
 
 func (c *Core) With(fields []zapcore.Field) zapcore.Core {
+	for _, transform := range c.WithTransformers {
+		fields = transform(fields)
+	}
+
+	withCapped, warning := c.withCapped, c.withWarning
+	if c.MaxWithFields > 0 && c.withFields+len(fields) > c.MaxWithFields {
+		keep := c.MaxWithFields - c.withFields
+		if keep < 0 {
+			keep = 0
+		}
+		if !withCapped {
+			warning = &withWarning{pending: 1, dropped: len(fields) - keep}
+			withCapped = true
+		}
+		fields = fields[:keep]
+	}
+
+	withKeys := c.withKeys
+	if c.DuplicateKeys != AllowDuplicateKeys {
+		fields, withKeys = c.DuplicateKeys.dedupe(fields, c.withKeys)
+	}
+
+	withFields, withTruncated := c.withFields, c.withTruncated
+	if c.MaxFields > 0 {
+		fields, withTruncated = c.truncate(fields, c.withFields, withTruncated)
+	}
+	if c.MaxFields > 0 || c.MaxWithFields > 0 {
+		withFields += len(fields)
+	}
+
 	clones := map[Encoding]zapcore.Encoder{}
 	for name, enc := range c.Encoders {
 		clone := enc.Clone()
 		addFields(clone, fields)
 		clones[name] = clone
 	}
+	delegate := c.Delegate
+	if delegate != nil {
+		delegate = delegate.With(fields)
+	}
 
 	return &Core{
-		LevelEnabler: c.LevelEnabler,
-		Levels:       c.Levels,
-		Encoders:     clones,
-		Selector:     c.Selector,
-		Output:       c.Output,
-		Observer:     c.Observer,
+		LevelEnabler:       c.LevelEnabler,
+		Levels:             c.Levels,
+		Encoders:           clones,
+		Selector:           c.Selector,
+		Output:             c.Output,
+		Sinks:              c.Sinks,
+		Delegate:           delegate,
+		Transformers:       c.Transformers,
+		WithTransformers:   c.WithTransformers,
+		Observer:           c.Observer,
+		ObserveEnabledOnly: c.ObserveEnabledOnly,
+		Fallback:           c.Fallback,
+		Sequence:           c.Sequence,
+		MaxFields:          c.MaxFields,
+		Annotate:           c.Annotate,
+		DuplicateKeys:      c.DuplicateKeys,
+		MaxWithFields:      c.MaxWithFields,
+		OnPanic:            c.OnPanic,
+		OnFatal:            c.OnFatal,
+		MessageLevels:      c.MessageLevels,
+
+		withFields:    withFields,
+		withCapped:    withCapped,
+		withWarning:   warning,
+		withTruncated: withTruncated,
+		withKeys:      withKeys,
+	}
+}
+
+// Freeze returns a core that is independent of c. The fields added to c with
+// With are materialized in clones of its encoders and the Encoders map,
+// Transformers, and Sinks are copied, so changes made to either core after
+// Freeze returns, including fields added with With and modifications of the
+// exported fields, do not affect the other. The frozen core continues to
+// share the Levels, Selector, Output, Observer, Sequence, and Delegate of c,
+// which are safe for concurrent use.
+//
+// Freeze is intended for cores provided to long-lived goroutines.
+func (c *Core) Freeze() *Core {
+	frozen := c.With(nil).(*Core)
+	if c.Transformers != nil {
+		frozen.Transformers = append([]EntryTransformer(nil), c.Transformers...)
+	}
+	if c.WithTransformers != nil {
+		frozen.WithTransformers = append([]FieldsTransformer(nil), c.WithTransformers...)
+	}
+	if c.Sinks != nil {
+		frozen.Sinks = append([]Sink(nil), c.Sinks...)
+	}
+	return frozen
+}
+
+// warnWithFieldsCapped checks and writes the warning entry for fields dropped
+// from With because MaxWithFields was reached. The warning is written once,
+// with the logger name of the entry that is about to be written.
+func (c *Core) warnWithFieldsCapped(e zapcore.Entry) error {
+	if c.withWarning == nil || !atomic.CompareAndSwapInt32(&c.withWarning.pending, 1, 0) {
+		return nil
+	}
+	warning := zapcore.Entry{
+		Level:      zapcore.WarnLevel,
+		Time:       e.Time,
+		LoggerName: e.LoggerName,
+		Message:    "Persistent field limit reached; dropping fields added with With",
+	}
+	return writeChecked(c.Check(warning, nil),
+		zap.Int("max_with_fields", c.MaxWithFields),
+		zap.Int("dropped_fields", c.withWarning.dropped),
+	)
+}
+
+// Validate returns an error when an encoding is registered with a nil encoder
+// or when an encoding that can be selected for output has no encoder. Cores
+// that fail validation panic when entries are written.
+func (c *Core) Validate() error {
+	for encoding, enc := range c.Encoders {
+		if enc == nil {
+			return errors.Errorf("nil encoder for encoding %d", encoding)
+		}
+	}
+	if c.Delegate != nil {
+		return nil
+	}
+
+	if len(c.Sinks) > 0 {
+		for _, sink := range c.Sinks {
+			if _, ok := c.Encoders[sink.Encoding]; !ok {
+				return errors.Errorf("no encoder for sink encoding %d", sink.Encoding)
+			}
+			if sink.Output == nil {
+				return errors.Errorf("no output for sink encoding %d", sink.Encoding)
+			}
+		}
+		return nil
+	}
+
+	if c.Selector == nil {
+		return errors.New("no encoding selector")
+	}
+	if encoding := c.Selector.Encoding(); c.Encoders[encoding] == nil {
+		return errors.Errorf("no encoder for selected encoding %d", encoding)
+	}
+	if c.Output == nil {
+		return errors.New("no output")
+	}
+	return nil
+}
+
+// truncate returns the fields that fit within MaxFields when used fields
+// have already been encoded, and the updated count of truncated fields.
+func (c *Core) truncate(fields []zapcore.Field, used, truncated int) ([]zapcore.Field, int) {
+	remaining := c.MaxFields - used
+	if remaining < 0 {
+		remaining = 0
 	}
+	if len(fields) > remaining {
+		truncated += len(fields) - remaining
+		fields = fields[:remaining]
+	}
+	return fields, truncated
+}
+
+// Enabled returns true when entries at the level may be written. When
+// MessageLevels is set, entries at any level may be reclassified to an enabled
+// level so the decision is deferred to Check.
+func (c *Core) Enabled(l zapcore.Level) bool {
+	if c.MessageLevels != nil && c.MessageLevels.enables(c.LevelEnabler) {
+		return true
+	}
+	return c.LevelEnabler.Enabled(l)
 }
 
 func (c *Core) Check(e zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
-	if c.Observer != nil {
+	level := e.Level
+	if c.MessageLevels != nil {
+		e.Level = c.MessageLevels.Level(e)
+	}
+	enabled := c.LevelEnabler.Enabled(e.Level) && c.Levels.Level(e.LoggerName).Enabled(e.Level)
+	if c.Observer != nil && (enabled || !c.ObserveEnabledOnly) {
 		c.Observer.Check(e, ce)
 	}
 
-	if c.Enabled(e.Level) && c.Levels.Level(e.LoggerName).Enabled(e.Level) {
-		return ce.AddCore(e, c)
+	if !enabled {
+		return ce
+	}
+	if e.Level != level {
+		// The checked entry may have been created by another core with the
+		// original level so the reclassified level is carried by the core.
+		return ce.AddCore(e, &reclassifiedCore{Core: c, level: e.Level})
 	}
-	return ce
+	return ce.AddCore(e, c)
+}
+
+// reclassifiedCore writes entries at the level determined by MessageLevels
+// when the entry was checked.
+type reclassifiedCore struct {
+	*Core
+	level zapcore.Level
+}
+
+func (r *reclassifiedCore) Write(e zapcore.Entry, fields []zapcore.Field) error {
+	e.Level = r.level
+	return r.Core.Write(e, fields)
 }
 
 func (c *Core) Write(e zapcore.Entry, fields []zapcore.Field) error {
-	encoding := c.Selector.Encoding()
-	enc := c.Encoders[encoding]
+	werr := c.warnWithFieldsCapped(e)
+	err := c.write(e, fields)
+	if err == nil {
+		err = werr
+	}
+
+	switch {
+	case e.Level == zapcore.PanicLevel && c.OnPanic != nil:
+		c.OnPanic(e)
+	case e.Level == zapcore.FatalLevel && c.OnFatal != nil:
+		c.OnFatal(e)
+	}
+
+	return err
+}
+
+func (c *Core) write(e zapcore.Entry, fields []zapcore.Field) error {
+	for _, transform := range c.Transformers {
+		e, fields = transform(e, fields)
+	}
+
+	encodedFields, layers := extractAnnotations(fields)
+	if c.DuplicateKeys != AllowDuplicateKeys {
+		encodedFields, _ = c.DuplicateKeys.dedupe(encodedFields, c.withKeys)
+	}
+	truncated := 0
+	if c.MaxFields > 0 {
+		encodedFields, truncated = c.truncate(encodedFields, c.withFields, c.withTruncated)
+	}
+	if truncated > 0 && c.Annotate {
+		layers = append(layers, ProcessedTruncated)
+	}
+	synthetic := c.Sequence != nil || truncated > 0 || len(layers) > 0
+	if synthetic {
+		encodedFields = append(make([]zapcore.Field, 0, len(encodedFields)+3), encodedFields...)
+	}
+	if truncated > 0 {
+		encodedFields = append(encodedFields, zap.Int(FieldsTruncatedKey, truncated))
+	}
+	if len(layers) > 0 {
+		encodedFields = append(encodedFields, processedField(layers))
+	}
+	if c.Sequence != nil {
+		encodedFields = append(encodedFields, zap.Uint64(SequenceKey, c.Sequence.Next()))
+	}
+	if synthetic && c.DuplicateKeys != AllowDuplicateKeys {
+		// the fields added by the core may repeat the keys of the entry
+		encodedFields, _ = c.DuplicateKeys.dedupe(encodedFields, c.withKeys)
+	}
+
+	if c.Delegate != nil {
+		if err := c.Delegate.Write(e, encodedFields); err != nil {
+			return err
+		}
+		if c.Observer != nil {
+			c.Observer.WriteEntry(e, fields)
+		}
+		return nil
+	}
+
+	sinks := c.Sinks
+	if len(sinks) == 0 {
+		sinks = []Sink{{Encoding: c.Selector.Encoding(), Output: c.Output}}
+	}
+
+	var err error
+	written := false
+	for _, sink := range sinks {
+		ok, serr := c.writeSink(sink, e, encodedFields)
+		written = written || ok
+		if err == nil {
+			err = serr
+		}
+	}
+
+	if written && c.Observer != nil {
+		c.Observer.WriteEntry(e, fields)
+	}
 
+	return err
+}
+
+// writeSink encodes the entry for the sink and writes it to the sink's output.
+// The returned bool is true when the entry was written, even if it could not
+// be synced.
+func (c *Core) writeSink(sink Sink, e zapcore.Entry, fields []zapcore.Field) (bool, error) {
+	enc, ok := c.Encoders[sink.Encoding]
+	if !ok {
+		return false, errors.Errorf("no encoder for encoding %d", sink.Encoding)
+	}
 	buf, err := enc.EncodeEntry(e, fields)
 	if err != nil {
-		return err
+		return false, err
 	}
-	_, err = c.Output.Write(buf.Bytes())
-	buf.Free()
+	defer buf.Free()
+	_, err = sink.Output.Write(buf.Bytes())
 	if err != nil {
-		return err
+		return false, err
 	}
 
+	// Entries at PanicLevel and above are synced as the process is likely to
+	// terminate. The entry has been written so it is still observed when the
+	// sync fails.
 	if e.Level >= zapcore.PanicLevel {
-		c.Sync()
+		return true, c.syncPanic(sink.Output, buf.Bytes())
 	}
+	return true, nil
+}
 
-	if c.Observer != nil {
-		c.Observer.WriteEntry(e, fields)
+// syncPanic syncs the output and, when that fails, writes the encoded entry to
+// the fallback. The sync error is returned even when the fallback succeeds.
+// Outputs that do not support sync, such as pipes and terminals, are not
+// treated as failures.
+func (c *Core) syncPanic(output zapcore.WriteSyncer, entry []byte) error {
+	err := output.Sync()
+	if err == nil || isSyncUnsupported(err) {
+		return nil
 	}
+	err = errors.Wrap(err, "failed to sync log output")
 
-	return nil
+	if c.Fallback == nil {
+		return err
+	}
+	if _, ferr := c.Fallback.Write(entry); ferr != nil {
+		return errors.WithMessagef(err, "fallback write failed: %s", ferr)
+	}
+	if ferr := c.Fallback.Sync(); ferr != nil {
+		return errors.WithMessagef(err, "fallback sync failed: %s", ferr)
+	}
+	return err
 }
 
+// Sync flushes the output, or the output of every sink when Sinks is set, or
+// the Delegate when it is set.
 func (c *Core) Sync() error {
-	return c.Output.Sync()
+	if c.Delegate != nil {
+		return c.Delegate.Sync()
+	}
+	if len(c.Sinks) == 0 {
+		return c.Output.Sync()
+	}
+
+	var err error
+	for _, sink := range c.Sinks {
+		if serr := sink.Output.Sync(); err == nil {
+			err = serr
+		}
+	}
+	return err
+}
+
+// isSyncUnsupported returns true when err reports that the output cannot be
+// synced. This is the case for os.Stderr when it is a pipe or terminal.
+func isSyncUnsupported(err error) bool {
+	switch errno(err) {
+	case syscall.EINVAL, syscall.ENOTSUP:
+		return true
+	default:
+		return false
+	}
+}
+
+// errno returns the system error number carried by err or zero.
+func errno(err error) syscall.Errno {
+	for {
+		switch e := err.(type) {
+		case *net.OpError:
+			err = e.Err
+		case *os.PathError:
+			err = e.Err
+		case *os.SyscallError:
+			err = e.Err
+		default:
+			n, _ := err.(syscall.Errno)
+			return n
+		}
+	}
+}
+
+// A fieldAdder is an encoder that adds fields itself rather than through
+// their AddTo method, such as the fabenc.FormatEncoder that renders the
+// human readable representation of typed fields.
+type fieldAdder interface {
+	AddField(zapcore.Field)
 }
 
 func addFields(enc zapcore.ObjectEncoder, fields []zapcore.Field) {
 	for i := range fields {
-		fields[i].AddTo(enc)
+		addField(enc, fields[i])
+	}
+}
+
+func addField(enc zapcore.ObjectEncoder, f zapcore.Field) {
+	if fa, ok := enc.(fieldAdder); ok {
+		fa.AddField(f)
+		return
 	}
+	f.AddTo(enc)
 }
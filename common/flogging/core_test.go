@@ -8,15 +8,24 @@ package flogging_test
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/common/flogging/fabenc"
 	"github.com/hyperledger/fabric/common/flogging/mock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 	"go.uber.org/zap/buffer"
 	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 func TestCoreWith(t *testing.T) {
@@ -77,6 +86,7 @@ type sw struct {
 	writeErr   error
 	syncCalled bool
 	syncErr    error
+	mutex      sync.Mutex
 }
 
 func (s *sw) Sync() error {
@@ -85,6 +95,8 @@ func (s *sw) Sync() error {
 }
 
 func (s *sw) Write(b []byte) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 	if s.writeErr != nil {
 		return 0, s.writeErr
 	}
@@ -151,6 +163,84 @@ func TestCoreWriteSync(t *testing.T) {
 	assert.True(t, output.syncCalled)
 }
 
+func TestCoreWriteSyncFailure(t *testing.T) {
+	encoderConfig := zap.NewDevelopmentEncoderConfig()
+	encoderConfig.EncodeTime = nil
+
+	output := &sw{syncErr: errors.New("bummer")}
+	observer := &mock.Observer{}
+	core := &flogging.Core{
+		Encoders: map[flogging.Encoding]zapcore.Encoder{
+			flogging.CONSOLE: zapcore.NewConsoleEncoder(encoderConfig),
+		},
+		Selector: output,
+		Output:   output,
+		Observer: observer,
+	}
+
+	// sync errors are ignored below the panic level
+	err := core.Write(zapcore.Entry{Level: zapcore.ErrorLevel, Message: "error"}, nil)
+	assert.NoError(t, err)
+
+	entry := zapcore.Entry{Level: zapcore.PanicLevel, Message: "gah!"}
+	err = core.Write(entry, nil)
+	assert.EqualError(t, err, "failed to sync log output: bummer")
+	assert.Equal(t, "ERROR\terror\nPANIC\tgah!\n", output.String())
+	assert.Equal(t, 2, observer.WriteEntryCallCount(), "written entries must be observed")
+
+	fallback := &sw{}
+	core.Fallback = fallback
+	err = core.Write(entry, nil)
+	assert.EqualError(t, err, "failed to sync log output: bummer")
+	assert.Equal(t, "PANIC\tgah!\n", fallback.String())
+	assert.True(t, fallback.syncCalled)
+
+	fallback.writeErr = errors.New("fallback broken")
+	err = core.Write(entry, nil)
+	assert.EqualError(t, err, "fallback write failed: fallback broken: failed to sync log output: bummer")
+
+	fallback.writeErr = nil
+	fallback.syncErr = errors.New("fallback unsynced")
+	err = core.Write(entry, nil)
+	assert.EqualError(t, err, "fallback sync failed: fallback unsynced: failed to sync log output: bummer")
+
+	// the fallback is not used when the output syncs
+	fallback.Reset()
+	output.syncErr = nil
+	err = core.Write(entry, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, fallback.String())
+}
+
+func TestCoreWritePanicToPipe(t *testing.T) {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	defer r.Close()
+	defer w.Close()
+
+	encoderConfig := zap.NewDevelopmentEncoderConfig()
+	encoderConfig.EncodeTime = nil
+	fallback := &sw{}
+	core := &flogging.Core{
+		Encoders: map[flogging.Encoding]zapcore.Encoder{
+			flogging.CONSOLE: zapcore.NewConsoleEncoder(encoderConfig),
+		},
+		Selector: &sw{},
+		Output:   w,
+		Fallback: fallback,
+	}
+
+	// pipes cannot be synced; that must not be reported as a failure
+	err = core.Write(zapcore.Entry{Level: zapcore.PanicLevel, Message: "gah!"}, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, fallback.String())
+
+	buf := make([]byte, 64)
+	n, err := r.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "PANIC\tgah!\n", string(buf[:n]))
+}
+
 type brokenEncoder struct{ zapcore.Encoder }
 
 func (b *brokenEncoder) EncodeEntry(zapcore.Entry, []zapcore.Field) (*buffer.Buffer, error) {
@@ -216,6 +306,42 @@ func TestObserverCheck(t *testing.T) {
 	assert.Equal(t, ce, observedCE)
 }
 
+func TestObserverCheckDisabled(t *testing.T) {
+	levels := &flogging.LoggerLevels{}
+	err := levels.ActivateSpec("info:disabled=error")
+	assert.NoError(t, err)
+
+	var tests = []struct {
+		name               string
+		observeEnabledOnly bool
+		expectedCalls      int
+	}{
+		{name: "Default", observeEnabledOnly: false, expectedCalls: 2},
+		{name: "EnabledOnly", observeEnabledOnly: true, expectedCalls: 1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			observer := &mock.Observer{}
+			core := &flogging.Core{
+				LevelEnabler:       levels,
+				Levels:             levels,
+				Observer:           observer,
+				ObserveEnabledOnly: tc.observeEnabledOnly,
+			}
+			derived := core.With(nil)
+
+			ce := derived.Check(zapcore.Entry{LoggerName: "disabled", Level: zapcore.WarnLevel}, nil)
+			assert.Nil(t, ce)
+			ce = derived.Check(zapcore.Entry{LoggerName: "enabled", Level: zapcore.WarnLevel}, nil)
+			assert.NotNil(t, ce)
+
+			assert.Equal(t, tc.expectedCalls, observer.CheckCallCount())
+			entry, _ := observer.CheckArgsForCall(tc.expectedCalls - 1)
+			assert.Equal(t, "enabled", entry.LoggerName)
+		})
+	}
+}
+
 func TestObserverWriteEntry(t *testing.T) {
 	observer := &mock.Observer{}
 	entry := zapcore.Entry{
@@ -251,3 +377,512 @@ func TestObserverWriteEntry(t *testing.T) {
 	assert.Equal(t, entry, observedEntry)
 	assert.Equal(t, fields, observedFields)
 }
+
+// fixedSelector is the EncodingSelector shared by the tests that need a core
+// with a fixed encoding.
+type fixedSelector flogging.Encoding
+
+func (f fixedSelector) Encoding() flogging.Encoding { return flogging.Encoding(f) }
+
+func TestCoreSequence(t *testing.T) {
+	buf := &bytes.Buffer{}
+	core := &flogging.Core{
+		LevelEnabler: zapcore.DebugLevel,
+		Levels:       &flogging.LoggerLevels{},
+		Encoders: map[flogging.Encoding]zapcore.Encoder{
+			flogging.JSON: zapcore.NewJSONEncoder(zapcore.EncoderConfig{MessageKey: "msg"}),
+		},
+		Selector: fixedSelector(flogging.JSON),
+		Output:   zapcore.Lock(zapcore.AddSync(buf)),
+		Sequence: flogging.NewSequenceCounter(),
+	}
+	derived := core.With([]zapcore.Field{zap.String("derived", "true")})
+
+	const goroutines, entries = 10, 100
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		c := zapcore.Core(core)
+		if i%2 == 0 {
+			c = derived
+		}
+		go func(c zapcore.Core, g int) {
+			defer wg.Done()
+			for j := 0; j < entries; j++ {
+				fields := []zapcore.Field{zap.Int("goroutine", g)}
+				err := c.Write(zapcore.Entry{Message: "message"}, fields)
+				assert.NoError(t, err)
+				assert.Len(t, fields, 1, "caller fields must not be modified")
+			}
+		}(c, i)
+	}
+	wg.Wait()
+
+	var seqs []int
+	last := map[int]int{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record struct {
+			Goroutine int `json:"goroutine"`
+			Seq       int `json:"seq"`
+		}
+		err := json.Unmarshal([]byte(line), &record)
+		assert.NoError(t, err)
+		assert.True(t, record.Seq > last[record.Goroutine], "sequence must increase within a goroutine")
+		last[record.Goroutine] = record.Seq
+		seqs = append(seqs, record.Seq)
+	}
+
+	sort.Ints(seqs)
+	assert.Len(t, seqs, goroutines*entries)
+	for i, seq := range seqs {
+		assert.Equal(t, i+1, seq)
+	}
+}
+
+func TestCoreMaxFields(t *testing.T) {
+	buf := &bytes.Buffer{}
+	core := &flogging.Core{
+		Encoders: map[flogging.Encoding]zapcore.Encoder{
+			flogging.JSON: zapcore.NewJSONEncoder(zapcore.EncoderConfig{MessageKey: "msg"}),
+		},
+		Selector:  fixedSelector(flogging.JSON),
+		Output:    zapcore.AddSync(buf),
+		MaxFields: 3,
+	}
+
+	var tests = []struct {
+		name     string
+		core     zapcore.Core
+		fields   []zapcore.Field
+		expected string
+	}{
+		{
+			name:     "WithinLimit",
+			core:     core,
+			fields:   []zapcore.Field{zap.Int("a", 1), zap.Int("b", 2), zap.Int("c", 3)},
+			expected: `{"msg":"message","a":1,"b":2,"c":3}`,
+		},
+		{
+			name:     "Overflow",
+			core:     core,
+			fields:   []zapcore.Field{zap.Int("a", 1), zap.Int("b", 2), zap.Int("c", 3), zap.Int("d", 4), zap.Int("e", 5)},
+			expected: `{"msg":"message","a":1,"b":2,"c":3,"fields_truncated":2}`,
+		},
+		{
+			name:     "WithFields",
+			core:     core.With([]zapcore.Field{zap.Int("w", 0)}),
+			fields:   []zapcore.Field{zap.Int("a", 1), zap.Int("b", 2), zap.Int("c", 3)},
+			expected: `{"msg":"message","w":0,"a":1,"b":2,"fields_truncated":1}`,
+		},
+		{
+			name: "WithOverflow",
+			core: core.
+				With([]zapcore.Field{zap.Int("w", 0), zap.Int("x", 1)}).
+				With([]zapcore.Field{zap.Int("y", 2), zap.Int("z", 3)}),
+			fields:   []zapcore.Field{zap.Int("a", 1)},
+			expected: `{"msg":"message","w":0,"x":1,"y":2,"fields_truncated":2}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			buf.Reset()
+			fields := append([]zapcore.Field{}, tc.fields...)
+			err := tc.core.Write(zapcore.Entry{Message: "message"}, fields)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected+"\n", buf.String())
+			assert.Equal(t, tc.fields, fields, "caller fields must not be modified")
+		})
+	}
+}
+
+func TestCoreMaxFieldsUnlimited(t *testing.T) {
+	buf := &bytes.Buffer{}
+	core := &flogging.Core{
+		Encoders: map[flogging.Encoding]zapcore.Encoder{
+			flogging.JSON: zapcore.NewJSONEncoder(zapcore.EncoderConfig{MessageKey: "msg"}),
+		},
+		Selector: fixedSelector(flogging.JSON),
+		Output:   zapcore.AddSync(buf),
+	}
+
+	var fields []zapcore.Field
+	for i := 0; i < 1000; i++ {
+		fields = append(fields, zap.Int("field", i))
+	}
+	err := core.Write(zapcore.Entry{Message: "message"}, fields)
+	assert.NoError(t, err)
+	assert.Equal(t, 1000, strings.Count(buf.String(), `"field":`))
+	assert.NotContains(t, buf.String(), flogging.FieldsTruncatedKey)
+}
+
+func TestCoreMaxWithFields(t *testing.T) {
+	levels := &flogging.LoggerLevels{}
+	err := levels.ActivateSpec("info:quiet=error")
+	require.NoError(t, err)
+	buf := &bytes.Buffer{}
+	core := &flogging.Core{
+		LevelEnabler: levels,
+		Levels:       levels,
+		Encoders: map[flogging.Encoding]zapcore.Encoder{
+			flogging.JSON: zapcore.NewJSONEncoder(zapcore.EncoderConfig{MessageKey: "msg", LevelKey: "level", NameKey: "name", EncodeLevel: zapcore.LowercaseLevelEncoder}),
+		},
+		Selector:      fixedSelector(flogging.JSON),
+		Output:        zapcore.AddSync(buf),
+		MaxWithFields: 3,
+	}
+
+	var derived zapcore.Core = core
+	for i := 0; i < 10; i++ {
+		derived = derived.With([]zapcore.Field{zap.Int(fmt.Sprintf("f%d", i), i)})
+	}
+	assert.Empty(t, buf.String())
+
+	for i := 0; i < 2; i++ {
+		err = derived.Write(zapcore.Entry{Level: zapcore.InfoLevel, LoggerName: "noisy", Message: "message"}, []zapcore.Field{zap.Int("entry", 1)})
+		assert.NoError(t, err)
+	}
+	assert.Equal(t,
+		`{"level":"warn","name":"noisy","msg":"Persistent field limit reached; dropping fields added with With","f0":0,"f1":1,"f2":2,"max_with_fields":3,"dropped_fields":1}`+"\n"+
+			`{"level":"info","name":"noisy","msg":"message","f0":0,"f1":1,"f2":2,"entry":1}`+"\n"+
+			`{"level":"info","name":"noisy","msg":"message","f0":0,"f1":1,"f2":2,"entry":1}`+"\n",
+		buf.String(), "the warning must be written once",
+	)
+
+	buf.Reset()
+	partial := core.With([]zapcore.Field{zap.Int("a", 1), zap.Int("b", 2)}).With([]zapcore.Field{zap.Int("c", 3), zap.Int("d", 4), zap.Int("e", 5)})
+	err = partial.Write(zapcore.Entry{Level: zapcore.InfoLevel, Message: "message"}, nil)
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), `"dropped_fields":2`)
+	assert.Contains(t, buf.String(), `{"level":"info","msg":"message","a":1,"b":2,"c":3}`+"\n")
+
+	// the warning is not written when WARN is disabled for the logger
+	buf.Reset()
+	quiet := core.With([]zapcore.Field{zap.Int("a", 1), zap.Int("b", 2), zap.Int("c", 3), zap.Int("d", 4)})
+	err = quiet.Write(zapcore.Entry{Level: zapcore.ErrorLevel, LoggerName: "quiet", Message: "message"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"level":"error","name":"quiet","msg":"message","a":1,"b":2,"c":3}`+"\n", buf.String())
+}
+
+func TestCoreMaxWithFieldsUnlimited(t *testing.T) {
+	buf := &bytes.Buffer{}
+	core := &flogging.Core{
+		Encoders: map[flogging.Encoding]zapcore.Encoder{
+			flogging.JSON: zapcore.NewJSONEncoder(zapcore.EncoderConfig{MessageKey: "msg"}),
+		},
+		Selector: fixedSelector(flogging.JSON),
+		Output:   zapcore.AddSync(buf),
+	}
+
+	var derived zapcore.Core = core
+	for i := 0; i < 100; i++ {
+		derived = derived.With([]zapcore.Field{zap.Int("field", i)})
+	}
+	err := derived.Write(zapcore.Entry{Message: "message"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 100, strings.Count(buf.String(), `"field":`))
+}
+
+func TestCoreOnFatal(t *testing.T) {
+	output := &sw{}
+	core := newTestConsoleCore(t, "info", output)
+
+	type exitCode int
+	exit := func(code int) { panic(exitCode(code)) }
+	var written string
+	core.OnFatal = func(e zapcore.Entry) {
+		written = output.String()
+		exit(1)
+	}
+	logger := zap.New(core).Named("fatal").With(zap.String("key", "value"))
+
+	assert.PanicsWithValue(t, exitCode(1), func() { logger.Fatal("fatal message") })
+	assert.Equal(t, "[fatal] FATAL fatal message key=value\n", written, "the hook must run after the entry is written")
+	assert.True(t, output.syncCalled)
+
+	written = ""
+	logger.Error("error message")
+	assert.Empty(t, written, "the hook must only run for fatal entries")
+}
+
+func TestCoreOnPanic(t *testing.T) {
+	output := &sw{}
+	core := newTestConsoleCore(t, "info", output)
+
+	var hooked []string
+	core.OnPanic = func(e zapcore.Entry) { hooked = append(hooked, e.Message) }
+	logger := zap.New(core).Named("panic")
+
+	assert.PanicsWithValue(t, "panic message", func() { logger.Panic("panic message") })
+	assert.Equal(t, []string{"panic message"}, hooked)
+	assert.Equal(t, "[panic] PANIC panic message\n", output.String())
+
+	logger.DPanic("dpanic message")
+	logger.Warn("warn message")
+	assert.Equal(t, []string{"panic message"}, hooked)
+}
+
+func TestCoreSinks(t *testing.T) {
+	formatters, err := fabenc.ParseFormat("%{level} %{message}")
+	assert.NoError(t, err)
+
+	file := &sw{}
+	network := &sw{}
+	observer := &mock.Observer{}
+	core := &flogging.Core{
+		Encoders: map[flogging.Encoding]zapcore.Encoder{
+			flogging.CONSOLE: fabenc.NewFormatEncoder(formatters...),
+			flogging.JSON:    zapcore.NewJSONEncoder(zapcore.EncoderConfig{MessageKey: "msg", LevelKey: "level", EncodeLevel: zapcore.CapitalLevelEncoder}),
+		},
+		Sinks: []flogging.Sink{
+			{Encoding: flogging.CONSOLE, Output: file},
+			{Encoding: flogging.JSON, Output: network},
+		},
+		Observer: observer,
+		Sequence: flogging.NewSequenceCounter(),
+	}
+	derived := core.With([]zapcore.Field{zap.String("with", "value")})
+
+	err = derived.Write(zapcore.Entry{Level: zapcore.WarnLevel, Message: "same entry"}, []zapcore.Field{zap.Int("count", 1)})
+	assert.NoError(t, err)
+	assert.Equal(t, "WARN same entry with=value count=1 seq=1\n", file.String())
+	assert.Equal(t, `{"level":"WARN","msg":"same entry","with":"value","count":1,"seq":1}`+"\n", network.String())
+	assert.Equal(t, 1, observer.WriteEntryCallCount(), "entries must be observed once")
+
+	err = core.Sync()
+	assert.NoError(t, err)
+	assert.True(t, file.syncCalled)
+	assert.True(t, network.syncCalled)
+
+	// a failing sink does not prevent delivery to the others
+	file.Reset()
+	network.Reset()
+	file.writeErr = errors.New("disk full")
+	err = core.Write(zapcore.Entry{Message: "partial"}, nil)
+	assert.EqualError(t, err, "disk full")
+	assert.Contains(t, network.String(), `"msg":"partial"`)
+	assert.Equal(t, 2, observer.WriteEntryCallCount())
+
+	network.syncErr = errors.New("connection reset")
+	err = core.Sync()
+	assert.EqualError(t, err, "connection reset")
+
+	core.Sinks = append(core.Sinks, flogging.Sink{Encoding: flogging.CBOR, Output: &sw{}})
+	file.writeErr = nil
+	err = core.Write(zapcore.Entry{Message: "missing"}, nil)
+	assert.EqualError(t, err, "no encoder for encoding 3")
+}
+
+func TestCoreTransformers(t *testing.T) {
+	output := &sw{}
+	observer := &mock.Observer{}
+	core := newTestConsoleCore(t, "debug", output)
+	core.Observer = observer
+
+	var calls []string
+	var mutex sync.Mutex
+	record := func(name string) {
+		mutex.Lock()
+		calls = append(calls, name)
+		mutex.Unlock()
+	}
+	core.Transformers = []flogging.EntryTransformer{
+		func(e zapcore.Entry, fields []zapcore.Field) (zapcore.Entry, []zapcore.Field) {
+			record("prefix")
+			e.Message = "first: " + e.Message
+			return e, append(fields[:len(fields):len(fields)], zap.String("stage", "first"))
+		},
+		func(e zapcore.Entry, fields []zapcore.Field) (zapcore.Entry, []zapcore.Field) {
+			record("redact")
+			e.Message = strings.Replace(e.Message, "secret", "******", -1)
+			redacted := make([]zapcore.Field, 0, len(fields))
+			for _, f := range fields {
+				if f.Key == "stage" {
+					f = zap.String("stage", "second saw "+f.String)
+				}
+				redacted = append(redacted, f)
+			}
+			return e, redacted
+		},
+	}
+	derived := core.With([]zapcore.Field{zap.String("with", "value")})
+
+	fields := []zapcore.Field{zap.Int("n", 1)}
+	err := derived.Write(zapcore.Entry{LoggerName: "pipeline", Level: zapcore.InfoLevel, Message: "the secret"}, fields)
+	assert.NoError(t, err)
+	assert.Equal(t, `[pipeline] INFO first: the ****** with=value n=1 stage="second saw first"`+"\n", output.String())
+	assert.Equal(t, []string{"prefix", "redact"}, calls)
+	assert.Equal(t, []zapcore.Field{zap.Int("n", 1)}, fields, "caller fields must not be modified")
+
+	observedEntry, observedFields := observer.WriteEntryArgsForCall(0)
+	assert.Equal(t, "first: the ******", observedEntry.Message)
+	assert.Len(t, observedFields, 2)
+
+	// transformers are shared by concurrent writers
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := derived.Write(zapcore.Entry{Message: "concurrent"}, []zapcore.Field{zap.Int("n", 2)})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Len(t, calls, 22)
+}
+
+func TestCoreDelegate(t *testing.T) {
+	levels := &flogging.LoggerLevels{}
+	err := levels.ActivateSpec("warn:peer.gossip=info")
+	assert.NoError(t, err)
+
+	delegate, logs := observer.New(zapcore.DebugLevel)
+	syncs := &sw{}
+	mockObserver := &mock.Observer{}
+	core := &flogging.Core{
+		LevelEnabler: levels,
+		Levels:       levels,
+		Observer:     mockObserver,
+		Delegate:     zapcore.NewTee(delegate, zapcore.NewCore(zapcore.NewJSONEncoder(zapcore.EncoderConfig{}), syncs, zapcore.DebugLevel)),
+		Sequence:     flogging.NewSequenceCounter(),
+	}
+
+	logger := zap.New(core).With(zap.String("with", "value"))
+	logger.Named("peer").Info("filtered by our levels")
+	logger.Named("peer.gossip").Info("delegated", zap.Int("n", 1))
+	logger.Named("peer.gossip").Debug("filtered by our levels")
+
+	entries := logs.AllUntimed()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "delegated", entries[0].Message)
+	assert.Equal(t, "peer.gossip", entries[0].LoggerName)
+	assert.Equal(t, []zapcore.Field{zap.String("with", "value"), zap.Int("n", 1), zap.Uint64("seq", 1)}, entries[0].Context)
+	assert.Equal(t, 1, mockObserver.WriteEntryCallCount())
+
+	assert.NoError(t, logger.Sync())
+	assert.True(t, syncs.syncCalled)
+}
+
+func TestCoreValidate(t *testing.T) {
+	output := &sw{}
+	valid := newTestConsoleCore(t, "info", output)
+	assert.NoError(t, valid.Validate())
+
+	var tests = []struct {
+		name   string
+		modify func(c *flogging.Core)
+		errMsg string
+	}{
+		{
+			name:   "nil encoder",
+			modify: func(c *flogging.Core) { c.Encoders[flogging.JSON] = nil },
+			errMsg: "nil encoder for encoding 1",
+		},
+		{
+			name:   "missing selected encoding",
+			modify: func(c *flogging.Core) { delete(c.Encoders, flogging.CONSOLE) },
+			errMsg: "no encoder for selected encoding 0",
+		},
+		{
+			name:   "missing selector",
+			modify: func(c *flogging.Core) { c.Selector = nil },
+			errMsg: "no encoding selector",
+		},
+		{
+			name:   "missing output",
+			modify: func(c *flogging.Core) { c.Output = nil },
+			errMsg: "no output",
+		},
+		{
+			name: "missing sink encoding",
+			modify: func(c *flogging.Core) {
+				c.Sinks = []flogging.Sink{{Encoding: flogging.CONSOLE, Output: output}, {Encoding: flogging.JSON, Output: output}}
+			},
+			errMsg: "no encoder for sink encoding 1",
+		},
+		{
+			name:   "missing sink output",
+			modify: func(c *flogging.Core) { c.Sinks = []flogging.Sink{{Encoding: flogging.CONSOLE}} },
+			errMsg: "no output for sink encoding 0",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			core := newTestConsoleCore(t, "info", output)
+			tc.modify(core)
+			assert.EqualError(t, core.Validate(), tc.errMsg)
+		})
+	}
+
+	// sinks and delegates replace the selector and output
+	sinks := newTestConsoleCore(t, "info", output)
+	sinks.Selector, sinks.Output = nil, nil
+	sinks.Sinks = []flogging.Sink{{Encoding: flogging.CONSOLE, Output: output}}
+	assert.NoError(t, sinks.Validate())
+
+	delegated := &flogging.Core{Delegate: zapcore.NewNopCore()}
+	assert.NoError(t, delegated.Validate())
+}
+
+func TestCoreFreeze(t *testing.T) {
+	transformer := func(value string) flogging.EntryTransformer {
+		return func(e zapcore.Entry, fields []zapcore.Field) (zapcore.Entry, []zapcore.Field) {
+			return e, append(fields[:len(fields):len(fields)], zap.String("transformer", value))
+		}
+	}
+
+	output := &sw{}
+	core := newTestConsoleCore(t, "info", output)
+	core.Transformers = []flogging.EntryTransformer{transformer("original")}
+	parent := core.With([]zapcore.Field{zap.String("scope", "parent")}).(*flogging.Core)
+	frozen := parent.Freeze()
+
+	// modify both cores after the freeze
+	parent.Transformers[0] = transformer("replaced")
+	parent.Encoders[flogging.CONSOLE].AddString("parent", "only")
+	frozen.Encoders[flogging.CONSOLE].AddString("child", "only")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			zap.New(parent).With(zap.Int("worker", i)).Info("from-parent")
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			zap.New(frozen).With(zap.Int("worker", i)).Info("from-child")
+		}(i)
+	}
+	wg.Wait()
+
+	lines := strings.Split(strings.TrimSuffix(output.String(), "\n"), "\n")
+	assert.Len(t, lines, 20)
+	for _, line := range lines {
+		assert.Contains(t, line, "scope=parent", "fields added before the freeze are shared")
+		if strings.Contains(line, "from-child") {
+			assert.Contains(t, line, "child=only")
+			assert.Contains(t, line, "transformer=original")
+			assert.NotContains(t, line, "parent=only")
+			continue
+		}
+		assert.Contains(t, line, "parent=only")
+		assert.Contains(t, line, "transformer=replaced")
+		assert.NotContains(t, line, "child=only")
+	}
+}
+
+func TestCoreFreezeSinks(t *testing.T) {
+	first, second := &sw{}, &sw{}
+	core := newTestConsoleCore(t, "info", first)
+	core.Sinks = []flogging.Sink{{Encoding: flogging.CONSOLE, Output: first}}
+	frozen := core.Freeze()
+	core.Sinks[0].Output = second
+
+	zap.New(frozen).Info("frozen")
+	assert.Contains(t, first.String(), "frozen")
+	assert.Empty(t, second.String())
+	assert.Nil(t, core.Freeze().Transformers)
+}
@@ -0,0 +1,95 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// CorrelationIDKey is the field key used for correlation ids.
+	CorrelationIDKey = "correlation_id"
+	// CorrelationIDHeader is the gRPC metadata key that carries correlation
+	// ids between processes.
+	CorrelationIDHeader = "x-correlation-id"
+)
+
+type correlationKey struct{}
+
+// NewCorrelationContext returns a copy of ctx that carries the correlation id.
+func NewCorrelationContext(ctx context.Context, correlationID string) context.Context {
+	return context.WithValue(ctx, correlationKey{}, correlationID)
+}
+
+// CorrelationID returns the correlation id stored in ctx by
+// NewCorrelationContext or, when there is none, the id received in the
+// incoming gRPC metadata of ctx. The returned bool is false when neither
+// provides a non-empty id.
+func CorrelationID(ctx context.Context) (string, bool) {
+	if id, _ := ctx.Value(correlationKey{}).(string); id != "" {
+		return id, true
+	}
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+	for _, id := range md.Get(CorrelationIDHeader) {
+		if id != "" {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// WithCorrelationID returns a correlation_id field for the correlation id
+// carried by ctx. No fields are returned when ctx does not carry an id.
+func WithCorrelationID(ctx context.Context) []zapcore.Field {
+	id, ok := CorrelationID(ctx)
+	if !ok {
+		return nil
+	}
+	return []zapcore.Field{zap.String(CorrelationIDKey, id)}
+}
+
+// OutgoingCorrelationContext returns a copy of ctx with the correlation id
+// carried by ctx added to the outgoing gRPC metadata. The context is returned
+// unchanged when it does not carry an id or the outgoing metadata already
+// contains one.
+func OutgoingCorrelationContext(ctx context.Context) context.Context {
+	id, ok := CorrelationID(ctx)
+	if !ok {
+		return ctx
+	}
+	if md, ok := metadata.FromOutgoingContext(ctx); ok && len(md.Get(CorrelationIDHeader)) > 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, CorrelationIDHeader, id)
+}
+
+// CorrelationUnaryServerInterceptor stores the correlation id received in the
+// request metadata in the context provided to the handler.
+func CorrelationUnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if id, ok := CorrelationID(ctx); ok {
+			ctx = NewCorrelationContext(ctx, id)
+		}
+		return handler(ctx, req)
+	}
+}
+
+// CorrelationUnaryClientInterceptor adds the correlation id carried by the
+// call context to the outgoing request metadata.
+func CorrelationUnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(OutgoingCorrelationContext(ctx), method, req, reply, cc, opts...)
+	}
+}
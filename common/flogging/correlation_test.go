@@ -0,0 +1,81 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestWithCorrelationID(t *testing.T) {
+	assert.Empty(t, flogging.WithCorrelationID(context.Background()))
+	assert.Empty(t, flogging.WithCorrelationID(flogging.NewCorrelationContext(context.Background(), "")))
+
+	incoming := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-correlation-id", "from-metadata"))
+	assert.Equal(t, []zap.Field{zap.String("correlation_id", "from-metadata")}, flogging.WithCorrelationID(incoming))
+
+	empty := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-correlation-id", ""))
+	assert.Empty(t, flogging.WithCorrelationID(empty))
+
+	// an id stored in the context takes precedence over the metadata
+	stored := flogging.NewCorrelationContext(incoming, "from-context")
+	assert.Equal(t, []zap.Field{zap.String("correlation_id", "from-context")}, flogging.WithCorrelationID(stored))
+
+	buf := &bytes.Buffer{}
+	logging, err := flogging.New(flogging.Config{Format: "%{message}", Writer: buf})
+	require.NoError(t, err)
+	logging.Logger("correlation").Zap().With(flogging.WithCorrelationID(incoming)...).Info("handled")
+	assert.Equal(t, "handled correlation_id=from-metadata\n", buf.String())
+}
+
+func TestOutgoingCorrelationContext(t *testing.T) {
+	ctx := flogging.OutgoingCorrelationContext(context.Background())
+	_, ok := metadata.FromOutgoingContext(ctx)
+	assert.False(t, ok)
+
+	ctx = flogging.OutgoingCorrelationContext(flogging.NewCorrelationContext(context.Background(), "id-1"))
+	md, ok := metadata.FromOutgoingContext(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"id-1"}, md.Get("x-correlation-id"))
+
+	// existing outgoing ids are not duplicated
+	ctx = flogging.OutgoingCorrelationContext(flogging.NewCorrelationContext(ctx, "id-2"))
+	md, _ = metadata.FromOutgoingContext(ctx)
+	assert.Equal(t, []string{"id-1"}, md.Get("x-correlation-id"))
+}
+
+func TestCorrelationInterceptors(t *testing.T) {
+	// the client injects the id into the outgoing metadata
+	var outgoing metadata.MD
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		outgoing, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+	client := flogging.CorrelationUnaryClientInterceptor()
+	err := client(flogging.NewCorrelationContext(context.Background(), "call-id"), "/test.Service/Method", nil, nil, nil, invoker)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"call-id"}, outgoing.Get("x-correlation-id"))
+
+	// the server makes the received id available to the handler
+	var fields []zap.Field
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		fields = flogging.WithCorrelationID(metadata.NewIncomingContext(ctx, nil))
+		return nil, nil
+	}
+	server := flogging.CorrelationUnaryServerInterceptor()
+	_, err = server(metadata.NewIncomingContext(context.Background(), outgoing), nil, &grpc.UnaryServerInfo{}, handler)
+	assert.NoError(t, err)
+	assert.Equal(t, []zap.Field{zap.String("correlation_id", "call-id")}, fields)
+}
@@ -0,0 +1,65 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"bytes"
+	"io"
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// A CRLFSyncer is a zapcore.WriteSyncer that converts LF line endings to CRLF
+// for consumers, such as Windows log viewers, that require them. Line feeds
+// that are already preceded by a carriage return are written unchanged, even
+// when the carriage return ended the previous write.
+type CRLFSyncer struct {
+	mutex  sync.Mutex
+	writer io.Writer
+	lastCR bool
+}
+
+// NewCRLFSyncer creates a CRLFSyncer that writes to w.
+func NewCRLFSyncer(w io.Writer) *CRLFSyncer {
+	return &CRLFSyncer{writer: w}
+}
+
+// Write writes b to the underlying writer with every bare LF replaced by CRLF.
+// The returned count is the number of bytes of b that were consumed.
+func (c *CRLFSyncer) Write(b []byte) (int, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if len(b) == 0 {
+		return 0, nil
+	}
+
+	converted := make([]byte, 0, len(b)+bytes.Count(b, []byte{'\n'}))
+	prevCR := c.lastCR
+	for _, ch := range b {
+		if ch == '\n' && !prevCR {
+			converted = append(converted, '\r')
+		}
+		converted = append(converted, ch)
+		prevCR = ch == '\r'
+	}
+
+	if _, err := c.writer.Write(converted); err != nil {
+		return 0, err
+	}
+	c.lastCR = prevCR
+	return len(b), nil
+}
+
+// Sync syncs the underlying writer when it is a zapcore.WriteSyncer.
+func (c *CRLFSyncer) Sync() error {
+	if ws, ok := c.writer.(zapcore.WriteSyncer); ok {
+		return ws.Sync()
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCRLFSyncer(t *testing.T) {
+	var tests = []struct {
+		name     string
+		writes   []string
+		expected string
+	}{
+		{name: "lf", writes: []string{"one\ntwo\n"}, expected: "one\r\ntwo\r\n"},
+		{name: "crlf", writes: []string{"one\r\ntwo\r\n"}, expected: "one\r\ntwo\r\n"},
+		{name: "mixed", writes: []string{"one\r\ntwo\n\nthree"}, expected: "one\r\ntwo\r\n\r\nthree"},
+		{name: "split crlf", writes: []string{"one\r", "\ntwo\n"}, expected: "one\r\ntwo\r\n"},
+		{name: "bare cr", writes: []string{"one\rtwo\n"}, expected: "one\rtwo\r\n"},
+		{name: "empty", writes: []string{"", "\n"}, expected: "\r\n"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			crlf := flogging.NewCRLFSyncer(buf)
+			for _, w := range tc.writes {
+				n, err := crlf.Write([]byte(w))
+				assert.NoError(t, err)
+				assert.Equal(t, len(w), n)
+			}
+			assert.Equal(t, tc.expected, buf.String())
+		})
+	}
+}
+
+func TestCRLFSyncerSync(t *testing.T) {
+	output := &sw{}
+	crlf := flogging.NewCRLFSyncer(output)
+	assert.NoError(t, crlf.Sync())
+	assert.True(t, output.syncCalled)
+
+	output.syncErr = errors.New("sync failed")
+	assert.EqualError(t, crlf.Sync(), "sync failed")
+
+	output.writeErr = errors.New("write failed")
+	n, err := crlf.Write([]byte("line\n"))
+	assert.EqualError(t, err, "write failed")
+	assert.Equal(t, 0, n)
+
+	assert.NoError(t, flogging.NewCRLFSyncer(&bytes.Buffer{}).Sync())
+}
+
+func TestLoggingCRLFLineEndings(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logging, err := flogging.New(flogging.Config{
+		Format:          "%{message}",
+		Writer:          buf,
+		CRLFLineEndings: true,
+	})
+	assert.NoError(t, err)
+
+	logging.Logger("crlf").Info("first")
+	logging.Logger("crlf").Info("second")
+	assert.Equal(t, "first\r\nsecond\r\n", buf.String())
+
+	buf.Reset()
+	logging, err = flogging.New(flogging.Config{Format: "%{message}", Writer: buf})
+	assert.NoError(t, err)
+	logging.Logger("crlf").Info("first")
+	assert.Equal(t, "first\n", buf.String())
+}
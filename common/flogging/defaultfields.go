@@ -0,0 +1,106 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// DefaultFields is a registry of fields that are added to the entries of the
+// loggers below a logger name prefix. A prefix applies to the logger with
+// that name and the loggers below it so gossip applies to gossip.state. When
+// several prefixes apply, the fields of the longer prefix take precedence.
+// Fields provided when an entry is written always take precedence over the
+// defaults; fields added to a logger with With are encoded when the logger is
+// created and are not considered.
+//
+// Its Transform method is an EntryTransformer for use with Core.Transformers.
+type DefaultFields struct {
+	mutex    sync.RWMutex
+	prefixes []string // sorted from the longest to the shortest
+	fields   map[string][]zapcore.Field
+}
+
+// NewDefaultFields creates an empty DefaultFields registry.
+func NewDefaultFields() *DefaultFields {
+	return &DefaultFields{fields: map[string][]zapcore.Field{}}
+}
+
+// Set replaces the default fields of the loggers below prefix. Providing no
+// fields removes the defaults for the prefix.
+func (d *DefaultFields) Set(prefix string, fields ...zapcore.Field) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if len(fields) == 0 {
+		delete(d.fields, prefix)
+	} else {
+		d.fields[prefix] = append([]zapcore.Field(nil), fields...)
+	}
+
+	d.prefixes = d.prefixes[:0]
+	for p := range d.fields {
+		d.prefixes = append(d.prefixes, p)
+	}
+	sort.Slice(d.prefixes, func(i, j int) bool {
+		if len(d.prefixes[i]) != len(d.prefixes[j]) {
+			return len(d.prefixes[i]) > len(d.prefixes[j])
+		}
+		return d.prefixes[i] < d.prefixes[j]
+	})
+}
+
+// Fields returns the default fields that apply to the named logger.
+func (d *DefaultFields) Fields(loggerName string) []zapcore.Field {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	var fields []zapcore.Field
+	seen := map[string]bool{}
+	for _, prefix := range d.prefixes {
+		if loggerName != prefix && !strings.HasPrefix(loggerName, prefix+".") {
+			continue
+		}
+		for _, f := range d.fields[prefix] {
+			if !seen[f.Key] {
+				seen[f.Key] = true
+				fields = append(fields, f)
+			}
+		}
+	}
+	return fields
+}
+
+// Transform adds the default fields of the entry's logger that are not
+// already present in fields.
+func (d *DefaultFields) Transform(e zapcore.Entry, fields []zapcore.Field) (zapcore.Entry, []zapcore.Field) {
+	defaults := d.Fields(e.LoggerName)
+	if len(defaults) == 0 {
+		return e, fields
+	}
+
+	merged := make([]zapcore.Field, 0, len(defaults)+len(fields))
+	for _, def := range defaults {
+		if !hasKey(fields, def.Key) {
+			merged = append(merged, def)
+		}
+	}
+	return e, append(merged, fields...)
+}
+
+func hasKey(fields []zapcore.Field, key string) bool {
+	for _, f := range fields {
+		if f.Key == key {
+			return true
+		}
+	}
+	return false
+}
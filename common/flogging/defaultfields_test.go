@@ -0,0 +1,73 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestDefaultFields(t *testing.T) {
+	df := flogging.NewDefaultFields()
+	df.Set("gossip", zap.String("component", "gossip"), zap.String("team", "core"))
+	df.Set("gossip.state", zap.String("component", "state"))
+	df.Set("peer", zap.Int("n", 1))
+
+	var tests = []struct {
+		logger   string
+		expected []zapcore.Field
+	}{
+		{logger: "gossip", expected: []zapcore.Field{zap.String("component", "gossip"), zap.String("team", "core")}},
+		{logger: "gossip.state", expected: []zapcore.Field{zap.String("component", "state"), zap.String("team", "core")}},
+		{logger: "gossip.state.sync", expected: []zapcore.Field{zap.String("component", "state"), zap.String("team", "core")}},
+		{logger: "gossipy", expected: nil},
+		{logger: "peer", expected: []zapcore.Field{zap.Int("n", 1)}},
+		{logger: "", expected: nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.logger, func(t *testing.T) {
+			assert.Equal(t, tc.expected, df.Fields(tc.logger))
+		})
+	}
+
+	df.Set("gossip.state")
+	assert.Equal(t, []zapcore.Field{zap.String("component", "gossip"), zap.String("team", "core")}, df.Fields("gossip.state"))
+}
+
+func TestDefaultFieldsTransform(t *testing.T) {
+	df := flogging.NewDefaultFields()
+	df.Set("gossip", zap.String("component", "gossip"), zap.String("team", "core"))
+
+	fields := []zapcore.Field{zap.String("team", "caller"), zap.Int("n", 1)}
+	_, transformed := df.Transform(zapcore.Entry{LoggerName: "gossip"}, fields)
+	assert.Equal(t, []zapcore.Field{zap.String("component", "gossip"), zap.String("team", "caller"), zap.Int("n", 1)}, transformed)
+	assert.Equal(t, []zapcore.Field{zap.String("team", "caller"), zap.Int("n", 1)}, fields, "input fields must not be modified")
+
+	_, transformed = df.Transform(zapcore.Entry{LoggerName: "peer"}, fields)
+	assert.Equal(t, fields, transformed)
+}
+
+func TestDefaultFieldsCore(t *testing.T) {
+	df := flogging.NewDefaultFields()
+	df.Set("module", zap.String("component", "default"))
+
+	output := &sw{}
+	core := newTestConsoleCore(t, "info", output)
+	core.Transformers = []flogging.EntryTransformer{df.Transform}
+
+	err := core.Write(zapcore.Entry{Level: zapcore.InfoLevel, LoggerName: "module", Message: "default"}, nil)
+	assert.NoError(t, err)
+	err = core.Write(zapcore.Entry{Level: zapcore.InfoLevel, LoggerName: "module", Message: "override"}, []zapcore.Field{zap.String("component", "caller")})
+	assert.NoError(t, err)
+	err = core.Write(zapcore.Entry{Level: zapcore.InfoLevel, LoggerName: "other", Message: "none"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "[module] INFO default component=default\n[module] INFO override component=caller\n[other] INFO none\n", output.String())
+}
@@ -0,0 +1,183 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// A DeferredCore is a zapcore.Core that holds log entries in memory until the
+// real core is installed. It is intended to be used during process start,
+// before the logging configuration has been processed, so early diagnostics
+// are neither lost nor encoded with the wrong settings.
+//
+// When Install is called, the buffered entries are replayed through the
+// installed core and all subsequent calls are delegated to it. Entries are
+// replayed through the Check method of the installed core so its level
+// configuration is honored.
+//
+// Entries at PanicLevel and above cannot wait for Install as the logger
+// panics or exits the process after writing them. When one is written before
+// Install, the buffered entries and the entry are written to the emergency
+// core, which is the BootstrapCore by default, and are not replayed.
+//
+// Entries accumulate without bound until Install is called.
+type DeferredCore struct {
+	zapcore.LevelEnabler
+
+	state  *deferredState
+	fields []zapcore.Field
+
+	mutex    sync.Mutex
+	delegate zapcore.Core
+}
+
+type deferredState struct {
+	mutex     sync.RWMutex
+	core      zapcore.Core
+	emergency zapcore.Core
+	entries   []deferredEntry
+}
+
+type deferredEntry struct {
+	entry  zapcore.Entry
+	fields []zapcore.Field
+}
+
+// NewDeferredCore creates a DeferredCore that buffers entries at the levels
+// enabled by the provided LevelEnabler.
+func NewDeferredCore(enabler zapcore.LevelEnabler) *DeferredCore {
+	return &DeferredCore{
+		LevelEnabler: enabler,
+		state: &deferredState{
+			emergency: BootstrapCore(),
+		},
+	}
+}
+
+// SetEmergencyCore sets the core that receives the buffered entries when an
+// entry at PanicLevel or above is written before Install.
+func (d *DeferredCore) SetEmergencyCore(core zapcore.Core) {
+	d.state.mutex.Lock()
+	d.state.emergency = core
+	d.state.mutex.Unlock()
+}
+
+// Install replays the buffered entries through the provided core and
+// delegates all future operations to it. Only the first call to Install has
+// an effect.
+func (d *DeferredCore) Install(core zapcore.Core) error {
+	d.state.mutex.Lock()
+	defer d.state.mutex.Unlock()
+
+	if d.state.core != nil {
+		return nil
+	}
+	d.state.core = core
+
+	return d.state.flush(core)
+}
+
+// flush replays the buffered entries through the provided core and discards
+// them. It must be called with the state mutex held.
+func (s *deferredState) flush(core zapcore.Core) error {
+	var err error
+	for _, de := range s.entries {
+		if werr := writeChecked(core.Check(de.entry, nil), de.fields...); err == nil {
+			err = werr
+		}
+	}
+	s.entries = nil
+
+	if serr := core.Sync(); err == nil {
+		err = serr
+	}
+	return err
+}
+
+// installed returns the core derived from the installed core for this
+// instance or nil if a core has not been installed.
+func (d *DeferredCore) installed() zapcore.Core {
+	d.state.mutex.RLock()
+	core := d.state.core
+	d.state.mutex.RUnlock()
+	if core == nil {
+		return nil
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.delegate == nil {
+		d.delegate = core
+		if len(d.fields) != 0 {
+			d.delegate = core.With(d.fields)
+		}
+	}
+	return d.delegate
+}
+
+func (d *DeferredCore) Enabled(lvl zapcore.Level) bool {
+	if core := d.installed(); core != nil {
+		return core.Enabled(lvl)
+	}
+	return d.LevelEnabler.Enabled(lvl)
+}
+
+func (d *DeferredCore) With(fields []zapcore.Field) zapcore.Core {
+	if core := d.installed(); core != nil {
+		return core.With(fields)
+	}
+
+	combined := make([]zapcore.Field, 0, len(d.fields)+len(fields))
+	combined = append(combined, d.fields...)
+	combined = append(combined, fields...)
+
+	return &DeferredCore{
+		LevelEnabler: d.LevelEnabler,
+		state:        d.state,
+		fields:       combined,
+	}
+}
+
+func (d *DeferredCore) Check(e zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if core := d.installed(); core != nil {
+		return core.Check(e, ce)
+	}
+	if d.LevelEnabler.Enabled(e.Level) {
+		return ce.AddCore(e, d)
+	}
+	return ce
+}
+
+func (d *DeferredCore) Write(e zapcore.Entry, fields []zapcore.Field) error {
+	d.state.mutex.Lock()
+	if d.state.core == nil {
+		combined := make([]zapcore.Field, 0, len(d.fields)+len(fields))
+		combined = append(combined, d.fields...)
+		combined = append(combined, fields...)
+		d.state.entries = append(d.state.entries, deferredEntry{entry: e, fields: combined})
+		defer d.state.mutex.Unlock()
+		if e.Level >= zapcore.PanicLevel {
+			return d.state.flush(d.state.emergency)
+		}
+		return nil
+	}
+	d.state.mutex.Unlock()
+
+	// the entry was checked before the core was installed so it is checked
+	// again to honor the configuration of the installed core
+	return writeChecked(d.installed().Check(e, nil), fields...)
+}
+
+func (d *DeferredCore) Sync() error {
+	if core := d.installed(); core != nil {
+		return core.Sync()
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging_test
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/common/flogging/fabenc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func newTestConsoleCore(t *testing.T, spec string, output *sw) *flogging.Core {
+	levels := &flogging.LoggerLevels{}
+	err := levels.ActivateSpec(spec)
+	assert.NoError(t, err)
+
+	formatters, err := fabenc.ParseFormat("[%{module}] %{level} %{message}")
+	assert.NoError(t, err)
+
+	return &flogging.Core{
+		LevelEnabler: levels,
+		Levels:       levels,
+		Encoders: map[flogging.Encoding]zapcore.Encoder{
+			flogging.CONSOLE: fabenc.NewFormatEncoder(formatters...),
+		},
+		Selector: output,
+		Output:   output,
+	}
+}
+
+func TestDeferredCoreReplay(t *testing.T) {
+	deferred := flogging.NewDeferredCore(zapcore.DebugLevel)
+	logger := zap.New(deferred).Named("early").With(zap.String("phase", "boot"))
+
+	logger.Debug("debug message")
+	logger.Info("info message", zap.Int("count", 1))
+
+	output := &sw{}
+	err := deferred.Install(newTestConsoleCore(t, "info", output))
+	assert.NoError(t, err)
+	assert.True(t, output.syncCalled)
+	assert.Equal(t, "[early] INFO info message phase=boot count=1\n", output.String())
+
+	output.Reset()
+	logger.Info("after install")
+	assert.Equal(t, "[early] INFO after install phase=boot\n", output.String())
+
+	output.Reset()
+	logger.With(zap.String("key", "value")).Warn("derived after install")
+	assert.Equal(t, "[early] WARN derived after install phase=boot key=value\n", output.String())
+}
+
+func TestDeferredCoreInstallOnce(t *testing.T) {
+	deferred := flogging.NewDeferredCore(zapcore.InfoLevel)
+	logger := zap.New(deferred)
+	logger.Info("buffered")
+
+	first, second := &sw{}, &sw{}
+	assert.NoError(t, deferred.Install(newTestConsoleCore(t, "info", first)))
+	assert.NoError(t, deferred.Install(newTestConsoleCore(t, "info", second)))
+
+	logger.Info("delegated")
+	assert.Equal(t, "[] INFO buffered\n[] INFO delegated\n", first.String())
+	assert.Empty(t, second.String())
+}
+
+func TestDeferredCoreLevels(t *testing.T) {
+	deferred := flogging.NewDeferredCore(zapcore.WarnLevel)
+	assert.False(t, deferred.Enabled(zapcore.InfoLevel))
+	assert.True(t, deferred.Enabled(zapcore.WarnLevel))
+
+	logger := zap.New(deferred)
+	logger.Info("dropped")
+	logger.Warn("buffered")
+
+	output := &sw{}
+	err := deferred.Install(newTestConsoleCore(t, "debug", output))
+	assert.NoError(t, err)
+	assert.Equal(t, "[] WARN buffered\n", output.String())
+	assert.True(t, deferred.Enabled(zapcore.DebugLevel))
+}
+
+func TestDeferredCoreCheckedBeforeInstall(t *testing.T) {
+	deferred := flogging.NewDeferredCore(zapcore.DebugLevel)
+	debug := deferred.Check(zapcore.Entry{Level: zapcore.DebugLevel, Message: "debug"}, nil)
+	warn := deferred.Check(zapcore.Entry{Level: zapcore.WarnLevel, Message: "warn"}, nil)
+	require.NotNil(t, debug)
+	require.NotNil(t, warn)
+
+	output := &sw{}
+	assert.NoError(t, deferred.Install(newTestConsoleCore(t, "warn", output)))
+
+	// entries checked before Install are checked again by the installed core
+	debug.Write()
+	warn.Write()
+	assert.Equal(t, "[] WARN warn\n", output.String())
+}
+
+func TestDeferredCoreSync(t *testing.T) {
+	deferred := flogging.NewDeferredCore(zapcore.InfoLevel)
+	assert.NoError(t, deferred.Sync())
+
+	output := &sw{}
+	assert.NoError(t, deferred.Install(newTestConsoleCore(t, "info", output)))
+	output.syncCalled = false
+	assert.NoError(t, deferred.Sync())
+	assert.True(t, output.syncCalled)
+}
+
+func TestDeferredCorePanicBeforeInstall(t *testing.T) {
+	deferred := flogging.NewDeferredCore(zapcore.InfoLevel)
+	emergency := &sw{}
+	deferred.SetEmergencyCore(newTestConsoleCore(t, "info", emergency))
+	logger := zap.New(deferred).Named("early")
+
+	logger.Info("buffered")
+	assert.Empty(t, emergency.String())
+
+	assert.Panics(t, func() { logger.Panic("boom") })
+	assert.Equal(t, "[early] INFO buffered\n[early] PANIC boom\n", emergency.String())
+	assert.True(t, emergency.syncCalled)
+
+	// entries written to the emergency core are not replayed
+	output := &sw{}
+	err := deferred.Install(newTestConsoleCore(t, "info", output))
+	assert.NoError(t, err)
+	assert.Empty(t, output.String())
+}
+
+func TestDeferredCoreBootstrapEmergencyCore(t *testing.T) {
+	stderr, err := ioutil.TempFile("", "stderr")
+	require.NoError(t, err)
+	defer os.Remove(stderr.Name())
+	defer stderr.Close()
+	defer func(f *os.File) { os.Stderr = f }(os.Stderr)
+	os.Stderr = stderr
+
+	deferred := flogging.NewDeferredCore(zapcore.DebugLevel)
+	logger := zap.New(deferred).Named("early")
+	logger.Debug("debug message")
+	logger.Info("buffered")
+	assert.Panics(t, func() { logger.Panic("boom") })
+
+	output, err := ioutil.ReadFile(stderr.Name())
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSuffix(string(output), "\n"), "\n")
+	require.Len(t, lines, 2, "entries should be written by the bootstrap core: %q", output)
+	assert.Regexp(t, `^{"level":"info","ts":[0-9.e+]+,"name":"early","msg":"buffered"}$`, lines[0])
+	assert.Regexp(t, `^{"level":"panic","ts":[0-9.e+]+,"name":"early","msg":"boom"}$`, lines[1])
+}
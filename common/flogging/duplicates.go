@@ -0,0 +1,101 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"strconv"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// DuplicateKeyPolicy determines how a Core handles fields that share a key.
+type DuplicateKeyPolicy int
+
+const (
+	// AllowDuplicateKeys encodes all fields, even when keys are repeated.
+	AllowDuplicateKeys DuplicateKeyPolicy = iota
+	// LastKeyWins drops earlier fields with the same key as a later field.
+	LastKeyWins
+	// RenameDuplicateKeys renames repeated keys by appending a numeric
+	// suffix. The second error field becomes error.1, the third error.2, and
+	// so on.
+	RenameDuplicateKeys
+)
+
+// keySet is the set of keys in the innermost namespace of the fields added
+// to a Core with With. It is never modified after it is created.
+type keySet map[string]struct{}
+
+// dedupe applies the policy to fields. The keys in seen have already been
+// encoded by With and cannot be dropped so fields that repeat them are
+// renamed under either policy. The returned keySet holds the keys of the
+// innermost namespace after the fields have been applied.
+func (p DuplicateKeyPolicy) dedupe(fields []zapcore.Field, seen keySet) ([]zapcore.Field, keySet) {
+	result := make([]zapcore.Field, 0, len(fields))
+	used := keySet{}
+	for k := range seen {
+		used[k] = struct{}{}
+	}
+
+	start := 0
+	for i := 0; i <= len(fields); i++ {
+		if i < len(fields) && fields[i].Type != zapcore.NamespaceType {
+			continue
+		}
+		result = append(result, p.dedupeScope(fields[start:i], used)...)
+		if i == len(fields) {
+			break
+		}
+
+		// a namespace starts a new scope for the fields that follow
+		ns := fields[i]
+		ns.Key = uniqueKey(ns.Key, used)
+		result = append(result, ns)
+		used = keySet{}
+		start = i + 1
+	}
+	return result, used
+}
+
+// dedupeScope handles the fields that share a namespace and records their
+// final keys in used.
+func (p DuplicateKeyPolicy) dedupeScope(fields []zapcore.Field, used keySet) []zapcore.Field {
+	last := map[string]int{}
+	if p == LastKeyWins {
+		for i, f := range fields {
+			last[f.Key] = i
+		}
+	}
+
+	result := make([]zapcore.Field, 0, len(fields))
+	for i, f := range fields {
+		if f.Type == zapcore.SkipType {
+			result = append(result, f)
+			continue
+		}
+		if p == LastKeyWins && last[f.Key] != i {
+			continue
+		}
+		f.Key = uniqueKey(f.Key, used)
+		result = append(result, f)
+	}
+	return result
+}
+
+// uniqueKey returns key, or key with the lowest numeric suffix that is not in
+// used, and adds the returned key to used.
+func uniqueKey(key string, used keySet) string {
+	unique := key
+	for n := 1; ; n++ {
+		if _, ok := used[unique]; !ok {
+			break
+		}
+		unique = key + "." + strconv.Itoa(n)
+	}
+	used[unique] = struct{}{}
+	return unique
+}
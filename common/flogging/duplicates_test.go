@@ -0,0 +1,200 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// duplicateKeys returns the keys that are repeated within a JSON object,
+// including nested objects.
+func duplicateKeys(t *testing.T, line string) []string {
+	dec := json.NewDecoder(strings.NewReader(line))
+	var duplicates []string
+	var walk func() // consumes a single value
+	walk = func() {
+		tok, err := dec.Token()
+		require.NoError(t, err)
+		switch tok {
+		case json.Delim('{'):
+			keys := map[string]bool{}
+			for dec.More() {
+				key, err := dec.Token()
+				require.NoError(t, err)
+				if keys[key.(string)] {
+					duplicates = append(duplicates, key.(string))
+				}
+				keys[key.(string)] = true
+				walk()
+			}
+			_, err = dec.Token()
+			require.NoError(t, err)
+		case json.Delim('['):
+			for dec.More() {
+				walk()
+			}
+			_, err = dec.Token()
+			require.NoError(t, err)
+		}
+	}
+	walk()
+	_, err := dec.Token()
+	require.Equal(t, io.EOF, err)
+	return duplicates
+}
+
+func newDuplicateKeysCore(buf *bytes.Buffer, policy flogging.DuplicateKeyPolicy) *flogging.Core {
+	return &flogging.Core{
+		Encoders: map[flogging.Encoding]zapcore.Encoder{
+			flogging.JSON: zapcore.NewJSONEncoder(zapcore.EncoderConfig{MessageKey: "msg"}),
+		},
+		Selector:      fixedSelector(flogging.JSON),
+		Output:        zapcore.AddSync(buf),
+		DuplicateKeys: policy,
+	}
+}
+
+func TestCoreDuplicateKeys(t *testing.T) {
+	var tests = []struct {
+		name     string
+		policy   flogging.DuplicateKeyPolicy
+		with     []zapcore.Field
+		fields   []zapcore.Field
+		expected string
+	}{
+		{
+			name:     "Allow",
+			policy:   flogging.AllowDuplicateKeys,
+			fields:   []zapcore.Field{zap.Error(errors.New("first")), zap.Error(errors.New("second"))},
+			expected: `{"msg":"message","error":"first","error":"second"}`,
+		},
+		{
+			name:     "LastKeyWins",
+			policy:   flogging.LastKeyWins,
+			fields:   []zapcore.Field{zap.Error(errors.New("first")), zap.Int("n", 1), zap.Error(errors.New("second"))},
+			expected: `{"msg":"message","n":1,"error":"second"}`,
+		},
+		{
+			name:     "Rename",
+			policy:   flogging.RenameDuplicateKeys,
+			fields:   []zapcore.Field{zap.Error(errors.New("first")), zap.String("error.1", "taken"), zap.Error(errors.New("second")), zap.Error(errors.New("third"))},
+			expected: `{"msg":"message","error":"first","error.1":"taken","error.2":"second","error.3":"third"}`,
+		},
+		{
+			name:     "LastKeyWinsWith",
+			policy:   flogging.LastKeyWins,
+			with:     []zapcore.Field{zap.String("key", "with"), zap.String("key", "with-last")},
+			fields:   []zapcore.Field{zap.String("key", "entry"), zap.String("key", "entry-last")},
+			expected: `{"msg":"message","key":"with-last","key.1":"entry-last"}`,
+		},
+		{
+			name:     "RenameWith",
+			policy:   flogging.RenameDuplicateKeys,
+			with:     []zapcore.Field{zap.String("key", "with")},
+			fields:   []zapcore.Field{zap.String("key", "entry")},
+			expected: `{"msg":"message","key":"with","key.1":"entry"}`,
+		},
+		{
+			name:     "Namespaces",
+			policy:   flogging.RenameDuplicateKeys,
+			with:     []zapcore.Field{zap.String("key", "outer"), zap.Namespace("ns")},
+			fields:   []zapcore.Field{zap.String("key", "inner"), zap.String("key", "inner-again"), zap.Namespace("ns"), zap.String("key", "nested")},
+			expected: `{"msg":"message","key":"outer","ns":{"key":"inner","key.1":"inner-again","ns":{"key":"nested"}}}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			core := zapcore.Core(newDuplicateKeysCore(buf, tc.policy))
+			if tc.with != nil {
+				core = core.With(tc.with)
+			}
+
+			fields := append([]zapcore.Field{}, tc.fields...)
+			err := core.Write(zapcore.Entry{Message: "message"}, fields)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected+"\n", buf.String())
+			assert.Equal(t, tc.fields, fields, "caller fields must not be modified")
+			if tc.policy != flogging.AllowDuplicateKeys {
+				assert.Empty(t, duplicateKeys(t, buf.String()))
+			}
+		})
+	}
+}
+
+func TestCoreDuplicateKeysSynthetic(t *testing.T) {
+	var tests = []struct {
+		name     string
+		policy   flogging.DuplicateKeyPolicy
+		with     []zapcore.Field
+		expected string
+	}{
+		{
+			name:     "LastKeyWins",
+			policy:   flogging.LastKeyWins,
+			expected: `{"msg":"message","fields_truncated":1,"seq":1}`,
+		},
+		{
+			name:     "Rename",
+			policy:   flogging.RenameDuplicateKeys,
+			expected: `{"msg":"message","seq":"entry","fields_truncated":1,"seq.1":1}`,
+		},
+		{
+			name:     "LastKeyWinsWith",
+			policy:   flogging.LastKeyWins,
+			with:     []zapcore.Field{zap.String("seq", "with")},
+			expected: `{"msg":"message","seq":"with","fields_truncated":2,"seq.1":1}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			core := newDuplicateKeysCore(buf, tc.policy)
+			core.Sequence = flogging.NewSequenceCounter()
+			core.MaxFields = 1
+
+			fields := []zapcore.Field{zap.String("seq", "entry"), zap.String("dropped", "value")}
+			err := core.With(tc.with).Write(zapcore.Entry{Message: "message"}, fields)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected+"\n", buf.String())
+			assert.Empty(t, duplicateKeys(t, buf.String()))
+		})
+	}
+}
+
+func TestCoreDuplicateKeysStrict(t *testing.T) {
+	keys := []string{"error", "error.1", "key", "ns"}
+	for _, policy := range []flogging.DuplicateKeyPolicy{flogging.LastKeyWins, flogging.RenameDuplicateKeys} {
+		buf := &bytes.Buffer{}
+		core := newDuplicateKeysCore(buf, policy)
+		for i := 0; i < 50; i++ {
+			var fields []zapcore.Field
+			for j := 0; j < 6; j++ {
+				fields = append(fields, zap.Int(keys[(i+j*j)%len(keys)], j))
+			}
+			err := core.With(fields[:i%3]).Write(zapcore.Entry{Message: "message"}, fields[i%3:])
+			require.NoError(t, err)
+		}
+
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			assert.Empty(t, duplicateKeys(t, line), "policy %d: %s", policy, line)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"os"
+	"sync"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap/zapcore"
+)
+
+// A DynamicMultiSyncer is a zapcore.WriteSyncer that writes every record to a
+// set of named sinks that can be changed while records are being written. It
+// allows an operator to attach a temporary sink, such as a socket, during an
+// incident and detach it afterward without restarting the process.
+//
+// Records are written to the sinks in the order they were added. Sinks may be
+// written concurrently by several writers and must be safe for concurrent use.
+//
+// Sinks that write to the same underlying file are collapsed so a record is
+// written to the file once, by the first of the sinks that was added. Sinks
+// are identified by the os.FileInfo returned by their Stat method, as
+// implemented by *os.File; sinks without a Stat method are always written.
+type DynamicMultiSyncer struct {
+	mutex  sync.RWMutex
+	names  []string
+	sinks  map[string]zapcore.WriteSyncer
+	files  map[string]os.FileInfo
+	active []string // names of the sinks that are written
+}
+
+// NewDynamicMultiSyncer creates a DynamicMultiSyncer without any sinks.
+func NewDynamicMultiSyncer() *DynamicMultiSyncer {
+	return &DynamicMultiSyncer{
+		sinks: map[string]zapcore.WriteSyncer{},
+		files: map[string]os.FileInfo{},
+	}
+}
+
+// AddSink adds a sink that receives the records written after AddSink
+// returns. An error is returned if a sink with the name already exists.
+func (d *DynamicMultiSyncer) AddSink(name string, sink zapcore.WriteSyncer) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if _, ok := d.sinks[name]; ok {
+		return errors.Errorf("sink '%s' already exists", name)
+	}
+	d.sinks[name] = sink
+	d.names = append(d.names, name)
+	if info, ok := sinkFile(sink); ok {
+		d.files[name] = info
+	}
+	d.updateActive()
+	return nil
+}
+
+// RemoveSink detaches the named sink and syncs it. Records that are being
+// written when RemoveSink is called are written to the sink before it is
+// detached; no records are written to it after RemoveSink returns.
+func (d *DynamicMultiSyncer) RemoveSink(name string) error {
+	d.mutex.Lock()
+	sink, ok := d.sinks[name]
+	if ok {
+		delete(d.sinks, name)
+		delete(d.files, name)
+		names := make([]string, 0, len(d.names)-1)
+		for _, n := range d.names {
+			if n != name {
+				names = append(names, n)
+			}
+		}
+		d.names = names
+		d.updateActive()
+	}
+	d.mutex.Unlock()
+
+	if !ok {
+		return errors.Errorf("sink '%s' does not exist", name)
+	}
+	if err := sink.Sync(); err != nil {
+		return errors.Wrapf(err, "failed to sync sink '%s'", name)
+	}
+	return nil
+}
+
+// Sinks returns the names of the attached sinks in the order they were added.
+func (d *DynamicMultiSyncer) Sinks() []string {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	return append([]string(nil), d.names...)
+}
+
+// Write writes the record to every attached sink, once for each underlying
+// file. The record is written to all of the sinks even when some of them
+// fail; the first failure is returned.
+func (d *DynamicMultiSyncer) Write(b []byte) (int, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	var err error
+	for _, name := range d.active {
+		if _, werr := d.sinks[name].Write(b); werr != nil && err == nil {
+			err = errors.Wrapf(werr, "failed to write to sink '%s'", name)
+		}
+	}
+	return len(b), err
+}
+
+// Sync syncs every attached sink and returns the first failure.
+func (d *DynamicMultiSyncer) Sync() error {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	var err error
+	for _, name := range d.names {
+		if serr := d.sinks[name].Sync(); serr != nil && err == nil {
+			err = errors.Wrapf(serr, "failed to sync sink '%s'", name)
+		}
+	}
+	return err
+}
+
+// updateActive determines the sinks that are written. A sink is skipped when
+// it writes to the same file as a sink that was added before it.
+func (d *DynamicMultiSyncer) updateActive() {
+	d.active = d.active[:0]
+	var seen []os.FileInfo
+	for _, name := range d.names {
+		info, ok := d.files[name]
+		if ok && sameFile(seen, info) {
+			continue
+		}
+		if ok {
+			seen = append(seen, info)
+		}
+		d.active = append(d.active, name)
+	}
+}
+
+// sinkFile returns the identity of the file the sink writes to.
+func sinkFile(sink zapcore.WriteSyncer) (os.FileInfo, bool) {
+	f, ok := sink.(interface{ Stat() (os.FileInfo, error) })
+	if !ok {
+		return nil, false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return nil, false
+	}
+	return info, true
+}
+
+func sameFile(infos []os.FileInfo, info os.FileInfo) bool {
+	for _, i := range infos {
+		if os.SameFile(i, info) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,216 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging_test
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// lockedSink is a WriteSyncer that is safe for concurrent use and records
+// when it is synced.
+type lockedSink struct {
+	mutex   sync.Mutex
+	buf     strings.Builder
+	syncs   int
+	syncErr error
+}
+
+func (l *lockedSink) Write(b []byte) (int, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.buf.Write(b)
+}
+
+func (l *lockedSink) Sync() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.syncs++
+	return l.syncErr
+}
+
+func (l *lockedSink) String() string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.buf.String()
+}
+
+func TestDynamicMultiSyncer(t *testing.T) {
+	syncer := flogging.NewDynamicMultiSyncer()
+	n, err := syncer.Write([]byte("nowhere\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, 8, n)
+	assert.Empty(t, syncer.Sinks())
+
+	primary, debug := &lockedSink{}, &lockedSink{}
+	require.NoError(t, syncer.AddSink("primary", primary))
+	_, err = syncer.Write([]byte("first\n"))
+	assert.NoError(t, err)
+
+	require.NoError(t, syncer.AddSink("debug", debug))
+	assert.EqualError(t, syncer.AddSink("debug", &lockedSink{}), "sink 'debug' already exists")
+	assert.Equal(t, []string{"primary", "debug"}, syncer.Sinks())
+	_, err = syncer.Write([]byte("second\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, syncer.Sync())
+	assert.Equal(t, 1, primary.syncs)
+	assert.Equal(t, 1, debug.syncs)
+
+	require.NoError(t, syncer.RemoveSink("debug"))
+	assert.Equal(t, 2, debug.syncs, "removal should flush the detached sink")
+	assert.EqualError(t, syncer.RemoveSink("debug"), "sink 'debug' does not exist")
+	_, err = syncer.Write([]byte("third\n"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, "first\nsecond\nthird\n", primary.String())
+	assert.Equal(t, "second\n", debug.String())
+	assert.Equal(t, []string{"primary"}, syncer.Sinks())
+
+	// a removed name can be reused
+	assert.NoError(t, syncer.AddSink("debug", debug))
+}
+
+func TestDynamicMultiSyncerErrors(t *testing.T) {
+	syncer := flogging.NewDynamicMultiSyncer()
+	failing := &sw{writeErr: errors.New("broken pipe"), syncErr: errors.New("sync failed")}
+	healthy := &lockedSink{}
+	require.NoError(t, syncer.AddSink("failing", failing))
+	require.NoError(t, syncer.AddSink("healthy", healthy))
+
+	_, err := syncer.Write([]byte("record\n"))
+	assert.EqualError(t, err, "failed to write to sink 'failing': broken pipe")
+	assert.Equal(t, "record\n", healthy.String(), "the record should reach the other sinks")
+	assert.EqualError(t, syncer.Sync(), "failed to sync sink 'failing': sync failed")
+	assert.Equal(t, 1, healthy.syncs)
+
+	assert.EqualError(t, syncer.RemoveSink("failing"), "failed to sync sink 'failing': sync failed")
+	assert.Equal(t, []string{"healthy"}, syncer.Sinks(), "the sink should be removed when the flush fails")
+}
+
+func TestDynamicMultiSyncerConcurrency(t *testing.T) {
+	syncer := flogging.NewDynamicMultiSyncer()
+	primary := &lockedSink{}
+	require.NoError(t, syncer.AddSink("primary", primary))
+
+	output := &sw{}
+	core := newTestConsoleCore(t, "info", output)
+	core.Output = syncer
+	logger := zap.New(core)
+
+	stop := make(chan struct{})
+	var writers sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		writers.Add(1)
+		go func(i int) {
+			defer writers.Done()
+			for j := 0; ; j++ {
+				select {
+				case <-stop:
+					return
+				default:
+					logger.Info(fmt.Sprintf("worker-%d-%d", i, j))
+				}
+			}
+		}(i)
+	}
+
+	for primary.String() == "" {
+		runtime.Gosched()
+	}
+
+	var detached []*lockedSink
+	for i := 0; i < 20; i++ {
+		sink := &lockedSink{}
+		name := fmt.Sprintf("debug-%d", i)
+		require.NoError(t, syncer.AddSink(name, sink))
+		syncer.Sinks()
+		require.NoError(t, syncer.RemoveSink(name))
+		detached = append(detached, sink)
+	}
+	close(stop)
+	writers.Wait()
+
+	for _, sink := range detached {
+		assert.Equal(t, 1, sink.syncs)
+		for _, line := range strings.SplitAfter(sink.String(), "\n") {
+			if line != "" {
+				assert.Regexp(t, `^\[\] INFO worker-\d-\d+\n$`, line, "records should not be split")
+			}
+		}
+	}
+	assert.NotEmpty(t, primary.String())
+	assert.Equal(t, []string{"primary"}, syncer.Sinks())
+}
+
+func TestDynamicMultiSyncerSameFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sinks")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	open := func(name string) *os.File {
+		f, err := os.OpenFile(filepath.Join(dir, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		require.NoError(t, err)
+		return f
+	}
+	first, second, other := open("peer.log"), open("peer.log"), open("other.log")
+	defer first.Close()
+	defer second.Close()
+	defer other.Close()
+	require.NoError(t, os.Symlink(filepath.Join(dir, "peer.log"), filepath.Join(dir, "link.log")))
+	link := open("link.log")
+	defer link.Close()
+	buffer := &lockedSink{}
+
+	syncer := flogging.NewDynamicMultiSyncer()
+	require.NoError(t, syncer.AddSink("first", first))
+	require.NoError(t, syncer.AddSink("second", second))
+	require.NoError(t, syncer.AddSink("link", link))
+	require.NoError(t, syncer.AddSink("other", other))
+	require.NoError(t, syncer.AddSink("buffer", buffer))
+	assert.Equal(t, []string{"first", "second", "link", "other", "buffer"}, syncer.Sinks())
+
+	_, err = syncer.Write([]byte("once\n"))
+	assert.NoError(t, err)
+
+	// the next sink on the same file takes over when the first is removed
+	require.NoError(t, syncer.RemoveSink("first"))
+	_, err = syncer.Write([]byte("again\n"))
+	assert.NoError(t, err)
+
+	contents := func(name string) string {
+		b, err := ioutil.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		return string(b)
+	}
+	assert.Equal(t, "once\nagain\n", contents("peer.log"), "sinks on the same file should receive the bytes once")
+	assert.Equal(t, "once\nagain\n", contents("other.log"))
+	assert.Equal(t, "once\nagain\n", buffer.String())
+}
+
+func TestDynamicMultiSyncerDistinctSinks(t *testing.T) {
+	first, second := &lockedSink{}, &lockedSink{}
+	syncer := flogging.NewDynamicMultiSyncer()
+	require.NoError(t, syncer.AddSink("first", first))
+	require.NoError(t, syncer.AddSink("second", second))
+
+	_, err := syncer.Write([]byte("record\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "record\n", first.String(), "sinks without a Stat method are always written")
+	assert.Equal(t, "record\n", second.String())
+}
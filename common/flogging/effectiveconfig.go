@@ -0,0 +1,61 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// A ConfigSource identifies why the effective logging configuration is being
+// recorded.
+type ConfigSource string
+
+const (
+	// ConfigSourceStartup records the configuration applied at startup.
+	ConfigSourceStartup ConfigSource = "startup"
+	// ConfigSourceRuntime records a configuration change made at runtime.
+	ConfigSourceRuntime ConfigSource = "runtime"
+)
+
+// EffectiveConfigLogger is the name of the logger used for entries written by
+// LogEffectiveConfig.
+const EffectiveConfigLogger = "flogging"
+
+// LogEffectiveConfig writes an entry that records the effective logging spec
+// and encoding of the core along with the source of the configuration. The
+// entry is written at InfoLevel regardless of the active spec so that the
+// configuration can always be audited.
+func LogEffectiveConfig(core *Core, source ConfigSource) error {
+	fields := []zapcore.Field{zap.String("source", string(source))}
+	if core.Levels != nil {
+		fields = append(fields, zap.String("spec", core.Levels.Spec()))
+	}
+	if core.Selector != nil && len(core.Sinks) == 0 && core.Delegate == nil {
+		fields = append(fields, zap.String("encoding", encodingName(core.Selector.Encoding())))
+	}
+
+	return core.Write(zapcore.Entry{
+		Level:      zapcore.InfoLevel,
+		Time:       time.Now(),
+		LoggerName: EffectiveConfigLogger,
+		Message:    "Effective logging configuration",
+	}, fields)
+}
+
+// encodingName returns the name used to select an encoding.
+func encodingName(e Encoding) string {
+	for name, encoding := range encodingNames {
+		if encoding == e {
+			return name
+		}
+	}
+	return fmt.Sprintf("%d", e)
+}
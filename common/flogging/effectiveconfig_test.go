@@ -0,0 +1,64 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLogEffectiveConfig(t *testing.T) {
+	buf := &bytes.Buffer{}
+	levels := &flogging.LoggerLevels{}
+	require.NoError(t, levels.ActivateSpec("error:gossip=debug"))
+	core := &flogging.Core{
+		LevelEnabler: levels,
+		Levels:       levels,
+		Encoders: map[flogging.Encoding]zapcore.Encoder{
+			flogging.JSON: zapcore.NewJSONEncoder(zapcore.EncoderConfig{MessageKey: "msg", NameKey: "logger", LevelKey: "level", EncodeLevel: zapcore.LowercaseLevelEncoder}),
+		},
+		Selector: fixedSelector(flogging.JSON),
+		Output:   zapcore.AddSync(buf),
+	}
+
+	err := flogging.LogEffectiveConfig(core, flogging.ConfigSourceStartup)
+	require.NoError(t, err)
+	var entry map[string]string
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, map[string]string{
+		"level":    "info",
+		"logger":   "flogging",
+		"msg":      "Effective logging configuration",
+		"source":   "startup",
+		"spec":     "gossip=debug:error",
+		"encoding": "json",
+	}, entry, "the entry is written even though the spec disables info")
+
+	buf.Reset()
+	require.NoError(t, levels.ActivateSpec("warn"))
+	err = flogging.LogEffectiveConfig(core, flogging.ConfigSourceRuntime)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "runtime", entry["source"])
+	assert.Equal(t, "warn", entry["spec"])
+}
+
+func TestLogEffectiveConfigConsole(t *testing.T) {
+	output := &sw{}
+	core := newTestConsoleCore(t, "info", output)
+	core.Selector = fixedSelector(flogging.CONSOLE)
+
+	err := flogging.LogEffectiveConfig(core, flogging.ConfigSourceStartup)
+	require.NoError(t, err)
+	assert.Equal(t, "[flogging] INFO Effective logging configuration source=startup spec=info encoding=console\n", output.String())
+}
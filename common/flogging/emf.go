@@ -0,0 +1,127 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// An EmbeddedMetric is a metric value published with the CloudWatch embedded
+// metric format.
+type EmbeddedMetric struct {
+	Name  string
+	Value float64
+	// Unit is a CloudWatch unit such as Seconds, Bytes, or Count. When Unit
+	// is empty, CloudWatch uses None.
+	Unit string
+}
+
+// EmbeddedMetrics returns the fields that publish metrics with the CloudWatch
+// embedded metric format when entries are encoded as JSON. The fields contain
+// the _aws metadata envelope, the dimension values, and the metric values.
+// The dimensions are published as a single dimension set.
+//
+// The metric timestamp is the time of the entry when the fields are written
+// by a core with the EmbeddedMetricsTimestamp transformer, which the loggers
+// created by Logging use. Otherwise it is the time the entry is encoded.
+func EmbeddedMetrics(namespace string, dimensions map[string]string, metrics ...EmbeddedMetric) []zapcore.Field {
+	names := make([]string, 0, len(dimensions))
+	for name := range dimensions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fields := make([]zapcore.Field, 0, 1+len(names)+len(metrics))
+	fields = append(fields, zap.Object(emfMetadataKey, emfMetadata{
+		namespace:  namespace,
+		dimensions: names,
+		metrics:    metrics,
+	}))
+	for _, name := range names {
+		fields = append(fields, zap.String(name, dimensions[name]))
+	}
+	for _, m := range metrics {
+		fields = append(fields, zap.Float64(m.Name, m.Value))
+	}
+	return fields
+}
+
+// EmbeddedMetricsTimestamp is an EntryTransformer that sets the timestamp of
+// the metrics published by EmbeddedMetrics fields to the time of the entry.
+func EmbeddedMetricsTimestamp(e zapcore.Entry, fields []zapcore.Field) (zapcore.Entry, []zapcore.Field) {
+	var result []zapcore.Field
+	for i, f := range fields {
+		metadata, ok := f.Interface.(emfMetadata)
+		if !ok || f.Type != zapcore.ObjectMarshalerType || f.Key != emfMetadataKey {
+			continue
+		}
+		if result == nil {
+			result = append([]zapcore.Field(nil), fields...)
+		}
+		metadata.timestamp = e.Time
+		result[i] = zap.Object(emfMetadataKey, metadata)
+	}
+	if result == nil {
+		return e, fields
+	}
+	return e, result
+}
+
+const emfMetadataKey = "_aws"
+
+type emfMetadata struct {
+	timestamp  time.Time
+	namespace  string
+	dimensions []string
+	metrics    []EmbeddedMetric
+}
+
+func (e emfMetadata) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	timestamp := e.timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	enc.AddInt64("Timestamp", timestamp.UnixNano()/int64(time.Millisecond))
+	return enc.AddArray("CloudWatchMetrics", zapcore.ArrayMarshalerFunc(func(arr zapcore.ArrayEncoder) error {
+		return arr.AppendObject(zapcore.ObjectMarshalerFunc(e.directive))
+	}))
+}
+
+func (e emfMetadata) directive(enc zapcore.ObjectEncoder) error {
+	enc.AddString("Namespace", e.namespace)
+	err := enc.AddArray("Dimensions", zapcore.ArrayMarshalerFunc(func(arr zapcore.ArrayEncoder) error {
+		return arr.AppendArray(zapcore.ArrayMarshalerFunc(func(set zapcore.ArrayEncoder) error {
+			for _, name := range e.dimensions {
+				set.AppendString(name)
+			}
+			return nil
+		}))
+	}))
+	if err != nil {
+		return err
+	}
+	return enc.AddArray("Metrics", zapcore.ArrayMarshalerFunc(func(arr zapcore.ArrayEncoder) error {
+		for _, m := range e.metrics {
+			m := m
+			err := arr.AppendObject(zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+				enc.AddString("Name", m.Name)
+				if m.Unit != "" {
+					enc.AddString("Unit", m.Unit)
+				}
+				return nil
+			}))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+}
@@ -0,0 +1,84 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestEmbeddedMetrics(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logging, err := flogging.New(flogging.Config{Format: "json", Writer: buf})
+	require.NoError(t, err)
+
+	before := time.Now().UnixNano() / int64(time.Millisecond)
+	fields := flogging.EmbeddedMetrics(
+		"Fabric/Peer",
+		map[string]string{"Channel": "testchannel", "Chaincode": "mycc"},
+		flogging.EmbeddedMetric{Name: "ProposalDuration", Value: 12.5, Unit: "Milliseconds"},
+		flogging.EmbeddedMetric{Name: "Proposals", Value: 1},
+	)
+	logging.ZapLogger("endorser").Info("proposal endorsed", fields...)
+	after := time.Now().UnixNano() / int64(time.Millisecond)
+
+	var entry map[string]interface{}
+	err = json.Unmarshal(buf.Bytes(), &entry)
+	require.NoError(t, err)
+	assert.Equal(t, "proposal endorsed", entry["msg"])
+	assert.Equal(t, "testchannel", entry["Channel"])
+	assert.Equal(t, "mycc", entry["Chaincode"])
+	assert.Equal(t, 12.5, entry["ProposalDuration"])
+	assert.Equal(t, float64(1), entry["Proposals"])
+
+	aws, ok := entry["_aws"].(map[string]interface{})
+	require.True(t, ok)
+	timestamp, ok := aws["Timestamp"].(float64)
+	require.True(t, ok)
+	assert.True(t, int64(timestamp) >= before && int64(timestamp) <= after)
+
+	directives, err := json.Marshal(aws["CloudWatchMetrics"])
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{
+		"Namespace": "Fabric/Peer",
+		"Dimensions": [["Chaincode", "Channel"]],
+		"Metrics": [{"Name": "ProposalDuration", "Unit": "Milliseconds"}, {"Name": "Proposals"}]
+	}]`, string(directives))
+}
+
+func TestEmbeddedMetricsEntryTime(t *testing.T) {
+	output := &sw{}
+	core := newTestConsoleCore(t, "info", output)
+	core.Encoders[flogging.JSON] = zapcore.NewJSONEncoder(zapcore.EncoderConfig{MessageKey: "msg"})
+	core.Selector = fixedSelector(flogging.JSON)
+	core.Transformers = []flogging.EntryTransformer{flogging.EmbeddedMetricsTimestamp}
+
+	// the entry is written long after the fields were created
+	fields := flogging.EmbeddedMetrics("Fabric", nil, flogging.EmbeddedMetric{Name: "Count", Value: 1})
+	original := append([]zapcore.Field(nil), fields...)
+	entryTime := time.Unix(1000, 0)
+	err := core.Write(zapcore.Entry{Time: entryTime, Message: "deferred"}, fields)
+	require.NoError(t, err)
+	assert.Contains(t, output.String(), `"_aws":{"Timestamp":1000000,`)
+	assert.Equal(t, original, fields, "input fields must not be modified")
+}
+
+func TestEmbeddedMetricsWithoutDimensions(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logging, err := flogging.New(flogging.Config{Format: "json", Writer: buf})
+	require.NoError(t, err)
+
+	logging.ZapLogger("emf").Info("sample", flogging.EmbeddedMetrics("Fabric", nil, flogging.EmbeddedMetric{Name: "Count", Value: 2, Unit: "Count"})...)
+	assert.Contains(t, buf.String(), `"CloudWatchMetrics":[{"Namespace":"Fabric","Dimensions":[[]],"Metrics":[{"Name":"Count","Unit":"Count"}]}]},"Count":2}`)
+}
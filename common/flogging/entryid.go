@@ -0,0 +1,96 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// EntryIDKey is the field key used for the random ids added to entries by
+	// the EntryIDCore.
+	EntryIDKey = "entry_id"
+	// TraceIDKey is the field key conventionally used for trace ids.
+	TraceIDKey = "trace_id"
+)
+
+// DefaultEntryIDKeys are the keys of the fields that identify an entry.
+var DefaultEntryIDKeys = []string{CorrelationIDKey, TraceIDKey, EntryIDKey}
+
+// An EntryIDCore is a zapcore.Core that adds a short random id to every entry
+// that is not already identified by a correlation or trace id. This makes
+// every line individually addressable in environments without distributed
+// tracing, such as when referencing a line in a support ticket.
+//
+// Identifying fields added to a logger with With are remembered so the
+// entries written by the derived logger are left alone.
+type EntryIDCore struct {
+	zapcore.Core
+
+	// Keys lists the keys of the fields that identify an entry. Entries with
+	// any of these fields do not receive an id.
+	Keys []string
+
+	identified bool
+}
+
+// NewEntryIDCore creates an EntryIDCore that delegates to the provided core
+// and treats the fields in DefaultEntryIDKeys as identifying.
+func NewEntryIDCore(core zapcore.Core) *EntryIDCore {
+	return &EntryIDCore{
+		Core: core,
+		Keys: DefaultEntryIDKeys,
+	}
+}
+
+func (c *EntryIDCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.Core = c.Core.With(fields)
+	clone.identified = c.identified || c.hasID(fields)
+	return &clone
+}
+
+func (c *EntryIDCore) Check(e zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return checkWrapped(c.Core, e, ce, c, c.write)
+}
+
+// Write adds an EntryIDKey field to entries that are not identified by their
+// fields or the fields of the logger.
+func (c *EntryIDCore) Write(e zapcore.Entry, fields []zapcore.Field) error {
+	return c.write(e, fields, c.Core.Write)
+}
+
+func (c *EntryIDCore) write(e zapcore.Entry, fields []zapcore.Field, next writeFunc) error {
+	if !c.identified && !c.hasID(fields) {
+		if id := newEntryID(); id != "" {
+			fields = append(fields[:len(fields):len(fields)], zap.String(EntryIDKey, id))
+		}
+	}
+	return next(e, fields)
+}
+
+func (c *EntryIDCore) hasID(fields []zapcore.Field) bool {
+	for _, key := range c.Keys {
+		if hasKey(fields, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// newEntryID returns eight random hex characters.
+func newEntryID() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,93 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestEntryIDCore(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	logger := zap.New(flogging.NewEntryIDCore(core))
+
+	logger.Info("first")
+	logger.Info("second")
+	logger.Debug("disabled")
+	logger.Info("correlated", zap.String("correlation_id", "abc"))
+	logger.Info("traced", zap.String("trace_id", "def"))
+	logger.Info("identified", zap.String("entry_id", "ghi"))
+
+	entries := logs.AllUntimed()
+	require.Len(t, entries, 5)
+	first, second := entries[0].ContextMap()["entry_id"], entries[1].ContextMap()["entry_id"]
+	assert.Regexp(t, `^[0-9a-f]{8}$`, first)
+	assert.Regexp(t, `^[0-9a-f]{8}$`, second)
+	assert.NotEqual(t, first, second, "ids should be unique per entry")
+	assert.Equal(t, map[string]interface{}{"correlation_id": "abc"}, entries[2].ContextMap())
+	assert.Equal(t, map[string]interface{}{"trace_id": "def"}, entries[3].ContextMap())
+	assert.Equal(t, map[string]interface{}{"entry_id": "ghi"}, entries[4].ContextMap())
+}
+
+func TestEntryIDCoreWith(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	logger := zap.New(flogging.NewEntryIDCore(core))
+
+	logger.With(zap.String("correlation_id", "abc")).With(zap.String("key", "value")).Info("correlated")
+	logger.With(zap.String("key", "value")).Info("uncorrelated")
+
+	entries := logs.AllUntimed()
+	require.Len(t, entries, 2)
+	assert.Equal(t, map[string]interface{}{"correlation_id": "abc", "key": "value"}, entries[0].ContextMap())
+	assert.Contains(t, entries[1].ContextMap(), "entry_id")
+}
+
+func TestEntryIDCoreKeys(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	entryIDs := flogging.NewEntryIDCore(core)
+	entryIDs.Keys = []string{"request_id"}
+	logger := zap.New(entryIDs)
+
+	logger.Info("request", zap.String("request_id", "abc"))
+	logger.Info("traced", zap.String("trace_id", "def"))
+
+	entries := logs.AllUntimed()
+	require.Len(t, entries, 2)
+	assert.NotContains(t, entries[0].ContextMap(), "entry_id")
+	assert.Contains(t, entries[1].ContextMap(), "entry_id")
+}
+
+func TestLoggingEntryIDs(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logging, err := flogging.New(flogging.Config{Format: "json", Writer: buf, EntryIDs: true})
+	assert.NoError(t, err)
+
+	logger := logging.Logger("entryid")
+	logger.Info("message")
+	logger.Infow("message", "trace_id", "abc")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Regexp(t, `^[0-9a-f]{8}$`, entry["entry_id"])
+	assert.NotContains(t, lines[1], "entry_id")
+
+	buf.Reset()
+	logging.SetEntryIDs(false)
+	logging.Logger("entryid").Info("message")
+	assert.NotContains(t, buf.String(), "entry_id")
+}
@@ -0,0 +1,33 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import "go.uber.org/zap/zapcore"
+
+// EntryToMap materializes an entry and its fields as a generic map. The fields
+// are added with a zapcore.MapObjectEncoder so nested objects and arrays are
+// represented as maps and slices and errors are represented by their message.
+// The entry is recorded under the ts, level, logger, and msg keys, and under
+// the caller and stacktrace keys when present. These keys take precedence over
+// fields with the same key.
+func EntryToMap(e zapcore.Entry, fields []zapcore.Field) map[string]interface{} {
+	enc := zapcore.NewMapObjectEncoder()
+	addFields(enc, fields)
+
+	m := enc.Fields
+	m["ts"] = e.Time
+	m["level"] = e.Level.String()
+	m["logger"] = e.LoggerName
+	m["msg"] = e.Message
+	if e.Caller.Defined {
+		m["caller"] = e.Caller.TrimmedPath()
+	}
+	if e.Stack != "" {
+		m["stacktrace"] = e.Stack
+	}
+	return m
+}
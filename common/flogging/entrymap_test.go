@@ -0,0 +1,67 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestEntryToMap(t *testing.T) {
+	ts := time.Unix(1590000000, 0)
+	e := zapcore.Entry{
+		Level:      zapcore.WarnLevel,
+		Time:       ts,
+		LoggerName: "peer.gossip",
+		Message:    "message",
+	}
+	fields := []zapcore.Field{
+		zap.String("string", "value"),
+		zap.Int("int", 42),
+		zap.Float64("float", 1.5),
+		zap.Bool("bool", true),
+		zap.Duration("duration", time.Second),
+		zap.Strings("strings", []string{"a", "b"}),
+		flogging.SortedMap("labels", map[string]string{"b": "2", "a": "1"}),
+		zap.Error(errors.New("boom")),
+	}
+
+	assert.Equal(t, map[string]interface{}{
+		"ts":       ts,
+		"level":    "warn",
+		"logger":   "peer.gossip",
+		"msg":      "message",
+		"string":   "value",
+		"int":      int64(42),
+		"float":    1.5,
+		"bool":     true,
+		"duration": time.Second,
+		"strings":  []interface{}{"a", "b"},
+		"labels":   map[string]interface{}{"a": "1", "b": "2"},
+		"error":    "boom",
+	}, flogging.EntryToMap(e, fields))
+}
+
+func TestEntryToMapCallerAndStack(t *testing.T) {
+	e := zapcore.Entry{
+		Message: "message",
+		Caller:  zapcore.NewEntryCaller(0, "/src/github.com/hyperledger/fabric/core/peer.go", 10, true),
+		Stack:   "stack",
+	}
+	m := flogging.EntryToMap(e, []zapcore.Field{zap.String("msg", "field"), zap.String("key", "value")})
+	assert.Equal(t, "core/peer.go:10", m["caller"])
+	assert.Equal(t, "stack", m["stacktrace"])
+	assert.Equal(t, "message", m["msg"], "entry keys take precedence over fields")
+	assert.Equal(t, "value", m["key"])
+	assert.Equal(t, "", m["logger"])
+}
@@ -0,0 +1,50 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// encodingNames maps the names accepted by EnvEncodingSelector to encodings.
+var encodingNames = map[string]Encoding{
+	"console":            CONSOLE,
+	"json":               JSON,
+	"logfmt":             LOGFMT,
+	"cbor":               CBOR,
+	"msgpack":            MSGPACK,
+	"deterministic-json": DETERMINISTIC_JSON,
+	"ecs":                ECS,
+}
+
+// A fixedEncoding is an EncodingSelector that always selects the same
+// encoding.
+type fixedEncoding Encoding
+
+func (f fixedEncoding) Encoding() Encoding { return Encoding(f) }
+
+// EnvEncodingSelector returns an EncodingSelector for the encoding named by the
+// environment variable varName, such as FABRIC_LOGGING_FORMAT. The variable is
+// read once when the selector is created and its value is matched without
+// regard to case against console, json, logfmt, cbor, msgpack,
+// deterministic-json, and ecs. When the variable is unset or empty, the
+// default encoding is selected. An error is returned for any other value.
+func EnvEncodingSelector(varName string, defaultEncoding Encoding) (EncodingSelector, error) {
+	value := strings.TrimSpace(os.Getenv(varName))
+	if value == "" {
+		return fixedEncoding(defaultEncoding), nil
+	}
+
+	encoding, ok := encodingNames[strings.ToLower(value)]
+	if !ok {
+		return nil, errors.Errorf("invalid encoding '%s' in %s", value, varName)
+	}
+	return fixedEncoding(encoding), nil
+}
@@ -0,0 +1,75 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvEncodingSelector(t *testing.T) {
+	const varName = "FLOGGING_TEST_FORMAT"
+	defer os.Unsetenv(varName)
+
+	var tests = []struct {
+		value    string
+		expected flogging.Encoding
+	}{
+		{value: "", expected: flogging.JSON},
+		{value: "console", expected: flogging.CONSOLE},
+		{value: "json", expected: flogging.JSON},
+		{value: "logfmt", expected: flogging.LOGFMT},
+		{value: "cbor", expected: flogging.CBOR},
+		{value: "msgpack", expected: flogging.MSGPACK},
+		{value: "deterministic-json", expected: flogging.DETERMINISTIC_JSON},
+		{value: "ecs", expected: flogging.ECS},
+		{value: " LogFmt ", expected: flogging.LOGFMT},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.value, func(t *testing.T) {
+			os.Setenv(varName, tc.value)
+			selector, err := flogging.EnvEncodingSelector(varName, flogging.JSON)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, selector.Encoding())
+		})
+	}
+}
+
+func TestEnvEncodingSelectorReadsOnce(t *testing.T) {
+	const varName = "FLOGGING_TEST_FORMAT"
+	defer os.Unsetenv(varName)
+
+	os.Setenv(varName, "logfmt")
+	selector, err := flogging.EnvEncodingSelector(varName, flogging.CONSOLE)
+	require.NoError(t, err)
+	os.Setenv(varName, "json")
+	assert.EqualValues(t, flogging.LOGFMT, selector.Encoding())
+}
+
+func TestEnvEncodingSelectorUnset(t *testing.T) {
+	const varName = "FLOGGING_TEST_FORMAT"
+	os.Unsetenv(varName)
+
+	selector, err := flogging.EnvEncodingSelector(varName, flogging.CONSOLE)
+	require.NoError(t, err)
+	assert.EqualValues(t, flogging.CONSOLE, selector.Encoding())
+}
+
+func TestEnvEncodingSelectorInvalid(t *testing.T) {
+	const varName = "FLOGGING_TEST_FORMAT"
+	defer os.Unsetenv(varName)
+
+	os.Setenv(varName, "xml")
+	selector, err := flogging.EnvEncodingSelector(varName, flogging.CONSOLE)
+	assert.EqualError(t, err, "invalid encoding 'xml' in FLOGGING_TEST_FORMAT")
+	assert.Nil(t, selector)
+}
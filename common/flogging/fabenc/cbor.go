@@ -0,0 +1,247 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fabenc
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// CBOR major types as defined by RFC 7049.
+const (
+	cborUnsigned byte = 0 << 5
+	cborNegative byte = 1 << 5
+	cborBytes    byte = 2 << 5
+	cborText     byte = 3 << 5
+	cborArray    byte = 4 << 5
+	cborMap      byte = 5 << 5
+	cborTag      byte = 6 << 5
+	cborSimple   byte = 7 << 5
+)
+
+const (
+	cborFalse   byte = cborSimple | 20
+	cborTrue    byte = cborSimple | 21
+	cborNull    byte = cborSimple | 22
+	cborFloat64 byte = cborSimple | 27
+
+	cborEpochTimeTag = 1
+)
+
+// A CBOREncoder is a zapcore.Encoder that serializes log entries as CBOR
+// (RFC 7049) maps. Each entry is encoded as a map with the entry time (ts),
+// level, logger name (logger), message (msg), and a nested map of the
+// structured fields (fields). The caller and stacktrace are included when
+// present.
+//
+// Time values are encoded as epoch-based date/time (tag 1) floating point
+// values and durations are encoded as integer nanoseconds.
+type CBOREncoder struct {
+	*zapcore.MapObjectEncoder
+	pool buffer.Pool
+}
+
+// NewCBOREncoder creates a new CBOREncoder.
+func NewCBOREncoder() *CBOREncoder {
+	return &CBOREncoder{
+		MapObjectEncoder: zapcore.NewMapObjectEncoder(),
+		pool:             buffer.NewPool(),
+	}
+}
+
+// Clone creates a new instance of this encoder with the same fields.
+func (c *CBOREncoder) Clone() zapcore.Encoder {
+	return &CBOREncoder{
+		MapObjectEncoder: cloneMapObjectEncoder(c.MapObjectEncoder),
+		pool:             c.pool,
+	}
+}
+
+// EncodeEntry encodes the entry and fields as a CBOR map.
+func (c *CBOREncoder) EncodeEntry(entry zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	enc := cloneMapObjectEncoder(c.MapObjectEncoder)
+	for i := range fields {
+		fields[i].AddTo(enc)
+	}
+
+	record := map[string]interface{}{
+		"ts":     entry.Time,
+		"level":  entry.Level.String(),
+		"logger": entry.LoggerName,
+		"msg":    entry.Message,
+		"fields": enc.Fields,
+	}
+	if entry.Caller.Defined {
+		record["caller"] = entry.Caller.TrimmedPath()
+	}
+	if entry.Stack != "" {
+		record["stacktrace"] = entry.Stack
+	}
+
+	buf := c.pool.Get()
+	if err := appendCBOR(buf, record); err != nil {
+		buf.Free()
+		return nil, err
+	}
+	return buf, nil
+}
+
+// cloneMapObjectEncoder performs a deep copy of the fields held by a
+// zapcore.MapObjectEncoder.
+func cloneMapObjectEncoder(m *zapcore.MapObjectEncoder) *zapcore.MapObjectEncoder {
+	clone := zapcore.NewMapObjectEncoder()
+	for k, v := range cloneMap(m.Fields) {
+		clone.Fields[k] = v
+	}
+	return clone
+}
+
+func cloneMap(m map[string]interface{}) map[string]interface{} {
+	clone := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		if nested, ok := v.(map[string]interface{}); ok {
+			v = cloneMap(nested)
+		}
+		clone[k] = v
+	}
+	return clone
+}
+
+func appendCBORHeader(buf *buffer.Buffer, major byte, n uint64) {
+	var b [9]byte
+	switch {
+	case n < 24:
+		buf.AppendByte(major | byte(n))
+		return
+	case n <= math.MaxUint8:
+		b[0], b[1] = major|24, byte(n)
+		buf.Write(b[:2])
+	case n <= math.MaxUint16:
+		b[0] = major | 25
+		binary.BigEndian.PutUint16(b[1:], uint16(n))
+		buf.Write(b[:3])
+	case n <= math.MaxUint32:
+		b[0] = major | 26
+		binary.BigEndian.PutUint32(b[1:], uint32(n))
+		buf.Write(b[:5])
+	default:
+		b[0] = major | 27
+		binary.BigEndian.PutUint64(b[1:], n)
+		buf.Write(b[:9])
+	}
+}
+
+func appendCBORInt(buf *buffer.Buffer, i int64) {
+	if i < 0 {
+		appendCBORHeader(buf, cborNegative, uint64(-(i + 1)))
+		return
+	}
+	appendCBORHeader(buf, cborUnsigned, uint64(i))
+}
+
+func appendCBORFloat(buf *buffer.Buffer, f float64) {
+	var b [9]byte
+	b[0] = cborFloat64
+	binary.BigEndian.PutUint64(b[1:], math.Float64bits(f))
+	buf.Write(b[:])
+}
+
+func appendCBORString(buf *buffer.Buffer, s string) {
+	appendCBORHeader(buf, cborText, uint64(len(s)))
+	buf.AppendString(s)
+}
+
+// appendCBOR encodes the values produced by a zapcore.MapObjectEncoder.
+func appendCBOR(buf *buffer.Buffer, v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		buf.AppendByte(cborNull)
+	case bool:
+		if v {
+			buf.AppendByte(cborTrue)
+		} else {
+			buf.AppendByte(cborFalse)
+		}
+	case string:
+		appendCBORString(buf, v)
+	case []byte:
+		appendCBORHeader(buf, cborBytes, uint64(len(v)))
+		buf.Write(v)
+	case int:
+		appendCBORInt(buf, int64(v))
+	case int64:
+		appendCBORInt(buf, v)
+	case int32:
+		appendCBORInt(buf, int64(v))
+	case int16:
+		appendCBORInt(buf, int64(v))
+	case int8:
+		appendCBORInt(buf, int64(v))
+	case uint:
+		appendCBORHeader(buf, cborUnsigned, uint64(v))
+	case uint64:
+		appendCBORHeader(buf, cborUnsigned, v)
+	case uint32:
+		appendCBORHeader(buf, cborUnsigned, uint64(v))
+	case uint16:
+		appendCBORHeader(buf, cborUnsigned, uint64(v))
+	case uint8:
+		appendCBORHeader(buf, cborUnsigned, uint64(v))
+	case uintptr:
+		appendCBORHeader(buf, cborUnsigned, uint64(v))
+	case float64:
+		appendCBORFloat(buf, v)
+	case float32:
+		appendCBORFloat(buf, float64(v))
+	case complex128, complex64:
+		appendCBORString(buf, fmt.Sprint(v))
+	case time.Duration:
+		appendCBORInt(buf, int64(v))
+	case time.Time:
+		appendCBORHeader(buf, cborTag, cborEpochTimeTag)
+		appendCBORFloat(buf, float64(v.UnixNano())/float64(time.Second))
+	case []interface{}:
+		appendCBORHeader(buf, cborArray, uint64(len(v)))
+		for _, e := range v {
+			if err := appendCBOR(buf, e); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		appendCBORHeader(buf, cborMap, uint64(len(keys)))
+		for _, k := range keys {
+			appendCBORString(buf, k)
+			if err := appendCBOR(buf, v[k]); err != nil {
+				return err
+			}
+		}
+	default:
+		// reflected values are normalized through their JSON representation
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		var generic interface{}
+		if err := json.Unmarshal(b, &generic); err != nil {
+			return err
+		}
+		return appendCBOR(buf, generic)
+	}
+	return nil
+}
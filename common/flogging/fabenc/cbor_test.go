@@ -0,0 +1,182 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fabenc_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging/fabenc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// cborTime is the decoded form of a tagged epoch time.
+type cborTime float64
+
+// decodeCBOR is a minimal RFC 7049 decoder for the subset of CBOR produced by
+// the encoder.
+func decodeCBOR(b []byte) (interface{}, []byte, error) {
+	if len(b) == 0 {
+		return nil, nil, errors.New("unexpected end of input")
+	}
+	major, info := b[0]>>5, b[0]&0x1f
+	b = b[1:]
+
+	var n uint64
+	switch {
+	case major == 7 && info == 27:
+		f := math.Float64frombits(binary.BigEndian.Uint64(b))
+		return f, b[8:], nil
+	case major == 7:
+		switch info {
+		case 20:
+			return false, b, nil
+		case 21:
+			return true, b, nil
+		case 22:
+			return nil, b, nil
+		}
+		return nil, nil, fmt.Errorf("unsupported simple value %d", info)
+	case info < 24:
+		n = uint64(info)
+	case info == 24:
+		n, b = uint64(b[0]), b[1:]
+	case info == 25:
+		n, b = uint64(binary.BigEndian.Uint16(b)), b[2:]
+	case info == 26:
+		n, b = uint64(binary.BigEndian.Uint32(b)), b[4:]
+	case info == 27:
+		n, b = binary.BigEndian.Uint64(b), b[8:]
+	default:
+		return nil, nil, fmt.Errorf("unsupported additional info %d", info)
+	}
+
+	switch major {
+	case 0:
+		return int64(n), b, nil
+	case 1:
+		return -1 - int64(n), b, nil
+	case 2:
+		return b[:n], b[n:], nil
+	case 3:
+		return string(b[:n]), b[n:], nil
+	case 4:
+		arr := []interface{}{}
+		for i := uint64(0); i < n; i++ {
+			v, rest, err := decodeCBOR(b)
+			if err != nil {
+				return nil, nil, err
+			}
+			arr, b = append(arr, v), rest
+		}
+		return arr, b, nil
+	case 5:
+		m := map[string]interface{}{}
+		for i := uint64(0); i < n; i++ {
+			k, rest, err := decodeCBOR(b)
+			if err != nil {
+				return nil, nil, err
+			}
+			v, rest, err := decodeCBOR(rest)
+			if err != nil {
+				return nil, nil, err
+			}
+			m[k.(string)], b = v, rest
+		}
+		return m, b, nil
+	case 6:
+		v, rest, err := decodeCBOR(b)
+		if err != nil || n != 1 {
+			return nil, nil, fmt.Errorf("unsupported tag %d: %v", n, err)
+		}
+		return cborTime(v.(float64)), rest, nil
+	}
+	return nil, nil, fmt.Errorf("unsupported major type %d", major)
+}
+
+func TestCBOREncoder(t *testing.T) {
+	ts := time.Unix(1590000000, 500000000)
+	enc := fabenc.NewCBOREncoder().Clone()
+	enc.AddString("persistent", "value")
+
+	buf, err := enc.EncodeEntry(
+		zapcore.Entry{
+			Level:      zapcore.WarnLevel,
+			Time:       ts,
+			LoggerName: "logger.name",
+			Message:    "this is a message",
+			Stack:      "stack",
+		},
+		[]zapcore.Field{
+			zap.Int("int", -300),
+			zap.Uint64("uint64", math.MaxUint64),
+			zap.Float64("float", 2.5),
+			zap.Bool("bool", true),
+			zap.Duration("duration", time.Second),
+			zap.Binary("binary", []byte{1, 2}),
+			zap.Strings("strings", []string{"a", "b"}),
+			zap.Any("reflected", struct{ A int }{A: 1}),
+			zap.Error(errors.New("boom")),
+		},
+	)
+	require.NoError(t, err)
+
+	decoded, rest, err := decodeCBOR(buf.Bytes())
+	require.NoError(t, err)
+	assert.Empty(t, rest)
+	assert.Equal(t, map[string]interface{}{
+		"ts":         cborTime(1590000000.5),
+		"level":      "warn",
+		"logger":     "logger.name",
+		"msg":        "this is a message",
+		"stacktrace": "stack",
+		"fields": map[string]interface{}{
+			"persistent": "value",
+			"int":        int64(-300),
+			"uint64":     int64(-1), // wraps in the test decoder
+			"float":      2.5,
+			"bool":       true,
+			"duration":   int64(time.Second),
+			"binary":     []byte{1, 2},
+			"strings":    []interface{}{"a", "b"},
+			"reflected":  map[string]interface{}{"A": 1.0},
+			"error":      "boom",
+		},
+	}, decoded)
+}
+
+func TestCBOREncoderClone(t *testing.T) {
+	enc := fabenc.NewCBOREncoder()
+	enc.AddString("parent", "value")
+	clone := enc.Clone()
+	clone.AddString("child", "value")
+
+	buf, err := enc.EncodeEntry(zapcore.Entry{}, nil)
+	require.NoError(t, err)
+	decoded, _, err := decodeCBOR(buf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"parent": "value"}, decoded.(map[string]interface{})["fields"])
+
+	buf, err = clone.EncodeEntry(zapcore.Entry{}, []zapcore.Field{zap.Int("call", 1)})
+	require.NoError(t, err)
+	decoded, _, err = decodeCBOR(buf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"parent": "value", "child": "value", "call": int64(1)}, decoded.(map[string]interface{})["fields"])
+}
+
+func TestCBOREncoderReflectedError(t *testing.T) {
+	enc := fabenc.NewCBOREncoder()
+	_, err := enc.EncodeEntry(zapcore.Entry{}, []zapcore.Field{zap.Reflect("bad", make(chan int))})
+	assert.EqualError(t, err, "json: unsupported type: chan int")
+}
@@ -8,6 +8,8 @@ package fabenc
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 )
 
 type Color uint8
@@ -37,3 +39,33 @@ func (c Color) Bold() string {
 }
 
 func ResetColor() string { return ColorNone.Normal() }
+
+// A Style is the color and SGR attributes used to render the level colored
+// portion of a CONSOLE entry.
+type Style struct {
+	Color     Color
+	Bold      bool
+	Dim       bool
+	Underline bool
+}
+
+// Sequence returns the SGR escape sequence that applies the style.
+func (s Style) Sequence() string {
+	var codes []string
+	if s.Color != ColorNone {
+		codes = append(codes, strconv.Itoa(int(s.Color)))
+	}
+	if s.Bold {
+		codes = append(codes, "1")
+	}
+	if s.Dim {
+		codes = append(codes, "2")
+	}
+	if s.Underline {
+		codes = append(codes, "4")
+	}
+	if len(codes) == 0 {
+		return ResetColor()
+	}
+	return "\x1b[" + strings.Join(codes, ";") + "m"
+}
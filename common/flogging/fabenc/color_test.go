@@ -38,3 +38,20 @@ func TestBoldColors(t *testing.T) {
 	assert.Equal(t, fabenc.ColorCyan.Bold(), "\x1b[36;1m")
 	assert.Equal(t, fabenc.ColorWhite.Bold(), "\x1b[37;1m")
 }
+
+func TestStyleSequence(t *testing.T) {
+	tests := []struct {
+		style    fabenc.Style
+		expected string
+	}{
+		{style: fabenc.Style{}, expected: "\x1b[0m"},
+		{style: fabenc.Style{Color: fabenc.ColorRed}, expected: "\x1b[31m"},
+		{style: fabenc.Style{Color: fabenc.ColorMagenta, Bold: true}, expected: "\x1b[35;1m"},
+		{style: fabenc.Style{Dim: true}, expected: "\x1b[2m"},
+		{style: fabenc.Style{Color: fabenc.ColorCyan, Bold: true, Dim: true, Underline: true}, expected: "\x1b[36;1;2;4m"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, tt.style.Sequence())
+	}
+	assert.Equal(t, fabenc.ColorYellow.Bold(), fabenc.Style{Color: fabenc.ColorYellow, Bold: true}.Sequence())
+}
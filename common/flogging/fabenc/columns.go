@@ -0,0 +1,71 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fabenc
+
+import (
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// AutoWidth sizes the level column to fit the widest level name.
+const AutoWidth = -1
+
+// Columns specifies the widths, in runes, of the columns used to align
+// CONSOLE output. The columns are produced by the time, level, module, and
+// message verbs of the format; the separators between them come from the
+// format itself. A width of zero leaves a column unaligned.
+type Columns struct {
+	Time    int
+	Level   int // AutoWidth fits the widest level name
+	Logger  int
+	Message int // aligns the fields that follow the message
+
+	// TruncateLogger, when true, shortens logger names that are wider than
+	// the Logger column to their trailing runes, prefixed with a ~. Long
+	// names otherwise extend past the column and push the rest of the line
+	// to the right.
+	TruncateLogger bool
+}
+
+// Enabled returns true when any of the columns are aligned.
+func (c Columns) Enabled() bool {
+	return c.Time != 0 || c.Level != 0 || c.Logger != 0 || c.Message != 0
+}
+
+func (c Columns) levelWidth(short bool) int {
+	if c.Level != AutoWidth {
+		return c.Level
+	}
+	if short {
+		return 1
+	}
+	return len("DPANIC")
+}
+
+// writeColumn writes the output of format to w, padded with spaces to width
+// runes. When truncate is true, output that is wider than the column is
+// shortened to fit.
+func writeColumn(w io.Writer, width int, truncate bool, format func(w io.Writer)) {
+	if width <= 0 {
+		format(w)
+		return
+	}
+
+	var sb strings.Builder
+	format(&sb)
+	s := sb.String()
+	n := utf8.RuneCountInString(s)
+	switch {
+	case n < width:
+		s += strings.Repeat(" ", width-n)
+	case n > width && truncate:
+		r := []rune(s)
+		s = "~" + string(r[len(r)-width+1:])
+	}
+	io.WriteString(w, s)
+}
@@ -0,0 +1,106 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fabenc_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging/fabenc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestColumnsEnabled(t *testing.T) {
+	assert.False(t, fabenc.Columns{}.Enabled())
+	assert.False(t, fabenc.Columns{TruncateLogger: true}.Enabled())
+	assert.True(t, fabenc.Columns{Level: fabenc.AutoWidth}.Enabled())
+	assert.True(t, fabenc.Columns{Logger: 10}.Enabled())
+}
+
+func TestEncodeColumns(t *testing.T) {
+	formatters, err := fabenc.ParseFormat("%{time:15:04:05} | %{level} | %{module} | %{message}")
+	require.NoError(t, err)
+	enc := fabenc.NewFormatEncoder(formatters...)
+	enc.Columns = fabenc.Columns{Time: 8, Level: fabenc.AutoWidth, Logger: 12, Message: 10}
+
+	ts := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	entries := []zapcore.Entry{
+		{Time: ts, Level: zapcore.InfoLevel, LoggerName: "peer", Message: "started"},
+		{Time: ts, Level: zapcore.DPanicLevel, LoggerName: "orderer.raft", Message: "bad"},
+		{Time: ts, Level: zapcore.WarnLevel, LoggerName: "gossip.comm", Message: "slow"},
+	}
+	var lines []string
+	for _, e := range entries {
+		line, err := enc.Clone().EncodeEntry(e, []zapcore.Field{zap.Int("n", 1)})
+		require.NoError(t, err)
+		lines = append(lines, line.String())
+	}
+
+	assert.Equal(t, []string{
+		"03:04:05 | INFO   | peer         | started    n=1\n",
+		"03:04:05 | DPANIC | orderer.raft | bad        n=1\n",
+		"03:04:05 | WARN   | gossip.comm  | slow       n=1\n",
+	}, lines)
+	for _, line := range lines[1:] {
+		assert.Equal(t, strings.Index(lines[0], "n=1"), strings.Index(line, "n=1"), "fields should align")
+	}
+}
+
+func TestEncodeColumnsOverflow(t *testing.T) {
+	formatters, err := fabenc.ParseFormat("%{level} %{module} %{message}")
+	require.NoError(t, err)
+	entry := zapcore.Entry{Level: zapcore.InfoLevel, LoggerName: "orderer.consensus.etcdraft", Message: "message"}
+
+	enc := fabenc.NewFormatEncoder(formatters...)
+	enc.Columns = fabenc.Columns{Level: 5, Logger: 10}
+	line, err := enc.EncodeEntry(entry, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "INFO  orderer.consensus.etcdraft message\n", line.String(), "long names extend the column")
+
+	enc.Columns.TruncateLogger = true
+	line, err = enc.Clone().EncodeEntry(entry, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "INFO  ~.etcdraft message\n", line.String())
+}
+
+func TestEncodeColumnsShortLevels(t *testing.T) {
+	formatters, err := fabenc.ParseFormat("%{color}%{level}%{color:reset}|%{message}")
+	require.NoError(t, err)
+	enc := fabenc.NewFormatEncoder(fabenc.NewMultiFormatter(formatters...))
+	enc.ShortLevels = true
+	enc.Columns = fabenc.Columns{Level: fabenc.AutoWidth}
+
+	line, err := enc.EncodeEntry(zapcore.Entry{Level: zapcore.WarnLevel, Message: "message"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "\x1b[33mW\x1b[0m|message\n", line.String())
+
+	enc.ShortLevels = false
+	enc.Columns.Level = 7
+	line, err = enc.EncodeEntry(zapcore.Entry{Level: zapcore.WarnLevel, Message: "message"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "\x1b[33mWARN   \x1b[0m|message\n", line.String())
+}
+
+func TestEncodeColumnsElidedTime(t *testing.T) {
+	formatters, err := fabenc.ParseFormat("%{time:15:04:05}|%{message}")
+	require.NoError(t, err)
+	enc := fabenc.NewFormatEncoder(formatters...)
+	enc.TimeElider = fabenc.NewTimeElider()
+	enc.Columns = fabenc.Columns{Time: 10}
+
+	ts := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	first, err := enc.EncodeEntry(zapcore.Entry{Time: ts, Message: "first"}, nil)
+	require.NoError(t, err)
+	second, err := enc.EncodeEntry(zapcore.Entry{Time: ts, Message: "second"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "03:04:05  |first\n", first.String())
+	assert.Equal(t, "          |second\n", second.String())
+}
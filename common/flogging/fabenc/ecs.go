@@ -0,0 +1,171 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fabenc
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// ECSVersion is the version of the Elastic Common Schema produced by the
+// ECSEncoder.
+const ECSVersion = "1.6.0"
+
+// ecsFieldPaths maps the keys of fields with a defined location in the
+// Elastic Common Schema to that location.
+var ecsFieldPaths = map[string]string{
+	"pod":       "kubernetes.pod.name",
+	"namespace": "kubernetes.namespace",
+}
+
+// An ECSEncoder is a zapcore.Encoder that produces JSON documents that follow
+// the Elastic Common Schema. The entry metadata is placed in the @timestamp,
+// message, log.level, log.logger, log.origin, and error.stack_trace fields,
+// the pod and namespace fields are moved below kubernetes, and an error field
+// with a string value, as added by zap.Error, is moved to error.message. Field
+// keys that contain dots are expanded into nested objects as required by ECS.
+// Fields that would replace the entry metadata or another field, or that would
+// extend a field that is not an object, are moved below labels with the dots
+// in their keys replaced by underscores and, when that collides with another
+// label, a numeric suffix.
+type ECSEncoder struct {
+	*zapcore.MapObjectEncoder
+}
+
+// NewECSEncoder creates an encoder for the Elastic Common Schema.
+func NewECSEncoder() *ECSEncoder {
+	return &ECSEncoder{MapObjectEncoder: zapcore.NewMapObjectEncoder()}
+}
+
+// Clone creates a new instance of this encoder with the same fields.
+func (e *ECSEncoder) Clone() zapcore.Encoder {
+	return &ECSEncoder{MapObjectEncoder: cloneMapObjectEncoder(e.MapObjectEncoder)}
+}
+
+// EncodeEntry encodes the entry as an ECS JSON document.
+func (e *ECSEncoder) EncodeEntry(entry zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	enc := cloneMapObjectEncoder(e.MapObjectEncoder)
+	for i := range fields {
+		fields[i].AddTo(enc)
+	}
+
+	doc := map[string]interface{}{}
+	setPath(doc, "@timestamp", entry.Time.UTC().Format("2006-01-02T15:04:05.000Z07:00"))
+	setPath(doc, "message", entry.Message)
+	setPath(doc, "log.level", entry.Level.String())
+	setPath(doc, "ecs.version", ECSVersion)
+	if entry.LoggerName != "" {
+		setPath(doc, "log.logger", entry.LoggerName)
+	}
+	if entry.Caller.Defined {
+		setPath(doc, "log.origin.file.name", entry.Caller.File)
+		setPath(doc, "log.origin.file.line", entry.Caller.Line)
+	}
+	if entry.Stack != "" {
+		setPath(doc, "error.stack_trace", entry.Stack)
+	}
+
+	// Paths are set in sorted order so a value always claims a key before
+	// the paths that extend it, which keeps the result deterministic. When
+	// several fields map to the same path, the field whose key is the path
+	// claims it and the others are moved below labels.
+	keys := make(map[string][]string, len(enc.Fields))
+	paths := make([]string, 0, len(enc.Fields))
+	for k, v := range enc.Fields {
+		path := k
+		if p, ok := ecsFieldPaths[k]; ok {
+			path = p
+		}
+		if _, ok := v.(string); ok && k == "error" {
+			path = "error.message"
+		}
+		if _, ok := keys[path]; !ok {
+			paths = append(paths, path)
+		}
+		keys[path] = append(keys[path], k)
+	}
+	sort.Strings(paths)
+
+	var labels map[string]interface{}
+	for _, path := range paths {
+		pathKeys := keys[path]
+		sort.Slice(pathKeys, func(i, j int) bool {
+			if (pathKeys[i] == path) != (pathKeys[j] == path) {
+				return pathKeys[i] == path
+			}
+			return pathKeys[i] < pathKeys[j]
+		})
+		for _, key := range pathKeys {
+			if setPath(doc, path, enc.Fields[key]) {
+				continue
+			}
+			if labels == nil {
+				labels = map[string]interface{}{}
+			}
+			labels[labelKey(labels, key)] = enc.Fields[key]
+		}
+	}
+	for key, value := range labels {
+		if !setPath(doc, "labels."+key, value) {
+			doc["labels."+key] = value
+		}
+	}
+
+	json := zapcore.NewJSONEncoder(zapcore.EncoderConfig{})
+	if err := addSortedFields(json, doc); err != nil {
+		return nil, err
+	}
+	return json.EncodeEntry(zapcore.Entry{}, nil)
+}
+
+// labelKey returns the key used below labels for a field key. Dots are
+// replaced by underscores and, when the result is already used by another
+// field, a numeric suffix is added so no value is lost.
+func labelKey(labels map[string]interface{}, key string) string {
+	label := strings.Replace(key, ".", "_", -1)
+	if _, ok := labels[label]; !ok {
+		return label
+	}
+	for i := 2; ; i++ {
+		suffixed := label + "_" + strconv.Itoa(i)
+		if _, ok := labels[suffixed]; !ok {
+			return suffixed
+		}
+	}
+}
+
+// setPath stores value in doc at the location described by the dotted path.
+// False is returned and doc is not modified when the location already holds a
+// value or when an element of the path holds a value that is not an object.
+func setPath(doc map[string]interface{}, path string, value interface{}) bool {
+	elements := strings.Split(path, ".")
+	for i, element := range elements[:len(elements)-1] {
+		if element == "" {
+			break
+		}
+		child, ok := doc[element]
+		if !ok {
+			child = map[string]interface{}{}
+			doc[element] = child
+		}
+		m, ok := child.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		doc = m
+		path = strings.Join(elements[i+1:], ".")
+	}
+	if _, ok := doc[path]; ok {
+		return false
+	}
+	doc[path] = value
+	return true
+}
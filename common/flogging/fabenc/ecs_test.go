@@ -0,0 +1,124 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fabenc_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging/fabenc"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestECSEncoder(t *testing.T) {
+	enc := fabenc.NewECSEncoder()
+	enc.AddString("pod", "peer0-7d9f")
+	enc.AddString("namespace", "org1")
+	clone := enc.Clone()
+
+	entry := zapcore.Entry{
+		Level:      zapcore.WarnLevel,
+		Time:       time.Date(2020, 4, 1, 12, 30, 45, 123456789, time.FixedZone("EST", -5*60*60)),
+		LoggerName: "peer.gossip",
+		Message:    "message",
+		Caller:     zapcore.EntryCaller{Defined: true, File: "gossip/state.go", Line: 42},
+		Stack:      "goroutine 1",
+	}
+	line, err := clone.EncodeEntry(entry, []zapcore.Field{
+		zap.String("channel", "testchannel"),
+		zap.String("http.request.method", "GET"),
+		zap.Int("http.response.status_code", 200),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `{`+
+		`"@timestamp":"2020-04-01T17:30:45.123Z",`+
+		`"channel":"testchannel",`+
+		`"ecs":{"version":"1.6.0"},`+
+		`"error":{"stack_trace":"goroutine 1"},`+
+		`"http":{"request":{"method":"GET"},"response":{"status_code":200}},`+
+		`"kubernetes":{"namespace":"org1","pod":{"name":"peer0-7d9f"}},`+
+		`"log":{"level":"warn","logger":"peer.gossip","origin":{"file":{"line":42,"name":"gossip/state.go"}}},`+
+		`"message":"message"`+
+		"}\n",
+		line.String(),
+	)
+
+	// the entry metadata is omitted when it is not available and fields added
+	// to an entry do not leak into the encoder
+	line, err = enc.EncodeEntry(zapcore.Entry{Level: zapcore.InfoLevel, Time: time.Unix(0, 0), Message: "minimal"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"@timestamp":"1970-01-01T00:00:00.000Z","ecs":{"version":"1.6.0"},`+
+		`"kubernetes":{"namespace":"org1","pod":{"name":"peer0-7d9f"}},"log":{"level":"info"},"message":"minimal"}`+"\n",
+		line.String(),
+	)
+}
+
+func TestECSEncoderConflictingPaths(t *testing.T) {
+	enc := fabenc.NewECSEncoder()
+	line, err := enc.EncodeEntry(zapcore.Entry{Time: time.Unix(0, 0), Message: "entry", Stack: "goroutine 1"}, []zapcore.Field{
+		zap.String("log", "scalar"),
+		zap.String("message", "user message"),
+		zap.String("service", "peer"),
+		zap.String("service.name", "peer0"),
+		zap.String("trailing.", "dot"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"@timestamp":"1970-01-01T00:00:00.000Z","ecs":{"version":"1.6.0"},`+
+		`"error":{"stack_trace":"goroutine 1"},`+
+		`"labels":{"log":"scalar","message":"user message","service_name":"peer0"},`+
+		`"log":{"level":"info"},"message":"entry","service":"peer","trailing":{"":"dot"}}`+"\n",
+		line.String(),
+	)
+}
+
+func TestECSEncoderCollidingPaths(t *testing.T) {
+	enc := fabenc.NewECSEncoder()
+	line, err := enc.EncodeEntry(zapcore.Entry{Time: time.Unix(0, 0)}, []zapcore.Field{
+		zap.String("pod", "short"),
+		zap.String("kubernetes.pod.name", "explicit"),
+		zap.String("error", "boom"),
+		zap.String("error.message", "explicit error"),
+		zap.String("message.a.b", "first"),
+		zap.String("message.a_b", "second"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"@timestamp":"1970-01-01T00:00:00.000Z","ecs":{"version":"1.6.0"},`+
+		`"error":{"message":"explicit error"},"kubernetes":{"pod":{"name":"explicit"}},`+
+		`"labels":{"error":"boom","message_a_b":"first","message_a_b_2":"second","pod":"short"},`+
+		`"log":{"level":"info"},"message":""}`+"\n",
+		line.String(),
+	)
+}
+
+func TestECSEncoderError(t *testing.T) {
+	enc := fabenc.NewECSEncoder()
+	line, err := enc.EncodeEntry(zapcore.Entry{Time: time.Unix(0, 0), Stack: "goroutine 1"}, []zapcore.Field{
+		zap.Error(errors.New("boom")),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"@timestamp":"1970-01-01T00:00:00.000Z","ecs":{"version":"1.6.0"},`+
+		`"error":{"message":"boom","stack_trace":"goroutine 1"},`+
+		`"log":{"level":"info"},"message":""}`+"\n",
+		line.String(),
+	)
+
+	// an error field that is already an object is not moved
+	line, err = enc.EncodeEntry(zapcore.Entry{Time: time.Unix(0, 0)}, []zapcore.Field{
+		zap.Object("error", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+			enc.AddString("code", "E42")
+			return nil
+		})),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"@timestamp":"1970-01-01T00:00:00.000Z","ecs":{"version":"1.6.0"},`+
+		`"error":{"code":"E42"},"log":{"level":"info"},"message":""}`+"\n",
+		line.String(),
+	)
+}
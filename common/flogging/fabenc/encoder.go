@@ -8,9 +8,11 @@ package fabenc
 
 import (
 	"io"
+	"reflect"
+	"strings"
 	"time"
+	"unicode/utf8"
 
-	zaplogfmt "github.com/sykesm/zap-logfmt"
 	"go.uber.org/zap/buffer"
 	"go.uber.org/zap/zapcore"
 )
@@ -19,8 +21,37 @@ import (
 // go-logging based format specifier.
 type FormatEncoder struct {
 	zapcore.Encoder
+
+	// PrefixLoggerName, when true, prepends the logger name in brackets to
+	// the message of entries written by named loggers.
+	PrefixLoggerName bool
+	// ShortLevels, when true, renders levels as the single letter returned
+	// by ShortLevelString.
+	ShortLevels bool
+	// TimeElider, when set, replaces timestamps that fall within the same
+	// second as the timestamp of the previous entry with blank space.
+	TimeElider *TimeElider
+	// Columns, when enabled, pads the time, level, logger, and message of
+	// each entry to fixed widths so the output lines up across entries.
+	Columns Columns
+	// LevelStyles replaces the default color of the levels in the map. The
+	// styles are written by the %{color} verb; levels that are not in the
+	// map keep their default color.
+	LevelStyles map[zapcore.Level]Style
+
 	formatters []Formatter
 	pool       buffer.Pool
+	trees      []tree
+}
+
+// A ConsoleMarshaler is implemented by field values that provide a human
+// readable representation for the CONSOLE encoding. It is honored for
+// reflected and Stringer fields and for integer fields that carry the
+// marshaler as their interface value. Other encodings continue to use the
+// field's native representation. Representations that span multiple lines are
+// rendered as an indented block below the entry.
+type ConsoleMarshaler interface {
+	MarshalConsole() string
 }
 
 // A Formatter is used to format and write data from a zap log entry.
@@ -30,7 +61,7 @@ type Formatter interface {
 
 func NewFormatEncoder(formatters ...Formatter) *FormatEncoder {
 	return &FormatEncoder{
-		Encoder: zaplogfmt.NewEncoder(zapcore.EncoderConfig{
+		Encoder: NewLogfmtEncoder(zapcore.EncoderConfig{
 			MessageKey:     "", // disable
 			LevelKey:       "", // disable
 			TimeKey:        "", // disable
@@ -51,30 +82,194 @@ func NewFormatEncoder(formatters ...Formatter) *FormatEncoder {
 // Clone creates a new instance of this encoder with the same configuration.
 func (f *FormatEncoder) Clone() zapcore.Encoder {
 	return &FormatEncoder{
-		Encoder:    f.Encoder.Clone(),
-		formatters: f.formatters,
-		pool:       f.pool,
+		Encoder:          f.Encoder.Clone(),
+		PrefixLoggerName: f.PrefixLoggerName,
+		ShortLevels:      f.ShortLevels,
+		TimeElider:       f.TimeElider,
+		Columns:          f.Columns,
+		LevelStyles:      f.LevelStyles,
+		formatters:       f.formatters,
+		pool:             f.pool,
+		trees:            append([]tree(nil), f.trees...),
+	}
+}
+
+// AddObject captures nested objects so they can be rendered as an indented
+// tree of key=value pairs below the formatted line.
+func (f *FormatEncoder) AddObject(key string, obj zapcore.ObjectMarshaler) error {
+	t, err := objectTree(key, obj)
+	if err != nil {
+		return f.Encoder.AddObject(key, obj)
+	}
+	f.trees = append(f.trees, t)
+	return nil
+}
+
+// AddReflected uses the human readable representation of values that
+// implement ConsoleMarshaler, renders maps as nested trees, and delegates all
+// other values to the wrapped encoder.
+func (f *FormatEncoder) AddReflected(key string, value interface{}) error {
+	if cm, ok := value.(ConsoleMarshaler); ok {
+		f.addConsole(key, cm)
+		return nil
+	}
+	if t, ok := reflectedTree(key, value); ok {
+		f.trees = append(f.trees, t)
+		return nil
+	}
+	return f.Encoder.AddReflected(key, value)
+}
+
+// AddField adds the field with the human readable representation of its
+// value when the value implements ConsoleMarshaler and through the field's
+// AddTo otherwise. Unlike AddTo, it honors the ConsoleMarshaler of typed
+// fields.
+func (f *FormatEncoder) AddField(field zapcore.Field) {
+	if cm, ok := consoleMarshaler(field); ok {
+		f.addConsole(field.Key, cm)
+		return
+	}
+	field.AddTo(f)
+}
+
+func (f *FormatEncoder) addConsole(key string, cm ConsoleMarshaler) {
+	text := cm.MarshalConsole()
+	if strings.Contains(text, "\n") {
+		f.trees = append(f.trees, tree{key: key, text: text})
+		return
+	}
+	f.Encoder.AddString(key, text)
+}
+
+// consoleMarshaler returns the ConsoleMarshaler carried by the field.
+func consoleMarshaler(field zapcore.Field) (ConsoleMarshaler, bool) {
+	switch field.Type {
+	case zapcore.ReflectType, zapcore.StringerType, zapcore.Int64Type:
+		cm, ok := field.Interface.(ConsoleMarshaler)
+		return cm, ok
+	default:
+		return nil, false
+	}
+}
+
+// format writes the entry with the formatter. When ShortLevels is set, level
+// formatters, including those delegated to by a MultiFormatter, render the
+// short level. When elide is true, time formatters write blank space. When
+// Columns is enabled, the time, level, module, and message are padded to the
+// column widths. Color formatters use the LevelStyles of the entry's level.
+func (f *FormatEncoder) format(w io.Writer, formatter Formatter, entry zapcore.Entry, fields []zapcore.Field, elide bool) {
+	if !f.ShortLevels && !elide && !f.Columns.Enabled() && len(f.LevelStyles) == 0 {
+		formatter.Format(w, entry, fields)
+		return
+	}
+	switch ft := formatter.(type) {
+	case ColorFormatter:
+		style, ok := f.LevelStyles[entry.Level]
+		if !ok || ft.Reset {
+			ft.Format(w, entry, fields)
+			return
+		}
+		style.Bold = style.Bold || ft.Bold
+		io.WriteString(w, style.Sequence())
+	case LevelFormatter:
+		ft.Short = ft.Short || f.ShortLevels
+		writeColumn(w, f.Columns.levelWidth(ft.Short), false, func(w io.Writer) {
+			ft.Format(w, entry, fields)
+		})
+	case TimeFormatter:
+		writeColumn(w, f.Columns.Time, false, func(w io.Writer) {
+			if elide {
+				io.WriteString(w, strings.Repeat(" ", utf8.RuneCountInString(entry.Time.Format(ft.Layout))))
+				return
+			}
+			ft.Format(w, entry, fields)
+		})
+	case ModuleFormatter:
+		writeColumn(w, f.Columns.Logger, f.Columns.TruncateLogger, func(w io.Writer) {
+			ft.Format(w, entry, fields)
+		})
+	case MessageFormatter:
+		writeColumn(w, f.Columns.Message, false, func(w io.Writer) {
+			ft.Format(w, entry, fields)
+		})
+	case *MultiFormatter:
+		for _, delegate := range ft.Formatters() {
+			f.format(w, delegate, entry, fields, elide)
+		}
+	default:
+		formatter.Format(w, entry, fields)
 	}
 }
 
 // EncodeEntry formats a zap log record. The structured fields are formatted by a
 // zapcore.ConsoleEncoder and are appended as JSON to the end of the formatted entry.
-// All entries are terminated by a newline.
+// Nested objects and maps are rendered as indented trees on the lines that
+// follow. All entries are terminated by a newline.
 func (f *FormatEncoder) EncodeEntry(entry zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	formatted := entry
+	if f.PrefixLoggerName && entry.LoggerName != "" {
+		formatted.Message = "[" + entry.LoggerName + "] " + entry.Message
+	}
+
 	line := f.pool.Get()
-	for _, f := range f.formatters {
-		f.Format(line, entry, fields)
+	elide := f.TimeElider != nil && f.TimeElider.Elide(entry.Time)
+	for _, formatter := range f.formatters {
+		f.format(line, formatter, formatted, fields, elide)
 	}
 
-	encodedFields, err := f.Encoder.EncodeEntry(entry, fields)
+	enc, trees := f.Encoder, f.trees
+	if needsFormatEncoder(fields) {
+		// the wrapped encoder does not know about ConsoleMarshaler or trees
+		// so the fields are added through a clone of this encoder
+		enc = f.Encoder.Clone()
+		clone := &FormatEncoder{Encoder: enc, trees: trees[:len(trees):len(trees)]}
+		for i := range fields {
+			clone.AddField(fields[i])
+		}
+		fields, trees = nil, clone.trees
+	}
+
+	encodedFields, err := enc.EncodeEntry(entry, fields)
 	if err != nil {
 		return nil, err
 	}
 	if line.Len() > 0 && encodedFields.Len() != 1 {
 		line.AppendString(" ")
 	}
-	line.AppendString(encodedFields.String())
+	if len(trees) == 0 {
+		line.AppendString(encodedFields.String())
+		encodedFields.Free()
+		return line, nil
+	}
+
+	line.AppendString(strings.TrimSuffix(encodedFields.String(), "\n"))
 	encodedFields.Free()
+	var sb strings.Builder
+	for _, t := range trees {
+		sb.WriteString("\n")
+		t.render(&sb, 1)
+	}
+	line.AppendString(sb.String())
+	line.AppendString("\n")
 
 	return line, nil
 }
+
+// needsFormatEncoder determines whether any of the fields must be added
+// through the FormatEncoder instead of directly to the wrapped encoder.
+func needsFormatEncoder(fields []zapcore.Field) bool {
+	for i := range fields {
+		if _, ok := consoleMarshaler(fields[i]); ok {
+			return true
+		}
+		switch fields[i].Type {
+		case zapcore.ObjectMarshalerType:
+			return true
+		case zapcore.ReflectType:
+			if v := fields[i].Interface; v != nil && reflect.TypeOf(v).Kind() == reflect.Map {
+				return true
+			}
+		}
+	}
+	return false
+}
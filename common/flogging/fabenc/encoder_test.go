@@ -10,6 +10,8 @@ import (
 	"errors"
 	"fmt"
 	"runtime"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -82,3 +84,211 @@ func TestFormatEncoderClone(t *testing.T) {
 	cloned := enc.Clone()
 	assert.Equal(t, enc, cloned)
 }
+
+type consoleValue struct{}
+
+func (consoleValue) MarshalConsole() string { return "human readable" }
+
+func TestEncodeConsoleMarshaler(t *testing.T) {
+	enc := fabenc.NewFormatEncoder()
+	enc.AddReflected("persistent", consoleValue{})
+
+	line, err := enc.EncodeEntry(zapcore.Entry{}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, `persistent="human readable"`+"\n", line.String())
+
+	line, err = enc.EncodeEntry(zapcore.Entry{}, []zapcore.Field{
+		zap.String("key", "value"),
+		zap.Reflect("reflected", consoleValue{}),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `persistent="human readable" key=value reflected="human readable"`+"\n", line.String())
+
+	line, err = enc.EncodeEntry(zapcore.Entry{}, []zapcore.Field{zap.String("key", "value")})
+	assert.NoError(t, err)
+	assert.Equal(t, `persistent="human readable" key=value`+"\n", line.String())
+}
+
+type request struct {
+	user    string
+	headers map[string]string
+}
+
+func (r request) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("user", r.user)
+	return enc.AddObject("headers", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+		for k, v := range r.headers {
+			enc.AddString(k, v)
+		}
+		return nil
+	}))
+}
+
+func TestEncodeNestedObjectsConcurrently(t *testing.T) {
+	formatters, err := fabenc.ParseFormat("%{message}")
+	assert.NoError(t, err)
+	enc := fabenc.NewFormatEncoder(formatters...)
+	object := func(value string) zapcore.ObjectMarshaler {
+		return zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+			enc.AddString("key", value)
+			return nil
+		})
+	}
+	// three persistent trees leave spare capacity in the encoder
+	for i := 0; i < 3; i++ {
+		enc.AddObject(fmt.Sprintf("context%d", i), object("persistent"))
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				value := fmt.Sprintf("entry-%d-%d", i, j)
+				line, err := enc.EncodeEntry(zapcore.Entry{Message: "message"}, []zapcore.Field{zap.Object("entry", object(value))})
+				assert.NoError(t, err)
+				assert.Contains(t, line.String(), "  entry:\n    key="+value+"\n")
+				assert.Equal(t, 4, strings.Count(line.String(), "key="), "unexpected trees: %s", line.String())
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestEncodeNestedObjects(t *testing.T) {
+	req := request{
+		user:    "alice",
+		headers: map[string]string{"accept": "application/json", "user-agent": "peer cli"},
+	}
+	reflected := map[string]interface{}{
+		"size": 2,
+		"tags": []string{"a", "b"},
+		"inner": map[string]interface{}{
+			"enabled": true,
+		},
+	}
+
+	formatters, err := fabenc.ParseFormat("%{message}")
+	assert.NoError(t, err)
+	enc := fabenc.NewFormatEncoder(formatters...)
+	enc.AddString("persistent", "value")
+	enc.AddObject("context", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+		enc.AddString("channel", "testchannel")
+		return nil
+	}))
+
+	// CONSOLE renders the nested objects as trees below the message
+	line, err := enc.EncodeEntry(zapcore.Entry{Message: "message"}, []zapcore.Field{
+		zap.Object("request", req),
+		zap.Any("reflected", reflected),
+		zap.String("key", "value"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "message persistent=value key=value\n"+
+		"  context:\n"+
+		"    channel=testchannel\n"+
+		"  request:\n"+
+		"    headers:\n"+
+		"      accept=application/json\n"+
+		"      user-agent=\"peer cli\"\n"+
+		"    user=alice\n"+
+		"  reflected:\n"+
+		"    inner:\n"+
+		"      enabled=true\n"+
+		"    size=2\n"+
+		`    tags="[\"a\",\"b\"]"`+"\n",
+		line.String(),
+	)
+
+	// the persistent tree remains after entry fields are encoded
+	line, err = enc.EncodeEntry(zapcore.Entry{Message: "message"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "message persistent=value\n  context:\n    channel=testchannel\n", line.String())
+
+	// JSON keeps its native nesting
+	jsonEnc := zapcore.NewJSONEncoder(zapcore.EncoderConfig{MessageKey: "msg"})
+	line, err = jsonEnc.EncodeEntry(zapcore.Entry{Message: "message"}, []zapcore.Field{
+		zap.Object("request", req),
+		zap.Any("reflected", reflected),
+	})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"msg": "message",
+		"request": {"user": "alice", "headers": {"accept": "application/json", "user-agent": "peer cli"}},
+		"reflected": {"size": 2, "tags": ["a", "b"], "inner": {"enabled": true}}
+	}`, line.String())
+}
+
+type multilineValue struct{}
+
+func (multilineValue) MarshalConsole() string { return "first line\nsecond line\n" }
+
+func TestEncodeMultilineConsoleMarshaler(t *testing.T) {
+	formatters, err := fabenc.ParseFormat("%{message}")
+	assert.NoError(t, err)
+	enc := fabenc.NewFormatEncoder(formatters...)
+
+	line, err := enc.EncodeEntry(zapcore.Entry{Message: "message"}, []zapcore.Field{
+		zap.Reflect("block", multilineValue{}),
+		zap.String("key", "value"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "message key=value\n  block:\n    first line\n    second line\n", line.String())
+}
+
+func TestEncodePrefixLoggerName(t *testing.T) {
+	formatters, err := fabenc.ParseFormat("%{level} %{message}")
+	assert.NoError(t, err)
+	enc := fabenc.NewFormatEncoder(formatters...)
+	entry := zapcore.Entry{Level: zapcore.InfoLevel, LoggerName: "peer.gossip", Message: "message"}
+
+	line, err := enc.EncodeEntry(entry, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "INFO message\n", line.String())
+
+	enc.PrefixLoggerName = true
+	line, err = enc.Clone().EncodeEntry(entry, []zapcore.Field{zap.String("key", "value")})
+	assert.NoError(t, err)
+	assert.Equal(t, "INFO [peer.gossip] message key=value\n", line.String())
+
+	line, err = enc.EncodeEntry(zapcore.Entry{Level: zapcore.InfoLevel, Message: "unnamed"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "INFO unnamed\n", line.String())
+}
+
+func TestEncodeShortLevels(t *testing.T) {
+	formatters, err := fabenc.ParseFormat("%{color}%{level}%{color:reset} %{message}")
+	assert.NoError(t, err)
+	enc := fabenc.NewFormatEncoder(formatters...)
+	enc.ShortLevels = true
+
+	line, err := enc.Clone().EncodeEntry(zapcore.Entry{Level: zapcore.WarnLevel, Message: "message"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "\x1b[33mW\x1b[0m message\n", line.String())
+}
+
+func TestEncodeLevelStyles(t *testing.T) {
+	formatters, err := fabenc.ParseFormat("%{color}%{level}%{color:reset} %{color:bold}%{message}%{color:reset}")
+	assert.NoError(t, err)
+	enc := fabenc.NewFormatEncoder(fabenc.NewMultiFormatter(formatters...))
+	enc.LevelStyles = map[zapcore.Level]fabenc.Style{
+		zapcore.FatalLevel: {Color: fabenc.ColorMagenta, Bold: true},
+		zapcore.DebugLevel: {Dim: true},
+	}
+
+	tests := []struct {
+		level    zapcore.Level
+		expected string
+	}{
+		{level: zapcore.FatalLevel, expected: "\x1b[35;1mFATAL\x1b[0m \x1b[35;1mmessage\x1b[0m\n"},
+		{level: zapcore.DebugLevel, expected: "\x1b[2mDEBUG\x1b[0m \x1b[1;2mmessage\x1b[0m\n"},
+		{level: zapcore.WarnLevel, expected: "\x1b[33mWARN\x1b[0m \x1b[33;1mmessage\x1b[0m\n"},
+		{level: zapcore.InfoLevel, expected: "\x1b[34mINFO\x1b[0m \x1b[34;1mmessage\x1b[0m\n"},
+	}
+	for _, tt := range tests {
+		line, err := enc.Clone().EncodeEntry(zapcore.Entry{Level: tt.level, Message: "message"}, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.expected, line.String(), tt.level.String())
+	}
+}
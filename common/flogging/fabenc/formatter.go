@@ -116,6 +116,13 @@ func (m *MultiFormatter) SetFormatters(formatters []Formatter) {
 	m.mutex.Unlock()
 }
 
+// Formatters returns the delegate formatters.
+func (m *MultiFormatter) Formatters() []Formatter {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.formatters
+}
+
 // A StringFormatter formats a fixed string.
 type StringFormatter struct{ Value string }
 
@@ -199,7 +206,10 @@ func (c ColorFormatter) Format(w io.Writer, entry zapcore.Entry, fields []zapcor
 }
 
 // LevelFormatter formats a log level.
-type LevelFormatter struct{ FormatVerb string }
+type LevelFormatter struct {
+	FormatVerb string
+	Short      bool // use the single letter returned by ShortLevelString
+}
 
 func newLevelFormatter(f string) LevelFormatter {
 	return LevelFormatter{FormatVerb: "%" + stringOrDefault(f, "s")}
@@ -207,9 +217,37 @@ func newLevelFormatter(f string) LevelFormatter {
 
 // Format writes the logging level to the provided writer.
 func (l LevelFormatter) Format(w io.Writer, entry zapcore.Entry, fields []zapcore.Field) {
+	if l.Short {
+		fmt.Fprintf(w, l.FormatVerb, ShortLevelString(entry.Level))
+		return
+	}
 	fmt.Fprintf(w, l.FormatVerb, entry.Level.CapitalString())
 }
 
+// ShortLevelString returns the single letter used to represent a level in
+// compact console output. Each level has its own letter; DPanic, whose
+// initial is used by Debug, is represented by X.
+func ShortLevelString(l zapcore.Level) string {
+	switch l {
+	case zapcore.DebugLevel:
+		return "D"
+	case zapcore.InfoLevel:
+		return "I"
+	case zapcore.WarnLevel:
+		return "W"
+	case zapcore.ErrorLevel:
+		return "E"
+	case zapcore.DPanicLevel:
+		return "X"
+	case zapcore.PanicLevel:
+		return "P"
+	case zapcore.FatalLevel:
+		return "F"
+	default:
+		return "?"
+	}
+}
+
 // MessageFormatter formats a log message.
 type MessageFormatter struct{ FormatVerb string }
 
@@ -165,15 +165,16 @@ func TestLevelFormatter(t *testing.T) {
 	var tests = []struct {
 		level     zapcore.Level
 		formatted string
+		short     string
 	}{
-		{level: zapcore.DebugLevel, formatted: "DEBUG"},
-		{level: zapcore.InfoLevel, formatted: "INFO"},
-		{level: zapcore.WarnLevel, formatted: "WARN"},
-		{level: zapcore.ErrorLevel, formatted: "ERROR"},
-		{level: zapcore.DPanicLevel, formatted: "DPANIC"},
-		{level: zapcore.PanicLevel, formatted: "PANIC"},
-		{level: zapcore.FatalLevel, formatted: "FATAL"},
-		{level: zapcore.Level(99), formatted: "LEVEL(99)"},
+		{level: zapcore.DebugLevel, formatted: "DEBUG", short: "D"},
+		{level: zapcore.InfoLevel, formatted: "INFO", short: "I"},
+		{level: zapcore.WarnLevel, formatted: "WARN", short: "W"},
+		{level: zapcore.ErrorLevel, formatted: "ERROR", short: "E"},
+		{level: zapcore.DPanicLevel, formatted: "DPANIC", short: "X"},
+		{level: zapcore.PanicLevel, formatted: "PANIC", short: "P"},
+		{level: zapcore.FatalLevel, formatted: "FATAL", short: "F"},
+		{level: zapcore.Level(99), formatted: "LEVEL(99)", short: "?"},
 	}
 
 	for i, tc := range tests {
@@ -182,10 +183,25 @@ func TestLevelFormatter(t *testing.T) {
 			entry := zapcore.Entry{Level: tc.level}
 			fabenc.LevelFormatter{FormatVerb: "%s"}.Format(buf, entry, nil)
 			assert.Equal(t, tc.formatted, buf.String())
+
+			buf.Reset()
+			fabenc.LevelFormatter{FormatVerb: "%s", Short: true}.Format(buf, entry, nil)
+			assert.Equal(t, tc.short, buf.String())
 		})
 	}
 }
 
+func TestShortLevelStringUnique(t *testing.T) {
+	seen := map[string]zapcore.Level{}
+	for l := zapcore.DebugLevel; l <= zapcore.FatalLevel; l++ {
+		short := fabenc.ShortLevelString(l)
+		if other, ok := seen[short]; ok {
+			t.Errorf("%s and %s share the short level %s", other, l, short)
+		}
+		seen[short] = l
+	}
+}
+
 func TestMessageFormatter(t *testing.T) {
 	buf := &bytes.Buffer{}
 	entry := zapcore.Entry{Message: "some message text \n\n"}
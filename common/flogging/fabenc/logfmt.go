@@ -0,0 +1,434 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fabenc
+
+import (
+	"encoding"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+const hex = "0123456789abcdef"
+
+// LogfmtKey returns a form of key that can be used as a logfmt key. Spaces,
+// control characters, equals signs, quotes, and invalid UTF-8 sequences are
+// not permitted in keys and are replaced with underscores. An empty key is
+// returned as a single underscore.
+func LogfmtKey(key string) string {
+	if key == "" {
+		return "_"
+	}
+	if strings.IndexFunc(key, needsQuote) == -1 {
+		return key
+	}
+
+	var sb strings.Builder
+	for _, r := range key {
+		if needsQuote(r) {
+			r = '_'
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// LogfmtValue returns value formatted as a logfmt value. Values that contain
+// spaces, control characters, equals signs, quotes, or invalid UTF-8 sequences
+// are quoted and escaped. Invalid UTF-8 bytes are replaced with the Unicode
+// replacement character. An empty value is returned as an empty quoted string.
+func LogfmtValue(value string) string {
+	if value != "" && strings.IndexFunc(value, needsQuote) == -1 {
+		return value
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(value) + 2)
+	sb.WriteByte('"')
+	for _, r := range value {
+		switch r {
+		case '"', '\\':
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		default:
+			if r < ' ' {
+				sb.WriteString(`\u00`)
+				sb.WriteByte(hex[r>>4])
+				sb.WriteByte(hex[r&0xf])
+				continue
+			}
+			// invalid sequences are decoded as utf8.RuneError
+			sb.WriteRune(r)
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+func needsQuote(r rune) bool {
+	return r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError
+}
+
+// A LogfmtEncoder is a zapcore.Encoder that writes entries as logfmt records.
+// Keys are formatted with LogfmtKey, prefixed by any open namespaces, and
+// values with LogfmtValue. Arrays are rendered as a bracketed list of comma
+// separated values and objects as a nested record; both are then quoted as a
+// single value.
+type LogfmtEncoder struct {
+	*zapcore.EncoderConfig
+	buf        *buffer.Buffer
+	pool       buffer.Pool
+	namespaces []string
+	array      bool
+}
+
+// NewLogfmtEncoder creates a LogfmtEncoder with the provided configuration.
+func NewLogfmtEncoder(cfg zapcore.EncoderConfig) *LogfmtEncoder {
+	pool := buffer.NewPool()
+	return &LogfmtEncoder{
+		EncoderConfig: &cfg,
+		buf:           pool.Get(),
+		pool:          pool,
+	}
+}
+
+// nested returns an encoder for the value of an array or object.
+func (enc *LogfmtEncoder) nested(array bool) *LogfmtEncoder {
+	return &LogfmtEncoder{
+		EncoderConfig: enc.EncoderConfig,
+		buf:           enc.pool.Get(),
+		pool:          enc.pool,
+		array:         array,
+	}
+}
+
+// Clone creates a copy of the encoder and the fields added to it.
+func (enc *LogfmtEncoder) Clone() zapcore.Encoder {
+	clone := &LogfmtEncoder{
+		EncoderConfig: enc.EncoderConfig,
+		buf:           enc.pool.Get(),
+		pool:          enc.pool,
+		namespaces:    enc.namespaces[:len(enc.namespaces):len(enc.namespaces)],
+	}
+	clone.buf.Write(enc.buf.Bytes())
+	return clone
+}
+
+// EncodeEntry writes the entry keys enabled by the configuration, the fields
+// added to the encoder, the entry fields, and the stack as a single record.
+func (enc *LogfmtEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	final := enc.nested(false)
+	if final.TimeKey != "" {
+		final.AddTime(final.TimeKey, ent.Time)
+	}
+	if final.LevelKey != "" {
+		final.addKey(final.LevelKey)
+		cur := final.buf.Len()
+		if final.EncodeLevel != nil {
+			final.EncodeLevel(ent.Level, final)
+		}
+		if cur == final.buf.Len() {
+			final.AppendString(ent.Level.String())
+		}
+	}
+	if ent.LoggerName != "" && final.NameKey != "" {
+		final.addKey(final.NameKey)
+		cur := final.buf.Len()
+		if final.EncodeName != nil {
+			final.EncodeName(ent.LoggerName, final)
+		}
+		if cur == final.buf.Len() {
+			final.AppendString(ent.LoggerName)
+		}
+	}
+	if ent.Caller.Defined && final.CallerKey != "" {
+		final.addKey(final.CallerKey)
+		cur := final.buf.Len()
+		if final.EncodeCaller != nil {
+			final.EncodeCaller(ent.Caller, final)
+		}
+		if cur == final.buf.Len() {
+			final.AppendString(ent.Caller.String())
+		}
+	}
+	if final.MessageKey != "" {
+		final.addKey(final.MessageKey)
+		final.AppendString(ent.Message)
+	}
+	if enc.buf.Len() > 0 {
+		if final.buf.Len() > 0 {
+			final.buf.AppendByte(' ')
+		}
+		final.buf.Write(enc.buf.Bytes())
+	}
+
+	// entry fields are added in the namespaces opened with the encoder
+	final.namespaces = enc.namespaces
+	for i := range fields {
+		fields[i].AddTo(final)
+	}
+	if ent.Stack != "" && final.StacktraceKey != "" {
+		final.AddString(final.StacktraceKey, ent.Stack)
+	}
+	if final.LineEnding != "" {
+		final.buf.AppendString(final.LineEnding)
+	} else {
+		final.buf.AppendString(zapcore.DefaultLineEnding)
+	}
+	return final.buf, nil
+}
+
+func (enc *LogfmtEncoder) addKey(key string) {
+	if enc.buf.Len() > 0 {
+		enc.buf.AppendByte(' ')
+	}
+	if len(enc.namespaces) > 0 {
+		key = strings.Join(enc.namespaces, ".") + "." + key
+	}
+	enc.buf.AppendString(LogfmtKey(key))
+	enc.buf.AppendByte('=')
+}
+
+// addSeparator separates the elements of an array.
+func (enc *LogfmtEncoder) addSeparator() {
+	if !enc.array {
+		return
+	}
+	if last := enc.buf.Len() - 1; last >= 0 && enc.buf.Bytes()[last] != '[' {
+		enc.buf.AppendByte(',')
+	}
+}
+
+func (enc *LogfmtEncoder) OpenNamespace(key string) {
+	enc.namespaces = append(enc.namespaces[:len(enc.namespaces):len(enc.namespaces)], key)
+}
+
+func (enc *LogfmtEncoder) AddArray(key string, arr zapcore.ArrayMarshaler) error {
+	enc.addKey(key)
+	return enc.AppendArray(arr)
+}
+
+func (enc *LogfmtEncoder) AddObject(key string, obj zapcore.ObjectMarshaler) error {
+	enc.addKey(key)
+	return enc.AppendObject(obj)
+}
+
+func (enc *LogfmtEncoder) AddReflected(key string, value interface{}) error {
+	enc.addKey(key)
+	return enc.AppendReflected(value)
+}
+
+func (enc *LogfmtEncoder) AddBinary(key string, value []byte) {
+	enc.AddString(key, base64.StdEncoding.EncodeToString(value))
+}
+
+func (enc *LogfmtEncoder) AddBool(k string, v bool)         { enc.addKey(k); enc.AppendBool(v) }
+func (enc *LogfmtEncoder) AddByteString(k string, v []byte) { enc.addKey(k); enc.AppendByteString(v) }
+func (enc *LogfmtEncoder) AddComplex128(k string, v complex128) {
+	enc.addKey(k)
+	enc.AppendComplex128(v)
+}
+func (enc *LogfmtEncoder) AddComplex64(k string, v complex64) { enc.AddComplex128(k, complex128(v)) }
+func (enc *LogfmtEncoder) AddDuration(k string, v time.Duration) {
+	enc.addKey(k)
+	enc.AppendDuration(v)
+}
+func (enc *LogfmtEncoder) AddFloat64(k string, v float64) { enc.addKey(k); enc.AppendFloat64(v) }
+func (enc *LogfmtEncoder) AddFloat32(k string, v float32) { enc.addKey(k); enc.AppendFloat32(v) }
+func (enc *LogfmtEncoder) AddInt(k string, v int)         { enc.AddInt64(k, int64(v)) }
+func (enc *LogfmtEncoder) AddInt64(k string, v int64)     { enc.addKey(k); enc.AppendInt64(v) }
+func (enc *LogfmtEncoder) AddInt32(k string, v int32)     { enc.AddInt64(k, int64(v)) }
+func (enc *LogfmtEncoder) AddInt16(k string, v int16)     { enc.AddInt64(k, int64(v)) }
+func (enc *LogfmtEncoder) AddInt8(k string, v int8)       { enc.AddInt64(k, int64(v)) }
+func (enc *LogfmtEncoder) AddString(k, v string)          { enc.addKey(k); enc.AppendString(v) }
+func (enc *LogfmtEncoder) AddTime(k string, v time.Time)  { enc.addKey(k); enc.AppendTime(v) }
+func (enc *LogfmtEncoder) AddUint(k string, v uint)       { enc.AddUint64(k, uint64(v)) }
+func (enc *LogfmtEncoder) AddUint64(k string, v uint64)   { enc.addKey(k); enc.AppendUint64(v) }
+func (enc *LogfmtEncoder) AddUint32(k string, v uint32)   { enc.AddUint64(k, uint64(v)) }
+func (enc *LogfmtEncoder) AddUint16(k string, v uint16)   { enc.AddUint64(k, uint64(v)) }
+func (enc *LogfmtEncoder) AddUint8(k string, v uint8)     { enc.AddUint64(k, uint64(v)) }
+func (enc *LogfmtEncoder) AddUintptr(k string, v uintptr) { enc.AddUint64(k, uint64(v)) }
+
+// AppendArray renders the array as a bracketed list and appends it as a
+// single value. An array that fails to marshal is appended as an empty value.
+func (enc *LogfmtEncoder) AppendArray(arr zapcore.ArrayMarshaler) error {
+	marshaler := enc.nested(true)
+	defer marshaler.buf.Free()
+
+	marshaler.buf.AppendByte('[')
+	if err := arr.MarshalLogArray(marshaler); err != nil {
+		enc.AppendString("")
+		return err
+	}
+	marshaler.buf.AppendByte(']')
+	enc.AppendString(marshaler.buf.String())
+	return nil
+}
+
+// AppendObject renders the object as a nested record and appends it as a
+// single value. An object that fails to marshal is appended as an empty value.
+func (enc *LogfmtEncoder) AppendObject(obj zapcore.ObjectMarshaler) error {
+	marshaler := enc.nested(false)
+	defer marshaler.buf.Free()
+
+	if err := obj.MarshalLogObject(marshaler); err != nil {
+		enc.AppendString("")
+		return err
+	}
+	enc.AppendString(marshaler.buf.String())
+	return nil
+}
+
+// AppendReflected appends errors, byte slices, fmt.Stringers, and
+// encoding.TextMarshalers as strings, slices and arrays as arrays, and other
+// values according to their kind.
+func (enc *LogfmtEncoder) AppendReflected(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		enc.AppendString("null")
+	case error:
+		enc.AppendString(v.Error())
+	case []byte:
+		enc.AppendByteString(v)
+	case fmt.Stringer:
+		enc.AppendString(v.String())
+	case encoding.TextMarshaler:
+		b, err := v.MarshalText()
+		if err != nil {
+			return err
+		}
+		enc.AppendString(string(b))
+	default:
+		rvalue := reflect.ValueOf(value)
+		switch rvalue.Kind() {
+		case reflect.Bool:
+			enc.AppendBool(rvalue.Bool())
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			enc.AppendInt64(rvalue.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			enc.AppendUint64(rvalue.Uint())
+		case reflect.Float32:
+			enc.appendFloat(rvalue.Float(), 32)
+		case reflect.Float64:
+			enc.appendFloat(rvalue.Float(), 64)
+		case reflect.String:
+			enc.AppendString(rvalue.String())
+		case reflect.Complex64, reflect.Complex128:
+			enc.AppendComplex128(rvalue.Complex())
+		case reflect.Array, reflect.Slice:
+			return enc.AppendArray(zapcore.ArrayMarshalerFunc(func(ae zapcore.ArrayEncoder) error {
+				for i := 0; i < rvalue.Len(); i++ {
+					if err := ae.AppendReflected(rvalue.Index(i).Interface()); err != nil {
+						return err
+					}
+				}
+				return nil
+			}))
+		case reflect.Interface, reflect.Ptr:
+			if rvalue.IsNil() {
+				enc.AppendString("null")
+				return nil
+			}
+			return enc.AppendReflected(rvalue.Elem().Interface())
+		case reflect.Chan, reflect.Func:
+			enc.AppendString(fmt.Sprintf("%T(%p)", value, value))
+		default:
+			enc.AppendString(fmt.Sprint(value))
+		}
+	}
+	return nil
+}
+
+func (enc *LogfmtEncoder) AppendString(v string) {
+	enc.addSeparator()
+	enc.buf.AppendString(LogfmtValue(v))
+}
+
+func (enc *LogfmtEncoder) AppendByteString(v []byte) { enc.AppendString(string(v)) }
+
+func (enc *LogfmtEncoder) AppendBool(v bool) {
+	enc.addSeparator()
+	enc.buf.AppendBool(v)
+}
+
+func (enc *LogfmtEncoder) AppendComplex128(v complex128) {
+	enc.addSeparator()
+	enc.buf.AppendFloat(real(v), 64)
+	enc.buf.AppendByte('+')
+	enc.buf.AppendFloat(imag(v), 64)
+	enc.buf.AppendByte('i')
+}
+
+func (enc *LogfmtEncoder) AppendDuration(v time.Duration) {
+	cur := enc.buf.Len()
+	if enc.EncodeDuration != nil {
+		enc.EncodeDuration(v, enc)
+	}
+	if cur == enc.buf.Len() {
+		enc.AppendInt64(int64(v))
+	}
+}
+
+func (enc *LogfmtEncoder) AppendTime(v time.Time) {
+	cur := enc.buf.Len()
+	if enc.EncodeTime != nil {
+		enc.EncodeTime(v, enc)
+	}
+	if cur == enc.buf.Len() {
+		enc.AppendInt64(v.UnixNano())
+	}
+}
+
+func (enc *LogfmtEncoder) appendFloat(v float64, bitSize int) {
+	enc.addSeparator()
+	switch {
+	case math.IsNaN(v):
+		enc.buf.AppendString("NaN")
+	case math.IsInf(v, 1):
+		enc.buf.AppendString("+Inf")
+	case math.IsInf(v, -1):
+		enc.buf.AppendString("-Inf")
+	default:
+		enc.buf.AppendFloat(v, bitSize)
+	}
+}
+
+func (enc *LogfmtEncoder) AppendInt64(v int64) {
+	enc.addSeparator()
+	enc.buf.AppendInt(v)
+}
+
+func (enc *LogfmtEncoder) AppendUint64(v uint64) {
+	enc.addSeparator()
+	enc.buf.AppendUint(v)
+}
+
+func (enc *LogfmtEncoder) AppendComplex64(v complex64) { enc.AppendComplex128(complex128(v)) }
+func (enc *LogfmtEncoder) AppendFloat64(v float64)     { enc.appendFloat(v, 64) }
+func (enc *LogfmtEncoder) AppendFloat32(v float32)     { enc.appendFloat(float64(v), 32) }
+func (enc *LogfmtEncoder) AppendInt(v int)             { enc.AppendInt64(int64(v)) }
+func (enc *LogfmtEncoder) AppendInt32(v int32)         { enc.AppendInt64(int64(v)) }
+func (enc *LogfmtEncoder) AppendInt16(v int16)         { enc.AppendInt64(int64(v)) }
+func (enc *LogfmtEncoder) AppendInt8(v int8)           { enc.AppendInt64(int64(v)) }
+func (enc *LogfmtEncoder) AppendUint(v uint)           { enc.AppendUint64(uint64(v)) }
+func (enc *LogfmtEncoder) AppendUint32(v uint32)       { enc.AppendUint64(uint64(v)) }
+func (enc *LogfmtEncoder) AppendUint16(v uint16)       { enc.AppendUint64(uint64(v)) }
+func (enc *LogfmtEncoder) AppendUint8(v uint8)         { enc.AppendUint64(uint64(v)) }
+func (enc *LogfmtEncoder) AppendUintptr(v uintptr)     { enc.AppendUint64(uint64(v)) }
@@ -0,0 +1,21 @@
+//go:build go1.18
+// +build go1.18
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fabenc_test
+
+import "testing"
+
+func FuzzLogfmtRoundTrip(f *testing.F) {
+	for _, seed := range logfmtSeeds {
+		f.Add(seed.key, seed.value)
+	}
+	f.Fuzz(func(t *testing.T, key, value string) {
+		assertLogfmtRoundTrip(t, key, value)
+	})
+}
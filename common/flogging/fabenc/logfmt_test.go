@@ -0,0 +1,172 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fabenc_test
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logfmt/logfmt"
+	"github.com/hyperledger/fabric/common/flogging/fabenc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// logfmtSeeds are tricky keys and values used by the unit and fuzz tests.
+var logfmtSeeds = []struct{ key, value string }{
+	{"key", "value"},
+	{"", ""},
+	{"key with spaces", "value with spaces"},
+	{"k=v", "a=b"},
+	{`"quoted"`, `say "hello"`},
+	{"multi\nline", "line one\nline two\r\n"},
+	{"tab\tkey", "tab\tvalue"},
+	{"control\x00", "bell\x07 escape\x1b"},
+	{"back\\slash", `C:\path\to\file`},
+	{"unicode-κλειδί", "τιμή ☃"},
+	{"invalid\xff", "invalid\xffutf8\xc3"},
+	{"replacement\ufffd", "\ufffd"},
+	{"null", "null"},
+	{"trailing=", "trailing\\"},
+}
+
+// decodeLogfmt parses a single key/value pair with the reference decoder.
+func decodeLogfmt(t *testing.T, line string) (string, string) {
+	dec := logfmt.NewDecoder(strings.NewReader(line))
+	require.True(t, dec.ScanRecord(), "no record in %q", line)
+	require.True(t, dec.ScanKeyval(), "no key/value in %q: %v", line, dec.Err())
+	key, value := string(dec.Key()), string(dec.Value())
+	require.False(t, dec.ScanKeyval(), "extra key/value in %q", line)
+	require.NoError(t, dec.Err(), "decoding %q", line)
+	require.False(t, dec.ScanRecord(), "extra record in %q", line)
+	return key, value
+}
+
+func assertLogfmtRoundTrip(t *testing.T, key, value string) {
+	encodedKey := fabenc.LogfmtKey(key)
+	line := encodedKey + "=" + fabenc.LogfmtValue(value)
+
+	decodedKey, decodedValue := decodeLogfmt(t, line)
+	assert.Equal(t, encodedKey, decodedKey)
+	// invalid UTF-8 bytes are replaced with utf8.RuneError by the encoder
+	assert.Equal(t, string([]rune(value)), decodedValue, "line %q", line)
+
+	// the encoder uses the same quoting
+	buf, err := fabenc.NewLogfmtEncoder(zapcore.EncoderConfig{}).EncodeEntry(zapcore.Entry{}, []zapcore.Field{zap.String(key, value)})
+	require.NoError(t, err)
+	assert.Equal(t, line+"\n", buf.String())
+}
+
+func TestLogfmtRoundTrip(t *testing.T) {
+	for _, seed := range logfmtSeeds {
+		t.Run(seed.key, func(t *testing.T) {
+			assertLogfmtRoundTrip(t, seed.key, seed.value)
+		})
+	}
+}
+
+func TestLogfmtKey(t *testing.T) {
+	assert.Equal(t, "key", fabenc.LogfmtKey("key"))
+	assert.Equal(t, "_", fabenc.LogfmtKey(""))
+	assert.Equal(t, "a_b_c_d_", fabenc.LogfmtKey("a b=c\"d\n"))
+	assert.Equal(t, "bad_", fabenc.LogfmtKey("bad\xff"))
+}
+
+func TestLogfmtValue(t *testing.T) {
+	var tests = []struct {
+		value    string
+		expected string
+	}{
+		{"value", "value"},
+		{"", `""`},
+		{"two words", `"two words"`},
+		{"a=b", `"a=b"`},
+		{`"`, `"\""`},
+		{"\\", "\\"},
+		{"a\\ b", `"a\\ b"`},
+		{"line\nbreak", `"line\nbreak"`},
+		{"\x01", `"\u0001"`},
+		{"\xff", "\"\ufffd\""},
+		{"κλειδί", "κλειδί"},
+	}
+	for _, tc := range tests {
+		assert.Equal(t, tc.expected, fabenc.LogfmtValue(tc.value), "value %q", tc.value)
+	}
+}
+
+func TestLogfmtEncoder(t *testing.T) {
+	enc := fabenc.NewLogfmtEncoder(zapcore.EncoderConfig{
+		MessageKey:     "msg",
+		LevelKey:       "level",
+		NameKey:        "name",
+		EncodeLevel:    zapcore.LowercaseLevelEncoder,
+		EncodeDuration: zapcore.StringDurationEncoder,
+	})
+	enc.AddString("with", "persistent value")
+	enc.OpenNamespace("ns")
+
+	entry := zapcore.Entry{Level: zapcore.InfoLevel, LoggerName: "peer", Message: "a message"}
+	buf, err := enc.Clone().EncodeEntry(entry, []zapcore.Field{
+		zap.String("path", `C:\dir`),
+		zap.String("empty", ""),
+		zap.Int("n", -1),
+		zap.Float64("nan", math.NaN()),
+		zap.Bool("ok", true),
+		zap.Duration("took", 1500*time.Millisecond),
+		zap.Strings("tags", []string{"a", "b c"}),
+		zap.Object("obj", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+			enc.AddString("key", "value")
+			enc.AddInt("count", 2)
+			return nil
+		})),
+		zap.Reflect("reflected", []int{1, 2}),
+		zap.Error(errors.New("it failed")),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `level=info name=peer msg="a message" with="persistent value" `+
+		`ns.path=C:\dir ns.empty="" ns.n=-1 ns.nan=NaN ns.ok=true ns.took=1.5s `+
+		`ns.tags="[a,\"b c\"]" ns.obj="key=value count=2" ns.reflected=[1,2] ns.error="it failed"`+"\n",
+		buf.String(),
+	)
+
+	dec := logfmt.NewDecoder(strings.NewReader(buf.String()))
+	require.True(t, dec.ScanRecord())
+	values := map[string]string{}
+	for dec.ScanKeyval() {
+		values[string(dec.Key())] = string(dec.Value())
+	}
+	require.NoError(t, dec.Err())
+	assert.Equal(t, `C:\dir`, values["ns.path"])
+	assert.Equal(t, `[a,"b c"]`, values["ns.tags"])
+	assert.Equal(t, "key=value count=2", values["ns.obj"])
+
+	// the encoder is not modified by encoding entries
+	buf, err = enc.EncodeEntry(zapcore.Entry{Message: "again"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, `level=info msg=again with="persistent value"`+"\n", buf.String())
+}
+
+func TestLogfmtEncoderMarshalError(t *testing.T) {
+	enc := fabenc.NewLogfmtEncoder(zapcore.EncoderConfig{})
+	err := enc.AddArray("array", zapcore.ArrayMarshalerFunc(func(zapcore.ArrayEncoder) error {
+		return errors.New("array failed")
+	}))
+	assert.EqualError(t, err, "array failed")
+	err = enc.AddObject("object", zapcore.ObjectMarshalerFunc(func(zapcore.ObjectEncoder) error {
+		return errors.New("object failed")
+	}))
+	assert.EqualError(t, err, "object failed")
+
+	buf, err := enc.EncodeEntry(zapcore.Entry{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, `array="" object=""`+"\n", buf.String())
+}
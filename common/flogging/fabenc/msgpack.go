@@ -0,0 +1,286 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fabenc
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// MessagePack format bytes.
+const (
+	msgpackNil      byte = 0xc0
+	msgpackFalse    byte = 0xc2
+	msgpackTrue     byte = 0xc3
+	msgpackBin8     byte = 0xc4
+	msgpackBin16    byte = 0xc5
+	msgpackBin32    byte = 0xc6
+	msgpackExt8     byte = 0xc7
+	msgpackFloat64  byte = 0xcb
+	msgpackUint8    byte = 0xcc
+	msgpackUint16   byte = 0xcd
+	msgpackUint32   byte = 0xce
+	msgpackUint64   byte = 0xcf
+	msgpackInt8     byte = 0xd0
+	msgpackInt16    byte = 0xd1
+	msgpackInt32    byte = 0xd2
+	msgpackInt64    byte = 0xd3
+	msgpackFixStr   byte = 0xa0
+	msgpackStr8     byte = 0xd9
+	msgpackStr16    byte = 0xda
+	msgpackStr32    byte = 0xdb
+	msgpackFixArray byte = 0x90
+	msgpackArray16  byte = 0xdc
+	msgpackArray32  byte = 0xdd
+	msgpackFixMap   byte = 0x80
+	msgpackMap16    byte = 0xde
+	msgpackMap32    byte = 0xdf
+
+	msgpackTimestampExt byte = 0xff // extension type -1
+)
+
+// A MsgpackEncoder is a zapcore.Encoder that serializes log entries as
+// MessagePack maps. Each entry is encoded as a map with the entry time (ts),
+// level, logger name (logger), message (msg), and a nested map of the
+// structured fields (fields). The caller and stacktrace are included when
+// present.
+//
+// Time values are encoded with the timestamp extension type (-1) and
+// durations are encoded as integer nanoseconds.
+type MsgpackEncoder struct {
+	*zapcore.MapObjectEncoder
+	pool buffer.Pool
+}
+
+// NewMsgpackEncoder creates a new MsgpackEncoder.
+func NewMsgpackEncoder() *MsgpackEncoder {
+	return &MsgpackEncoder{
+		MapObjectEncoder: zapcore.NewMapObjectEncoder(),
+		pool:             buffer.NewPool(),
+	}
+}
+
+// Clone creates a new instance of this encoder with the same fields.
+func (m *MsgpackEncoder) Clone() zapcore.Encoder {
+	return &MsgpackEncoder{
+		MapObjectEncoder: cloneMapObjectEncoder(m.MapObjectEncoder),
+		pool:             m.pool,
+	}
+}
+
+// EncodeEntry encodes the entry and fields as a MessagePack map.
+func (m *MsgpackEncoder) EncodeEntry(entry zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	enc := cloneMapObjectEncoder(m.MapObjectEncoder)
+	for i := range fields {
+		fields[i].AddTo(enc)
+	}
+
+	record := map[string]interface{}{
+		"ts":     entry.Time,
+		"level":  entry.Level.String(),
+		"logger": entry.LoggerName,
+		"msg":    entry.Message,
+		"fields": enc.Fields,
+	}
+	if entry.Caller.Defined {
+		record["caller"] = entry.Caller.TrimmedPath()
+	}
+	if entry.Stack != "" {
+		record["stacktrace"] = entry.Stack
+	}
+
+	buf := m.pool.Get()
+	if err := appendMsgpack(buf, record); err != nil {
+		buf.Free()
+		return nil, err
+	}
+	return buf, nil
+}
+
+// appendMsgpackHeader writes the header of a string, binary, array, or map
+// of length n using the smallest available format. A zero fix or b8 format
+// indicates that the type has no such format.
+func appendMsgpackHeader(buf *buffer.Buffer, fix byte, fixMax uint64, b8, b16, b32 byte, n uint64) {
+	var b [5]byte
+	switch {
+	case fix != 0 && n <= fixMax:
+		buf.AppendByte(fix | byte(n))
+	case b8 != 0 && n <= math.MaxUint8:
+		b[0], b[1] = b8, byte(n)
+		buf.Write(b[:2])
+	case n <= math.MaxUint16:
+		b[0] = b16
+		binary.BigEndian.PutUint16(b[1:], uint16(n))
+		buf.Write(b[:3])
+	default:
+		b[0] = b32
+		binary.BigEndian.PutUint32(b[1:], uint32(n))
+		buf.Write(b[:5])
+	}
+}
+
+func appendMsgpackUint(buf *buffer.Buffer, u uint64) {
+	var b [9]byte
+	switch {
+	case u <= 0x7f:
+		buf.AppendByte(byte(u))
+	case u <= math.MaxUint8:
+		b[0], b[1] = msgpackUint8, byte(u)
+		buf.Write(b[:2])
+	case u <= math.MaxUint16:
+		b[0] = msgpackUint16
+		binary.BigEndian.PutUint16(b[1:], uint16(u))
+		buf.Write(b[:3])
+	case u <= math.MaxUint32:
+		b[0] = msgpackUint32
+		binary.BigEndian.PutUint32(b[1:], uint32(u))
+		buf.Write(b[:5])
+	default:
+		b[0] = msgpackUint64
+		binary.BigEndian.PutUint64(b[1:], u)
+		buf.Write(b[:9])
+	}
+}
+
+func appendMsgpackInt(buf *buffer.Buffer, i int64) {
+	if i >= 0 {
+		appendMsgpackUint(buf, uint64(i))
+		return
+	}
+
+	var b [9]byte
+	switch {
+	case i >= -32:
+		buf.AppendByte(byte(i))
+	case i >= math.MinInt8:
+		b[0], b[1] = msgpackInt8, byte(i)
+		buf.Write(b[:2])
+	case i >= math.MinInt16:
+		b[0] = msgpackInt16
+		binary.BigEndian.PutUint16(b[1:], uint16(i))
+		buf.Write(b[:3])
+	case i >= math.MinInt32:
+		b[0] = msgpackInt32
+		binary.BigEndian.PutUint32(b[1:], uint32(i))
+		buf.Write(b[:5])
+	default:
+		b[0] = msgpackInt64
+		binary.BigEndian.PutUint64(b[1:], uint64(i))
+		buf.Write(b[:9])
+	}
+}
+
+func appendMsgpackFloat(buf *buffer.Buffer, f float64) {
+	var b [9]byte
+	b[0] = msgpackFloat64
+	binary.BigEndian.PutUint64(b[1:], math.Float64bits(f))
+	buf.Write(b[:])
+}
+
+func appendMsgpackString(buf *buffer.Buffer, s string) {
+	appendMsgpackHeader(buf, msgpackFixStr, 31, msgpackStr8, msgpackStr16, msgpackStr32, uint64(len(s)))
+	buf.AppendString(s)
+}
+
+// appendMsgpackTime encodes t with the 96-bit timestamp extension format.
+func appendMsgpackTime(buf *buffer.Buffer, t time.Time) {
+	var b [15]byte
+	b[0], b[1], b[2] = msgpackExt8, 12, msgpackTimestampExt
+	binary.BigEndian.PutUint32(b[3:], uint32(t.Nanosecond()))
+	binary.BigEndian.PutUint64(b[7:], uint64(t.Unix()))
+	buf.Write(b[:])
+}
+
+// appendMsgpack encodes the values produced by a zapcore.MapObjectEncoder.
+func appendMsgpack(buf *buffer.Buffer, v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		buf.AppendByte(msgpackNil)
+	case bool:
+		if v {
+			buf.AppendByte(msgpackTrue)
+		} else {
+			buf.AppendByte(msgpackFalse)
+		}
+	case string:
+		appendMsgpackString(buf, v)
+	case []byte:
+		appendMsgpackHeader(buf, 0, 0, msgpackBin8, msgpackBin16, msgpackBin32, uint64(len(v)))
+		buf.Write(v)
+	case int:
+		appendMsgpackInt(buf, int64(v))
+	case int64:
+		appendMsgpackInt(buf, v)
+	case int32:
+		appendMsgpackInt(buf, int64(v))
+	case int16:
+		appendMsgpackInt(buf, int64(v))
+	case int8:
+		appendMsgpackInt(buf, int64(v))
+	case uint:
+		appendMsgpackUint(buf, uint64(v))
+	case uint64:
+		appendMsgpackUint(buf, v)
+	case uint32:
+		appendMsgpackUint(buf, uint64(v))
+	case uint16:
+		appendMsgpackUint(buf, uint64(v))
+	case uint8:
+		appendMsgpackUint(buf, uint64(v))
+	case uintptr:
+		appendMsgpackUint(buf, uint64(v))
+	case float64:
+		appendMsgpackFloat(buf, v)
+	case float32:
+		appendMsgpackFloat(buf, float64(v))
+	case complex128, complex64:
+		appendMsgpackString(buf, fmt.Sprint(v))
+	case time.Duration:
+		appendMsgpackInt(buf, int64(v))
+	case time.Time:
+		appendMsgpackTime(buf, v)
+	case []interface{}:
+		appendMsgpackHeader(buf, msgpackFixArray, 15, 0, msgpackArray16, msgpackArray32, uint64(len(v)))
+		for _, e := range v {
+			if err := appendMsgpack(buf, e); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		appendMsgpackHeader(buf, msgpackFixMap, 15, 0, msgpackMap16, msgpackMap32, uint64(len(keys)))
+		for _, k := range keys {
+			appendMsgpackString(buf, k)
+			if err := appendMsgpack(buf, v[k]); err != nil {
+				return err
+			}
+		}
+	default:
+		// reflected values are normalized through their JSON representation
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		var generic interface{}
+		if err := json.Unmarshal(b, &generic); err != nil {
+			return err
+		}
+		return appendMsgpack(buf, generic)
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fabenc_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging/fabenc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// decodeMsgpack is a minimal MessagePack decoder for the subset of formats
+// produced by the encoder.
+func decodeMsgpack(b []byte) (interface{}, []byte, error) {
+	if len(b) == 0 {
+		return nil, nil, errors.New("unexpected end of input")
+	}
+	t, b := b[0], b[1:]
+
+	switch {
+	case t <= 0x7f:
+		return int64(t), b, nil
+	case t >= 0xe0:
+		return int64(int8(t)), b, nil
+	case t&0xe0 == 0xa0:
+		n := int(t & 0x1f)
+		return string(b[:n]), b[n:], nil
+	case t&0xf0 == 0x90:
+		return decodeMsgpackArray(b, int(t&0x0f))
+	case t&0xf0 == 0x80:
+		return decodeMsgpackMap(b, int(t&0x0f))
+	}
+
+	switch t {
+	case 0xc0:
+		return nil, b, nil
+	case 0xc2:
+		return false, b, nil
+	case 0xc3:
+		return true, b, nil
+	case 0xc4:
+		n := int(b[0])
+		return b[1 : 1+n], b[1+n:], nil
+	case 0xc7:
+		if b[0] != 12 || b[1] != 0xff {
+			return nil, nil, fmt.Errorf("unsupported extension %d of length %d", int8(b[1]), b[0])
+		}
+		nsec := binary.BigEndian.Uint32(b[2:])
+		sec := int64(binary.BigEndian.Uint64(b[6:]))
+		return time.Unix(sec, int64(nsec)), b[14:], nil
+	case 0xcb:
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), b[8:], nil
+	case 0xcc:
+		return int64(b[0]), b[1:], nil
+	case 0xcd:
+		return int64(binary.BigEndian.Uint16(b)), b[2:], nil
+	case 0xce:
+		return int64(binary.BigEndian.Uint32(b)), b[4:], nil
+	case 0xcf:
+		return binary.BigEndian.Uint64(b), b[8:], nil
+	case 0xd0:
+		return int64(int8(b[0])), b[1:], nil
+	case 0xd1:
+		return int64(int16(binary.BigEndian.Uint16(b))), b[2:], nil
+	case 0xd2:
+		return int64(int32(binary.BigEndian.Uint32(b))), b[4:], nil
+	case 0xd3:
+		return int64(binary.BigEndian.Uint64(b)), b[8:], nil
+	case 0xd9:
+		n := int(b[0])
+		return string(b[1 : 1+n]), b[1+n:], nil
+	case 0xda:
+		n := int(binary.BigEndian.Uint16(b))
+		return string(b[2 : 2+n]), b[2+n:], nil
+	case 0xdc:
+		return decodeMsgpackArray(b[2:], int(binary.BigEndian.Uint16(b)))
+	case 0xde:
+		return decodeMsgpackMap(b[2:], int(binary.BigEndian.Uint16(b)))
+	}
+	return nil, nil, fmt.Errorf("unsupported format 0x%02x", t)
+}
+
+func decodeMsgpackArray(b []byte, n int) (interface{}, []byte, error) {
+	arr := []interface{}{}
+	for i := 0; i < n; i++ {
+		v, rest, err := decodeMsgpack(b)
+		if err != nil {
+			return nil, nil, err
+		}
+		arr, b = append(arr, v), rest
+	}
+	return arr, b, nil
+}
+
+func decodeMsgpackMap(b []byte, n int) (interface{}, []byte, error) {
+	m := map[string]interface{}{}
+	for i := 0; i < n; i++ {
+		k, rest, err := decodeMsgpack(b)
+		if err != nil {
+			return nil, nil, err
+		}
+		v, rest, err := decodeMsgpack(rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		m[k.(string)], b = v, rest
+	}
+	return m, b, nil
+}
+
+func TestMsgpackEncoder(t *testing.T) {
+	ts := time.Unix(1590000000, 500000000)
+	enc := fabenc.NewMsgpackEncoder().Clone()
+	enc.AddString("persistent", "value")
+
+	buf, err := enc.EncodeEntry(
+		zapcore.Entry{
+			Level:      zapcore.WarnLevel,
+			Time:       ts,
+			LoggerName: "logger.name",
+			Message:    "this is a message",
+			Stack:      "stack",
+		},
+		[]zapcore.Field{
+			zap.Int("negative", -300),
+			zap.Int("fixneg", -5),
+			zap.Int("fixint", 100),
+			zap.Int("int16", math.MaxInt16),
+			zap.Int64("int64", math.MinInt64),
+			zap.Uint64("uint64", math.MaxUint64),
+			zap.Float64("float", 2.5),
+			zap.Bool("bool", true),
+			zap.Duration("duration", time.Second),
+			zap.Time("time", ts),
+			zap.Binary("binary", []byte{1, 2}),
+			zap.Strings("strings", []string{"a", "b"}),
+			zap.String("long", strings.Repeat("x", 40)),
+			zap.Any("reflected", struct{ A int }{A: 1}),
+			zap.Error(errors.New("boom")),
+		},
+	)
+	require.NoError(t, err)
+
+	decoded, rest, err := decodeMsgpack(buf.Bytes())
+	require.NoError(t, err)
+	assert.Empty(t, rest)
+	assert.Equal(t, map[string]interface{}{
+		"ts":         ts,
+		"level":      "warn",
+		"logger":     "logger.name",
+		"msg":        "this is a message",
+		"stacktrace": "stack",
+		"fields": map[string]interface{}{
+			"persistent": "value",
+			"negative":   int64(-300),
+			"fixneg":     int64(-5),
+			"fixint":     int64(100),
+			"int16":      int64(math.MaxInt16),
+			"int64":      int64(math.MinInt64),
+			"uint64":     uint64(math.MaxUint64),
+			"float":      2.5,
+			"bool":       true,
+			"duration":   int64(time.Second),
+			"time":       ts,
+			"binary":     []byte{1, 2},
+			"strings":    []interface{}{"a", "b"},
+			"long":       strings.Repeat("x", 40),
+			"reflected":  map[string]interface{}{"A": 1.0},
+			"error":      "boom",
+		},
+	}, decoded)
+}
+
+func TestMsgpackEncoderLargeCollections(t *testing.T) {
+	values := make([]string, 20)
+	for i := range values {
+		values[i] = fmt.Sprintf("%d", i)
+	}
+	fields := []zapcore.Field{zap.Strings("values", values)}
+	for i := 0; i < 20; i++ {
+		fields = append(fields, zap.Int(fmt.Sprintf("key%02d", i), i))
+	}
+
+	buf, err := fabenc.NewMsgpackEncoder().EncodeEntry(zapcore.Entry{}, fields)
+	require.NoError(t, err)
+	decoded, rest, err := decodeMsgpack(buf.Bytes())
+	require.NoError(t, err)
+	assert.Empty(t, rest)
+
+	decodedFields := decoded.(map[string]interface{})["fields"].(map[string]interface{})
+	assert.Len(t, decodedFields, 21)
+	assert.Len(t, decodedFields["values"], 20)
+	assert.Equal(t, int64(19), decodedFields["key19"])
+}
+
+func TestMsgpackEncoderClone(t *testing.T) {
+	enc := fabenc.NewMsgpackEncoder()
+	enc.AddString("parent", "value")
+	clone := enc.Clone()
+	clone.AddString("child", "value")
+
+	buf, err := enc.EncodeEntry(zapcore.Entry{}, nil)
+	require.NoError(t, err)
+	decoded, _, err := decodeMsgpack(buf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"parent": "value"}, decoded.(map[string]interface{})["fields"])
+
+	buf, err = clone.EncodeEntry(zapcore.Entry{}, []zapcore.Field{zap.Int("call", 1)})
+	require.NoError(t, err)
+	decoded, _, err = decodeMsgpack(buf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"parent": "value", "child": "value", "call": int64(1)}, decoded.(map[string]interface{})["fields"])
+}
+
+func TestMsgpackEncoderReflectedError(t *testing.T) {
+	enc := fabenc.NewMsgpackEncoder()
+	_, err := enc.EncodeEntry(zapcore.Entry{}, []zapcore.Field{zap.Reflect("bad", make(chan int))})
+	assert.EqualError(t, err, "json: unsupported type: chan int")
+}
@@ -0,0 +1,194 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fabenc
+
+import (
+	"sort"
+	"time"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// A SortedJSONEncoder is a zapcore.Encoder that produces JSON with the
+// structured fields, including the fields of nested objects, sorted by key.
+// Entries with the same fields always produce byte identical output, which is
+// useful when comparing logs against golden files.
+//
+// Fields are collected before encoding so the SortedJSONEncoder is slower than
+// the zap JSON encoder and should not be used when field order is irrelevant.
+type SortedJSONEncoder struct {
+	*zapcore.MapObjectEncoder
+	config zapcore.EncoderConfig
+}
+
+// NewSortedJSONEncoder creates a SortedJSONEncoder that encodes the entry
+// metadata as described by the provided configuration.
+func NewSortedJSONEncoder(config zapcore.EncoderConfig) *SortedJSONEncoder {
+	return &SortedJSONEncoder{
+		MapObjectEncoder: zapcore.NewMapObjectEncoder(),
+		config:           config,
+	}
+}
+
+// Clone creates a new instance of this encoder with the same fields.
+func (s *SortedJSONEncoder) Clone() zapcore.Encoder {
+	return &SortedJSONEncoder{
+		MapObjectEncoder: cloneMapObjectEncoder(s.MapObjectEncoder),
+		config:           s.config,
+	}
+}
+
+// EncodeEntry encodes the entry as JSON with the fields sorted by key.
+func (s *SortedJSONEncoder) EncodeEntry(entry zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	enc := cloneMapObjectEncoder(s.MapObjectEncoder)
+	for i := range fields {
+		fields[i].AddTo(enc)
+	}
+
+	json := zapcore.NewJSONEncoder(s.config)
+	if err := addSortedFields(json, enc.Fields); err != nil {
+		return nil, err
+	}
+	return json.EncodeEntry(entry, nil)
+}
+
+func addSortedFields(enc zapcore.ObjectEncoder, fields map[string]interface{}) error {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if err := addSortedField(enc, k, fields[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addSortedField adds a value captured by a zapcore.MapObjectEncoder to enc
+// using the typed method that originally produced it.
+func addSortedField(enc zapcore.ObjectEncoder, key string, value interface{}) error {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return enc.AddObject(key, sortedObject(v))
+	case []interface{}:
+		return enc.AddArray(key, sortedArray(v))
+	case string:
+		enc.AddString(key, v)
+	case bool:
+		enc.AddBool(key, v)
+	case []byte:
+		enc.AddBinary(key, v)
+	case int64:
+		enc.AddInt64(key, v)
+	case int32:
+		enc.AddInt32(key, v)
+	case int16:
+		enc.AddInt16(key, v)
+	case int8:
+		enc.AddInt8(key, v)
+	case int:
+		enc.AddInt(key, v)
+	case uint64:
+		enc.AddUint64(key, v)
+	case uint32:
+		enc.AddUint32(key, v)
+	case uint16:
+		enc.AddUint16(key, v)
+	case uint8:
+		enc.AddUint8(key, v)
+	case uint:
+		enc.AddUint(key, v)
+	case uintptr:
+		enc.AddUintptr(key, v)
+	case float64:
+		enc.AddFloat64(key, v)
+	case float32:
+		enc.AddFloat32(key, v)
+	case complex128:
+		enc.AddComplex128(key, v)
+	case complex64:
+		enc.AddComplex64(key, v)
+	case time.Time:
+		enc.AddTime(key, v)
+	case time.Duration:
+		enc.AddDuration(key, v)
+	default:
+		return enc.AddReflected(key, v)
+	}
+	return nil
+}
+
+type sortedObject map[string]interface{}
+
+func (s sortedObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	return addSortedFields(enc, s)
+}
+
+type sortedArray []interface{}
+
+func (s sortedArray) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for _, value := range s {
+		if err := appendSortedValue(enc, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func appendSortedValue(enc zapcore.ArrayEncoder, value interface{}) error {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return enc.AppendObject(sortedObject(v))
+	case []interface{}:
+		return enc.AppendArray(sortedArray(v))
+	case string:
+		enc.AppendString(v)
+	case bool:
+		enc.AppendBool(v)
+	case int64:
+		enc.AppendInt64(v)
+	case int32:
+		enc.AppendInt32(v)
+	case int16:
+		enc.AppendInt16(v)
+	case int8:
+		enc.AppendInt8(v)
+	case int:
+		enc.AppendInt(v)
+	case uint64:
+		enc.AppendUint64(v)
+	case uint32:
+		enc.AppendUint32(v)
+	case uint16:
+		enc.AppendUint16(v)
+	case uint8:
+		enc.AppendUint8(v)
+	case uint:
+		enc.AppendUint(v)
+	case uintptr:
+		enc.AppendUintptr(v)
+	case float64:
+		enc.AppendFloat64(v)
+	case float32:
+		enc.AppendFloat32(v)
+	case complex128:
+		enc.AppendComplex128(v)
+	case complex64:
+		enc.AppendComplex64(v)
+	case time.Time:
+		enc.AppendTime(v)
+	case time.Duration:
+		enc.AppendDuration(v)
+	default:
+		return enc.AppendReflected(v)
+	}
+	return nil
+}
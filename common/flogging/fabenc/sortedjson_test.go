@@ -0,0 +1,115 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fabenc_test
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging/fabenc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func newSortedJSONEncoder() *fabenc.SortedJSONEncoder {
+	return fabenc.NewSortedJSONEncoder(zapcore.EncoderConfig{
+		MessageKey:     "msg",
+		LevelKey:       "level",
+		EncodeLevel:    zapcore.LowercaseLevelEncoder,
+		EncodeDuration: zapcore.StringDurationEncoder,
+		EncodeTime:     zapcore.ISO8601TimeEncoder,
+	})
+}
+
+func TestSortedJSONEncoder(t *testing.T) {
+	enc := newSortedJSONEncoder()
+	enc.AddString("persistent", "value")
+
+	line, err := enc.EncodeEntry(zapcore.Entry{Level: zapcore.InfoLevel, Message: "message"}, []zapcore.Field{
+		zap.Int("zulu", 1),
+		zap.Duration("duration", time.Second),
+		zap.Object("nested", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+			enc.AddBool("yes", true)
+			enc.AddFloat64("float", 1.5)
+			return enc.AddArray("array", zapcore.ArrayMarshalerFunc(func(enc zapcore.ArrayEncoder) error {
+				enc.AppendString("b")
+				enc.AppendString("a")
+				return enc.AppendObject(zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+					enc.AddInt("z", 26)
+					enc.AddInt("a", 1)
+					return nil
+				}))
+			}))
+		})),
+		zap.Any("reflected", map[string]int{"two": 2, "one": 1}),
+		zap.Time("time", time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)),
+		zap.String("alpha", "first"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `{"level":"info","msg":"message",`+
+		`"alpha":"first",`+
+		`"duration":"1s",`+
+		`"nested":{"array":["b","a",{"a":1,"z":26}],"float":1.5,"yes":true},`+
+		`"persistent":"value",`+
+		`"reflected":{"one":1,"two":2},`+
+		`"time":"2020-01-02T03:04:05.000Z",`+
+		`"zulu":1}`+"\n", line.String())
+}
+
+func TestSortedJSONEncoderDeterministic(t *testing.T) {
+	fields := []zapcore.Field{
+		zap.String("string", "value"),
+		zap.Int("int", 42),
+		zap.Bool("bool", true),
+		zap.Strings("strings", []string{"a", "b"}),
+		zap.Any("map", map[string]interface{}{"c": 3, "b": map[string]int{"y": 2, "x": 1}, "a": 1}),
+		zap.Namespace("namespace"),
+		zap.Float64("float", 3.14),
+		zap.Uint8("uint8", 8),
+	}
+	fieldsWith := []zapcore.Field{zap.String("with1", "1"), zap.String("with2", "2")}
+
+	encode := func(fields, fieldsWith []zapcore.Field) string {
+		enc := newSortedJSONEncoder()
+		for _, f := range fieldsWith {
+			f.AddTo(enc)
+		}
+		line, err := enc.Clone().EncodeEntry(zapcore.Entry{Message: "message"}, fields)
+		require.NoError(t, err)
+		return line.String()
+	}
+	expected := encode(fields, fieldsWith)
+
+	r := rand.New(rand.NewSource(0))
+	for i := 0; i < 100; i++ {
+		// the namespace must remain last to receive the same fields
+		shuffled := append([]zapcore.Field{}, fields[:5]...)
+		r.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+		shuffled = append(shuffled, fields[5:]...)
+		shuffledWith := []zapcore.Field{fieldsWith[1], fieldsWith[0]}
+
+		assert.Equal(t, expected, encode(shuffled, shuffledWith))
+	}
+}
+
+func TestSortedJSONEncoderClone(t *testing.T) {
+	enc := newSortedJSONEncoder()
+	enc.AddString("parent", "value")
+	clone := enc.Clone()
+	clone.AddString("child", "value")
+
+	line, err := enc.EncodeEntry(zapcore.Entry{Message: "message"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, `{"level":"info","msg":"message","parent":"value"}`+"\n", line.String())
+
+	line, err = clone.EncodeEntry(zapcore.Entry{Message: "message"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, `{"level":"info","msg":"message","child":"value","parent":"value"}`+"\n", line.String())
+}
@@ -0,0 +1,97 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fabenc
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// A StringifyEncoder is a zapcore.Encoder that coerces scalar field values to
+// their string representation before delegating to the wrapped encoder. It
+// exists for consumers that expect every field value to be a string.
+//
+// Arrays, objects, and reflected values are passed to the wrapped encoder
+// unchanged.
+type StringifyEncoder struct {
+	zapcore.Encoder
+}
+
+// NewStringifyEncoder creates a StringifyEncoder that delegates to the
+// provided encoder.
+func NewStringifyEncoder(enc zapcore.Encoder) *StringifyEncoder {
+	return &StringifyEncoder{Encoder: enc}
+}
+
+// Clone creates a new instance of this encoder with a clone of the wrapped
+// encoder.
+func (s *StringifyEncoder) Clone() zapcore.Encoder {
+	return &StringifyEncoder{Encoder: s.Encoder.Clone()}
+}
+
+// EncodeEntry coerces the scalar field values to strings and passes the
+// resulting fields to the wrapped encoder. The provided fields are not
+// modified.
+func (s *StringifyEncoder) EncodeEntry(entry zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	stringified := make([]zapcore.Field, len(fields))
+	for i, f := range fields {
+		stringified[i] = stringifyField(f)
+	}
+	return s.Encoder.EncodeEntry(entry, stringified)
+}
+
+// stringifyField returns a string field with the representation of a scalar
+// field value. Other fields are returned unchanged.
+func stringifyField(f zapcore.Field) zapcore.Field {
+	switch f.Type {
+	case zapcore.BoolType, zapcore.Complex128Type, zapcore.Complex64Type, zapcore.DurationType,
+		zapcore.Float64Type, zapcore.Float32Type, zapcore.Int64Type, zapcore.Int32Type,
+		zapcore.Int16Type, zapcore.Int8Type, zapcore.TimeType, zapcore.Uint64Type,
+		zapcore.Uint32Type, zapcore.Uint16Type, zapcore.Uint8Type, zapcore.UintptrType:
+		capture := &stringCapture{}
+		f.AddTo(&StringifyEncoder{Encoder: capture})
+		return zapcore.Field{Key: f.Key, Type: zapcore.StringType, String: capture.value}
+	default:
+		return f
+	}
+}
+
+// stringCapture records the value of the string added to it. It is only used
+// with scalar fields, which add a single string through a StringifyEncoder.
+type stringCapture struct {
+	zapcore.Encoder
+	value string
+}
+
+func (c *stringCapture) AddString(_, v string) { c.value = v }
+
+func (s *StringifyEncoder) AddBool(k string, v bool)              { s.AddString(k, strconv.FormatBool(v)) }
+func (s *StringifyEncoder) AddComplex128(k string, v complex128)  { s.AddString(k, fmt.Sprint(v)) }
+func (s *StringifyEncoder) AddComplex64(k string, v complex64)    { s.AddString(k, fmt.Sprint(v)) }
+func (s *StringifyEncoder) AddDuration(k string, v time.Duration) { s.AddString(k, v.String()) }
+func (s *StringifyEncoder) AddFloat64(k string, v float64)        { s.AddString(k, formatFloat(v, 64)) }
+func (s *StringifyEncoder) AddFloat32(k string, v float32) {
+	s.AddString(k, formatFloat(float64(v), 32))
+}
+func (s *StringifyEncoder) AddInt(k string, v int)         { s.AddInt64(k, int64(v)) }
+func (s *StringifyEncoder) AddInt64(k string, v int64)     { s.AddString(k, strconv.FormatInt(v, 10)) }
+func (s *StringifyEncoder) AddInt32(k string, v int32)     { s.AddInt64(k, int64(v)) }
+func (s *StringifyEncoder) AddInt16(k string, v int16)     { s.AddInt64(k, int64(v)) }
+func (s *StringifyEncoder) AddInt8(k string, v int8)       { s.AddInt64(k, int64(v)) }
+func (s *StringifyEncoder) AddTime(k string, v time.Time)  { s.AddString(k, v.Format(time.RFC3339Nano)) }
+func (s *StringifyEncoder) AddUint(k string, v uint)       { s.AddUint64(k, uint64(v)) }
+func (s *StringifyEncoder) AddUint64(k string, v uint64)   { s.AddString(k, strconv.FormatUint(v, 10)) }
+func (s *StringifyEncoder) AddUint32(k string, v uint32)   { s.AddUint64(k, uint64(v)) }
+func (s *StringifyEncoder) AddUint16(k string, v uint16)   { s.AddUint64(k, uint64(v)) }
+func (s *StringifyEncoder) AddUint8(k string, v uint8)     { s.AddUint64(k, uint64(v)) }
+func (s *StringifyEncoder) AddUintptr(k string, v uintptr) { s.AddUint64(k, uint64(v)) }
+
+func formatFloat(f float64, bitSize int) string { return strconv.FormatFloat(f, 'g', -1, bitSize) }
@@ -0,0 +1,89 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fabenc_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging/fabenc"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestStringifyEncoder(t *testing.T) {
+	enc := fabenc.NewStringifyEncoder(zapcore.NewJSONEncoder(zapcore.EncoderConfig{
+		MessageKey: "msg",
+	}))
+
+	buf, err := enc.EncodeEntry(zapcore.Entry{Message: "message"}, []zapcore.Field{
+		zap.Int("int", -42),
+		zap.Uint8("uint8", 7),
+		zap.Float64("float64", 1.5),
+		zap.Bool("bool", true),
+		zap.Duration("duration", 1500*time.Millisecond),
+		zap.String("string", "value"),
+		zap.Strings("strings", []string{"a", "b"}),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t,
+		`{"msg":"message","int":"-42","uint8":"7","float64":"1.5","bool":"true","duration":"1.5s","string":"value","strings":["a","b"]}`+"\n",
+		buf.String(),
+	)
+}
+
+// fieldRecorder records the fields passed to EncodeEntry.
+type fieldRecorder struct {
+	zapcore.Encoder
+	fields []zapcore.Field
+}
+
+func (f *fieldRecorder) EncodeEntry(entry zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	f.fields = fields
+	return f.Encoder.EncodeEntry(entry, fields)
+}
+
+func TestStringifyEncoderEntryFields(t *testing.T) {
+	recorder := &fieldRecorder{Encoder: zapcore.NewJSONEncoder(zapcore.EncoderConfig{})}
+	enc := fabenc.NewStringifyEncoder(recorder)
+
+	fields := []zapcore.Field{
+		zap.Int("int", 1),
+		zap.Time("time", time.Unix(0, 0).UTC()),
+		zap.String("string", "value"),
+		zap.Strings("strings", []string{"a"}),
+	}
+	original := append([]zapcore.Field(nil), fields...)
+	_, err := enc.EncodeEntry(zapcore.Entry{}, fields)
+	assert.NoError(t, err)
+	assert.Equal(t, []zapcore.Field{
+		zap.String("int", "1"),
+		zap.String("time", "1970-01-01T00:00:00Z"),
+		zap.String("string", "value"),
+		zap.Strings("strings", []string{"a"}),
+	}, recorder.fields, "the stringified fields should be passed to the wrapped encoder")
+	assert.Equal(t, original, fields, "input fields must not be modified")
+}
+
+func TestStringifyEncoderClone(t *testing.T) {
+	enc := fabenc.NewStringifyEncoder(zapcore.NewJSONEncoder(zapcore.EncoderConfig{}))
+	clone := enc.Clone()
+	assert.Equal(t, enc, clone)
+
+	enc.AddInt("int", 1)
+	clone.AddBool("bool", false)
+
+	buf, err := enc.EncodeEntry(zapcore.Entry{}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"int":"1"}`+"\n", buf.String())
+
+	buf, err = clone.EncodeEntry(zapcore.Entry{}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"bool":"false"}`+"\n", buf.String())
+}
@@ -0,0 +1,51 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fabenc
+
+import (
+	"sync"
+	"time"
+)
+
+// A TimeElider tracks the second of the last timestamp written to an output
+// so that a FormatEncoder can replace timestamps that repeat it with blank
+// space. Encoders cloned from one another share the TimeElider they were
+// given, so a single TimeElider should be used for all of the encoders that
+// write to the same output. It is safe for concurrent use, but entries encoded
+// concurrently may reach the output in a different order than they were
+// encoded.
+type TimeElider struct {
+	mutex sync.Mutex
+	last  time.Time
+	valid bool
+}
+
+// NewTimeElider creates a TimeElider that has not seen a timestamp.
+func NewTimeElider() *TimeElider {
+	return &TimeElider{}
+}
+
+// Elide records t and reports whether it falls within the same second as the
+// previously recorded time.
+func (t *TimeElider) Elide(ts time.Time) bool {
+	second := ts.Truncate(time.Second)
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	repeated := t.valid && second.Equal(t.last)
+	t.last, t.valid = second, true
+	return repeated
+}
+
+// Reset forgets the previously recorded time so that the next timestamp is
+// written. It should be called when the output changes.
+func (t *TimeElider) Reset() {
+	t.mutex.Lock()
+	t.valid = false
+	t.mutex.Unlock()
+}
@@ -0,0 +1,60 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fabenc_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging/fabenc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestTimeElider(t *testing.T) {
+	elider := fabenc.NewTimeElider()
+	ts := time.Unix(1590000000, 0)
+
+	assert.False(t, elider.Elide(ts))
+	assert.True(t, elider.Elide(ts.Add(500*time.Millisecond)))
+	assert.False(t, elider.Elide(ts.Add(time.Second)))
+	assert.True(t, elider.Elide(ts.Add(time.Second)))
+
+	elider.Reset()
+	assert.False(t, elider.Elide(ts.Add(time.Second)))
+}
+
+func TestEncodeElideRepeatedTime(t *testing.T) {
+	formatters, err := fabenc.ParseFormat("%{time:15:04:05.000} %{level} %{message}")
+	require.NoError(t, err)
+	enc := fabenc.NewFormatEncoder(formatters...)
+	enc.TimeElider = fabenc.NewTimeElider()
+	clone := enc.Clone()
+
+	ts := time.Date(2020, 5, 20, 18, 40, 0, 0, time.UTC)
+	var lines []string
+	for i, offset := range []time.Duration{0, 100 * time.Millisecond, 999 * time.Millisecond, time.Second, 1500 * time.Millisecond} {
+		e := enc
+		if i%2 == 1 {
+			e = clone.(*fabenc.FormatEncoder)
+		}
+		buf, err := e.EncodeEntry(zapcore.Entry{Level: zapcore.InfoLevel, Time: ts.Add(offset), Message: "message"}, nil)
+		require.NoError(t, err)
+		lines = append(lines, buf.String())
+	}
+
+	blank := strings.Repeat(" ", len("18:40:00.000"))
+	assert.Equal(t, []string{
+		"18:40:00.000 INFO message\n",
+		blank + " INFO message\n",
+		blank + " INFO message\n",
+		"18:40:01.000 INFO message\n",
+		blank + " INFO message\n",
+	}, lines)
+}
@@ -0,0 +1,105 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fabenc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// A tree is a nested object field that is rendered below the formatted line
+// as an indented tree of key=value pairs. Multi-line text produced by a
+// ConsoleMarshaler is also rendered below the line as an indented block.
+type tree struct {
+	key   string
+	value map[string]interface{}
+	text  string
+}
+
+// objectTree captures the fields of an ObjectMarshaler, including nested
+// objects and namespaces, as nested maps.
+func objectTree(key string, obj zapcore.ObjectMarshaler) (tree, error) {
+	enc := zapcore.NewMapObjectEncoder()
+	err := obj.MarshalLogObject(enc)
+	return tree{key: key, value: enc.Fields}, err
+}
+
+// reflectedTree returns the tree for reflected map values. Values of any other
+// kind are not rendered as trees.
+func reflectedTree(key string, value interface{}) (tree, bool) {
+	if value == nil || reflect.TypeOf(value).Kind() != reflect.Map {
+		return tree{}, false
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return tree{}, false
+	}
+	dec := json.NewDecoder(bytes.NewReader(encoded))
+	dec.UseNumber()
+	var m map[string]interface{}
+	if err := dec.Decode(&m); err != nil || m == nil {
+		return tree{}, false
+	}
+	return tree{key: key, value: m}, true
+}
+
+// render writes the tree with each nesting level indented by two spaces.
+func (t tree) render(sb *strings.Builder, depth int) {
+	indent := strings.Repeat("  ", depth)
+	sb.WriteString(indent)
+	sb.WriteString(LogfmtKey(t.key))
+	sb.WriteString(":")
+
+	if t.value == nil {
+		for _, line := range strings.Split(strings.TrimSuffix(t.text, "\n"), "\n") {
+			sb.WriteString("\n")
+			sb.WriteString(indent)
+			sb.WriteString("  ")
+			sb.WriteString(line)
+		}
+		return
+	}
+
+	keys := make([]string, 0, len(t.value))
+	for k := range t.value {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		sb.WriteString("\n")
+		if m, ok := t.value[k].(map[string]interface{}); ok {
+			tree{key: k, value: m}.render(sb, depth+1)
+			continue
+		}
+		sb.WriteString(indent)
+		sb.WriteString("  ")
+		sb.WriteString(LogfmtKey(k))
+		sb.WriteString("=")
+		sb.WriteString(LogfmtValue(leafString(t.value[k])))
+	}
+}
+
+func leafString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []interface{}:
+		if encoded, err := json.Marshal(v); err == nil {
+			return string(encoded)
+		}
+	case fmt.Stringer:
+		return v.String()
+	}
+	return fmt.Sprint(v)
+}
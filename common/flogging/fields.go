@@ -0,0 +1,294 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Duration constructs a field for a time.Duration. It is rendered by the
+// duration encoder of each encoding: the structured encodings render a number
+// of milliseconds and the CONSOLE encoding a human readable string, such as
+// 1.2s.
+func Duration(key string, d time.Duration) zapcore.Field {
+	return zap.Duration(key, d)
+}
+
+// millisDurationEncoder encodes a time.Duration as a possibly fractional
+// number of milliseconds.
+func millisDurationEncoder(d time.Duration, enc zapcore.PrimitiveArrayEncoder) {
+	enc.AppendFloat64(float64(d) / float64(time.Millisecond))
+}
+
+// Bytes constructs a field that renders a byte count as an integer in the
+// structured encodings and as a human readable string using binary units,
+// such as 1.5 GiB, in the CONSOLE encoding.
+func Bytes(key string, n int64) zapcore.Field {
+	// like the location of a zap.Time field, the interface value is ignored
+	// by everything but the CONSOLE encoding
+	field := zap.Int64(key, n)
+	field.Interface = byteSize(n)
+	return field
+}
+
+type byteSize int64
+
+var byteUnits = []string{"KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+
+func (b byteSize) MarshalConsole() string {
+	n, sign := float64(b), ""
+	if n < 0 {
+		n, sign = -n, "-"
+	}
+	if n < 1024 {
+		return strconv.FormatInt(int64(b), 10) + " B"
+	}
+
+	unit := -1
+	for n >= 1024 && unit < len(byteUnits)-1 {
+		n /= 1024
+		unit++
+	}
+	s := strings.TrimSuffix(strconv.FormatFloat(n, 'f', 1, 64), ".0")
+	return sign + s + " " + byteUnits[unit]
+}
+
+// HexDump constructs a field for binary data that is rendered as a hex string
+// in the structured encodings and as a classic dump of offsets, hex bytes,
+// and printable characters in the CONSOLE encoding. At most maxBytes bytes
+// are rendered; a maxBytes of zero or less renders the entire slice.
+// Truncated hex strings end with an ellipsis and truncated dumps end with a
+// line that records the total length.
+func HexDump(key string, b []byte, maxBytes int) zapcore.Field {
+	return zap.Stringer(key, hexDump{data: b, max: maxBytes})
+}
+
+type hexDump struct {
+	data []byte
+	max  int
+}
+
+func (h hexDump) truncated() ([]byte, bool) {
+	if h.max > 0 && len(h.data) > h.max {
+		return h.data[:h.max], true
+	}
+	return h.data, false
+}
+
+func (h hexDump) String() string {
+	b, truncated := h.truncated()
+	s := hex.EncodeToString(b)
+	if truncated {
+		s += "..."
+	}
+	return s
+}
+
+func (h hexDump) MarshalConsole() string {
+	b, truncated := h.truncated()
+	dump := hex.Dump(b)
+	if truncated {
+		dump += fmt.Sprintf("... truncated, %d bytes total\n", len(h.data))
+	}
+	return dump
+}
+
+// SortedMap constructs a field for a map of strings that is encoded as an
+// object with its keys in sorted order so that the structured encodings are
+// stable from run to run. The CONSOLE encoding renders the map as a tree.
+func SortedMap(key string, m map[string]string) zapcore.Field {
+	return zap.Object(key, sortedMap(m))
+}
+
+type sortedMap map[string]string
+
+func (s sortedMap) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	keys := make([]string, 0, len(s))
+	for k := range s {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		enc.AddString(k, s[k])
+	}
+	return nil
+}
+
+// TLSState constructs a field for the negotiated parameters of a TLS
+// connection. The field is an object with the protocol version, the cipher
+// suite name, and, when the peer presented a certificate, the subject of the
+// peer's leaf certificate. A nil connection state produces no field.
+func TLSState(key string, cs *tls.ConnectionState) zapcore.Field {
+	if cs == nil {
+		return zap.Skip()
+	}
+	return zap.Object(key, tlsState{cs})
+}
+
+type tlsState struct{ *tls.ConnectionState }
+
+var tlsVersions = map[uint16]string{
+	tls.VersionTLS10: "TLS 1.0",
+	tls.VersionTLS11: "TLS 1.1",
+	tls.VersionTLS12: "TLS 1.2",
+	tls.VersionTLS13: "TLS 1.3",
+}
+
+func (t tlsState) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	version, ok := tlsVersions[t.Version]
+	if !ok {
+		version = fmt.Sprintf("0x%04x", t.Version)
+	}
+	enc.AddString("version", version)
+	enc.AddString("cipher_suite", tls.CipherSuiteName(t.CipherSuite))
+	if t.ServerName != "" {
+		enc.AddString("server_name", t.ServerName)
+	}
+	if len(t.PeerCertificates) > 0 {
+		enc.AddString("peer_subject", t.PeerCertificates[0].Subject.String())
+	}
+	return nil
+}
+
+// RecoveredPanic constructs a field named panic that records a value returned
+// by recover along with the stack of the panicking goroutine. The stack is
+// trimmed to exclude the recovery and panic machinery so that it starts at the
+// frame that panicked. RecoveredPanic should be called from the deferred
+// function that recovered the panic.
+func RecoveredPanic(r interface{}) zapcore.Field {
+	return zap.Reflect("panic", recoveredPanic{
+		value:  panicValue(r),
+		frames: panicFrames(),
+	})
+}
+
+type recoveredPanic struct {
+	value  string
+	frames []runtime.Frame
+}
+
+func panicValue(r interface{}) string {
+	if err, ok := r.(error); ok {
+		return err.Error()
+	}
+	return fmt.Sprint(r)
+}
+
+// panicFrames returns the callers of RecoveredPanic with the frames of the
+// deferred function and the runtime panic handling removed.
+func panicFrames() []runtime.Frame {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var stack []runtime.Frame
+	for {
+		frame, more := frames.Next()
+		stack = append(stack, frame)
+		if !more {
+			break
+		}
+	}
+
+	// drop the deferred function and the panic machinery when present
+	for i := range stack {
+		if stack[i].Function == "runtime.gopanic" {
+			stack = stack[i+1:]
+			for len(stack) > 0 && isRuntimeFrame(stack[0]) {
+				stack = stack[1:]
+			}
+			break
+		}
+	}
+	for len(stack) > 0 && isRuntimeFrame(stack[len(stack)-1]) {
+		stack = stack[:len(stack)-1]
+	}
+	return stack
+}
+
+func isRuntimeFrame(frame runtime.Frame) bool {
+	return strings.HasPrefix(frame.Function, "runtime.")
+}
+
+// stack renders the frames in the same form as zap stack traces.
+func (p recoveredPanic) stack() string {
+	var sb strings.Builder
+	for i, frame := range p.frames {
+		if i > 0 {
+			sb.WriteByte('\n')
+		}
+		fmt.Fprintf(&sb, "%s\n\t%s:%d", frame.Function, frame.File, frame.Line)
+	}
+	return sb.String()
+}
+
+func (p recoveredPanic) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Value string `json:"value"`
+		Stack string `json:"stack"`
+	}{p.value, p.stack()})
+}
+
+func (p recoveredPanic) MarshalText() ([]byte, error) {
+	return []byte(p.MarshalConsole()), nil
+}
+
+// MarshalConsole renders the value followed by the short form of each frame.
+func (p recoveredPanic) MarshalConsole() string {
+	var sb strings.Builder
+	sb.WriteString(p.value)
+	for i, frame := range p.frames {
+		if i == 0 {
+			sb.WriteString(" at ")
+		} else {
+			sb.WriteString(" <- ")
+		}
+		fmt.Fprintf(&sb, "%s(%s:%d)", shortFunction(frame.Function), shortFile(frame.File), frame.Line)
+	}
+	return sb.String()
+}
+
+func shortFunction(function string) string {
+	if idx := strings.LastIndexByte(function, '/'); idx >= 0 {
+		function = function[idx+1:]
+	}
+	return function
+}
+
+func shortFile(file string) string {
+	if idx := strings.LastIndexByte(file, '/'); idx >= 0 {
+		file = file[idx+1:]
+	}
+	return file
+}
+
+// stringerValue returns the result of the String method of the fmt.Stringer
+// v. As zap does when encoding the value, it recovers when String panics,
+// which is commonly the case for typed nil pointers, and returns false.
+func stringerValue(v interface{}) (s string, ok bool) {
+	defer func() {
+		if recover() != nil {
+			s, ok = "", false
+		}
+	}()
+	stringer, ok := v.(fmt.Stringer)
+	if !ok {
+		return "", false
+	}
+	return stringer.String(), true
+}
@@ -0,0 +1,223 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging_test
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/common/flogging/fabenc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+// encodeField logs a single entry with the provided field in the requested
+// format and returns the encoded line.
+func encodeField(t *testing.T, format string, field zapcore.Field) string {
+	buf := &bytes.Buffer{}
+	logging, err := flogging.New(flogging.Config{
+		Format: format,
+		Writer: buf,
+	})
+	assert.NoError(t, err)
+
+	logging.ZapLogger("fields").Info("message", field)
+	return buf.String()
+}
+
+func TestDuration(t *testing.T) {
+	field := flogging.Duration("dur_ms", 1200*time.Millisecond+500*time.Microsecond)
+
+	assert.Contains(t, encodeField(t, "json", field), `"dur_ms":1200.5}`)
+	assert.Contains(t, encodeField(t, "logfmt", field), ` dur_ms=1200.5`)
+	assert.Equal(t, "message dur_ms=1.2005s\n", encodeField(t, "%{message}", field))
+}
+
+func TestBytes(t *testing.T) {
+	field := flogging.Bytes("size", 3*1024*1024*1024/2)
+
+	assert.Contains(t, encodeField(t, "json", field), `"size":1610612736}`)
+	assert.Contains(t, encodeField(t, "logfmt", field), ` size=1610612736`)
+	assert.Equal(t, "message size=\"1.5 GiB\"\n", encodeField(t, "%{message}", field))
+}
+
+func TestBytesConsole(t *testing.T) {
+	var tests = []struct {
+		n        int64
+		expected string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1 KiB"},
+		{1536, "1.5 KiB"},
+		{10*1024*1024 + 300*1024, "10.3 MiB"},
+		{-2048, "-2 KiB"},
+		{1 << 62, "4 EiB"},
+	}
+	for _, tc := range tests {
+		line := encodeField(t, "%{message}", flogging.Bytes("size", tc.n))
+		assert.Equal(t, "message size="+fabenc.LogfmtValue(tc.expected)+"\n", line, "bytes %d", tc.n)
+	}
+}
+
+func TestHexDump(t *testing.T) {
+	data := []byte("hello, fabric!\x00\x01\x02\xff and more")
+	field := flogging.HexDump("payload", data, 20)
+
+	assert.Contains(t, encodeField(t, "json", field), `"payload":"68656c6c6f2c2066616272696321000102ff2061..."}`)
+	assert.Contains(t, encodeField(t, "logfmt", field), ` payload=68656c6c6f2c2066616272696321000102ff2061...`)
+	assert.Equal(t, "message\n"+
+		"  payload:\n"+
+		"    00000000  68 65 6c 6c 6f 2c 20 66  61 62 72 69 63 21 00 01  |hello, fabric!..|\n"+
+		"    00000010  02 ff 20 61                                       |.. a|\n"+
+		"    ... truncated, 27 bytes total\n",
+		encodeField(t, "%{message}", field),
+	)
+
+	field = flogging.HexDump("payload", []byte("short"), 0)
+	assert.Contains(t, encodeField(t, "json", field), `"payload":"73686f7274"}`)
+	assert.Equal(t, "message\n  payload:\n    00000000  73 68 6f 72 74                                    |short|\n", encodeField(t, "%{message}", field))
+
+	field = flogging.HexDump("payload", nil, 10)
+	assert.Contains(t, encodeField(t, "json", field), `"payload":""}`)
+	assert.Equal(t, "message payload=\"\"\n", encodeField(t, "%{message}", field))
+}
+
+func TestTypedFieldsWith(t *testing.T) {
+	fields := []zapcore.Field{
+		flogging.Duration("duration", 1500*time.Millisecond),
+		flogging.Bytes("size", 2048),
+		flogging.HexDump("payload", []byte("ab"), 0),
+	}
+	assert.Equal(t, zapcore.DurationType, fields[0].Type)
+	assert.Equal(t, zapcore.Int64Type, fields[1].Type)
+	assert.Equal(t, zapcore.StringerType, fields[2].Type)
+
+	for _, format := range []string{"json", "%{message}"} {
+		buf := &bytes.Buffer{}
+		logging, err := flogging.New(flogging.Config{Format: format, Writer: buf})
+		require.NoError(t, err)
+
+		logger := logging.ZapLogger("fields")
+		logger.With(fields...).Info("with")
+		logger.Info("entry", fields...)
+		lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		if format == "json" {
+			require.Len(t, lines, 2)
+			for _, line := range lines {
+				assert.Contains(t, line, `"duration":1500,"size":2048,"payload":"6162"`)
+			}
+			continue
+		}
+		dump := "  payload:\n    00000000  61 62                                             |ab|"
+		assert.Equal(t, "with duration=1.5s size=\"2 KiB\"\n"+dump+"\nentry duration=1.5s size=\"2 KiB\"\n"+dump+"\n", buf.String())
+	}
+}
+
+func panicker() {
+	panic("boom")
+}
+
+func recoveredPanicField(f func()) (field zapcore.Field) {
+	defer func() {
+		field = flogging.RecoveredPanic(recover())
+	}()
+	f()
+	return
+}
+
+func TestSortedMap(t *testing.T) {
+	m := map[string]string{}
+	for _, k := range []string{"peer.id", "channel", "zone", "address", "b", "a"} {
+		m[k] = "value-" + k
+	}
+	field := flogging.SortedMap("config", m)
+
+	expected := `"config":{"a":"value-a","address":"value-address","b":"value-b","channel":"value-channel","peer.id":"value-peer.id","zone":"value-zone"}}`
+	for i := 0; i < 20; i++ {
+		assert.Contains(t, encodeField(t, "json", field), expected)
+	}
+	assert.Equal(t, "message\n"+
+		"  config:\n"+
+		"    a=value-a\n"+
+		"    address=value-address\n"+
+		"    b=value-b\n"+
+		"    channel=value-channel\n"+
+		"    peer.id=value-peer.id\n"+
+		"    zone=value-zone\n",
+		encodeField(t, "%{message}", field),
+	)
+	assert.Contains(t, encodeField(t, "json", flogging.SortedMap("empty", nil)), `"empty":{}}`)
+}
+
+func TestTLSState(t *testing.T) {
+	cs := &tls.ConnectionState{
+		Version:     tls.VersionTLS12,
+		CipherSuite: tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+		ServerName:  "peer0.org1.example.com",
+		PeerCertificates: []*x509.Certificate{
+			{Subject: pkix.Name{CommonName: "peer0.org1.example.com", Organization: []string{"Org1"}}},
+		},
+	}
+
+	assert.Contains(t, encodeField(t, "json", flogging.TLSState("tls", cs)), `"tls":{`+
+		`"version":"TLS 1.2",`+
+		`"cipher_suite":"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",`+
+		`"server_name":"peer0.org1.example.com",`+
+		`"peer_subject":"CN=peer0.org1.example.com,O=Org1"}`,
+	)
+
+	// fields that are not available are omitted
+	minimal := encodeField(t, "json", flogging.TLSState("tls", &tls.ConnectionState{Version: 0x0305, CipherSuite: 0x1ff}))
+	assert.Contains(t, minimal, `"tls":{"version":"0x0305","cipher_suite":"0x01FF"}`)
+
+	assert.Equal(t, "message\n", encodeField(t, "%{message}", flogging.TLSState("tls", nil)))
+	assert.NotContains(t, encodeField(t, "json", flogging.TLSState("tls", nil)), "tls")
+}
+
+func TestRecoveredPanic(t *testing.T) {
+	field := recoveredPanicField(panicker)
+
+	var decoded struct {
+		Panic struct {
+			Value string `json:"value"`
+			Stack string `json:"stack"`
+		} `json:"panic"`
+	}
+	err := json.Unmarshal([]byte(encodeField(t, "json", field)), &decoded)
+	require.NoError(t, err)
+	assert.Equal(t, "boom", decoded.Panic.Value)
+	require.NotEmpty(t, decoded.Panic.Stack)
+	assert.True(t, strings.HasPrefix(decoded.Panic.Stack, "github.com/hyperledger/fabric/common/flogging_test.panicker\n\t"), "stack must start at the panicking frame: %s", decoded.Panic.Stack)
+	assert.NotContains(t, decoded.Panic.Stack, "runtime.gopanic")
+	assert.NotContains(t, decoded.Panic.Stack, "recoveredPanicField.func1")
+	assert.Contains(t, decoded.Panic.Stack, "flogging_test.recoveredPanicField\n\t")
+
+	console := encodeField(t, "%{message}", field)
+	assert.Regexp(t, `^message panic="boom at flogging_test\.panicker\(fields_test\.go:\d+\) <- flogging_test\.recoveredPanicField\(fields_test\.go:\d+\) <- `, console)
+	assert.NotContains(t, console, "runtime.")
+}
+
+func TestRecoveredPanicError(t *testing.T) {
+	field := recoveredPanicField(func() {
+		var m map[string]int
+		m["key"] = 1
+	})
+	assert.Contains(t, encodeField(t, "json", field), `"value":"assignment to entry in nil map"`)
+
+	field = recoveredPanicField(func() { panic(errors.New("failed")) })
+	assert.Contains(t, encodeField(t, "json", field), `{"value":"failed","stack":"github.com/hyperledger/fabric/common/flogging_test.TestRecoveredPanicError.func2\n\t`)
+}
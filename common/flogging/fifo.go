@@ -0,0 +1,204 @@
+//go:build darwin || linux
+// +build darwin linux
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"os"
+	"sync"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// A FifoSyncer is a zapcore.WriteSyncer that writes log records to a named
+// pipe for consumption by a collector. The pipe is created when it does not
+// exist.
+//
+// Opening a pipe for writing blocks until a reader connects, so the FifoSyncer
+// opens the pipe without blocking and buffers records in memory while no
+// reader is connected. Buffered records are written, in order, once a reader
+// connects. When the reader disconnects, the pipe is closed and records are
+// buffered until a reader connects again. Records that a reader had not read
+// before disconnecting are lost with the pipe buffer. Records are buffered
+// whole; a record that was partially written when the reader disconnected is
+// dropped and counted rather than resumed mid-record. Once more than the
+// buffer limit is held in memory, new records are dropped and counted.
+//
+// After a reader has connected, writes block while the pipe is full.
+type FifoSyncer struct {
+	path        string
+	maxBuffered int
+
+	mutex         sync.Mutex
+	file          *os.File
+	buffered      [][]byte
+	bufferedBytes int
+	dropped       uint64
+	closed        bool
+}
+
+// NewFifoSyncer creates a FifoSyncer that writes to the named pipe at path,
+// creating it when it does not exist. Up to maxBuffered bytes are held in
+// memory while no reader is connected.
+func NewFifoSyncer(path string, maxBuffered int) (*FifoSyncer, error) {
+	err := syscall.Mkfifo(path, 0600)
+	if err != nil && err != syscall.EEXIST {
+		return nil, errors.Wrapf(err, "failed to create fifo %s", path)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to stat fifo %s", path)
+	}
+	if info.Mode()&os.ModeNamedPipe == 0 {
+		return nil, errors.Errorf("%s is not a named pipe", path)
+	}
+
+	return &FifoSyncer{path: path, maxBuffered: maxBuffered}, nil
+}
+
+// Write writes b to the pipe or, when no reader is connected, buffers it.
+// Write returns an error only when the pipe cannot be opened or written for a
+// reason other than the absence of a reader.
+func (f *FifoSyncer) Write(b []byte) (int, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.closed {
+		return 0, errors.New("fifo is closed")
+	}
+	if err := f.flush(); err != nil {
+		return 0, err
+	}
+	if f.file == nil || len(f.buffered) > 0 {
+		f.buffer(b)
+		return len(b), nil
+	}
+
+	n, err := f.file.Write(b)
+	if isBrokenPipe(err) {
+		f.disconnect()
+		if n == 0 {
+			f.buffer(b)
+		} else {
+			f.dropped++
+		}
+		return len(b), nil
+	}
+	return n, err
+}
+
+// Sync writes the buffered records when a reader is connected.
+func (f *FifoSyncer) Sync() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.closed {
+		return nil
+	}
+	return f.flush()
+}
+
+// Close closes the pipe. Records that are still buffered are discarded.
+func (f *FifoSyncer) Close() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.closed {
+		return nil
+	}
+	f.closed = true
+	f.buffered, f.bufferedBytes = nil, 0
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// Dropped returns the number of records dropped because the buffer was full
+// or because the reader disconnected while they were being written.
+func (f *FifoSyncer) Dropped() uint64 {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.dropped
+}
+
+// flush connects to a reader, when one is available, and writes the buffered
+// records. It must be called with the mutex held.
+func (f *FifoSyncer) flush() error {
+	if f.file == nil {
+		file, err := os.OpenFile(f.path, os.O_WRONLY|syscall.O_NONBLOCK, 0)
+		if isNoReader(err) {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to open fifo %s", f.path)
+		}
+		f.file = file
+	}
+
+	for len(f.buffered) > 0 {
+		n, err := f.file.Write(f.buffered[0])
+		if err == nil || n > 0 {
+			f.shift()
+		}
+		if err != nil && n > 0 {
+			f.dropped++
+		}
+		if isBrokenPipe(err) {
+			f.disconnect()
+			return nil
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to write to fifo %s", f.path)
+		}
+	}
+	f.buffered = nil
+	return nil
+}
+
+// buffer holds a copy of the record b until a reader connects. It must be
+// called with the mutex held.
+func (f *FifoSyncer) buffer(b []byte) {
+	if len(b) == 0 {
+		return
+	}
+	if f.bufferedBytes+len(b) > f.maxBuffered {
+		f.dropped++
+		return
+	}
+	f.buffered = append(f.buffered, append([]byte(nil), b...))
+	f.bufferedBytes += len(b)
+}
+
+// shift removes the oldest buffered record. It must be called with the mutex
+// held.
+func (f *FifoSyncer) shift() {
+	f.bufferedBytes -= len(f.buffered[0])
+	f.buffered[0] = nil
+	f.buffered = f.buffered[1:]
+}
+
+// disconnect closes the pipe after the reader has gone away. It must be
+// called with the mutex held.
+func (f *FifoSyncer) disconnect() {
+	f.file.Close()
+	f.file = nil
+}
+
+func isNoReader(err error) bool {
+	return errno(err) == syscall.ENXIO
+}
+
+func isBrokenPipe(err error) bool {
+	return errno(err) == syscall.EPIPE
+}
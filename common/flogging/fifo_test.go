@@ -0,0 +1,198 @@
+//go:build darwin || linux
+// +build darwin linux
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging_test
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openFifoReader connects a reader to the pipe without waiting for a writer.
+func openFifoReader(t *testing.T, path string) (*os.File, *bufio.Reader) {
+	r, err := os.OpenFile(path, os.O_RDONLY|syscall.O_NONBLOCK, 0)
+	require.NoError(t, err)
+	return r, bufio.NewReader(r)
+}
+
+func readFifoLine(t *testing.T, r *bufio.Reader) string {
+	lines := make(chan string, 1)
+	go func() {
+		line, _ := r.ReadString('\n')
+		lines <- line
+	}()
+	select {
+	case line := <-lines:
+		return line
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out reading from fifo")
+		return ""
+	}
+}
+
+func TestFifoSyncer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fifo")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "log.fifo")
+
+	syncer, err := flogging.NewFifoSyncer(path, 1024)
+	require.NoError(t, err)
+	defer syncer.Close()
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.NotZero(t, info.Mode()&os.ModeNamedPipe, "the fifo should be created")
+
+	// records are buffered until a reader connects
+	_, err = syncer.Write([]byte("early\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, syncer.Sync())
+
+	reader, r := openFifoReader(t, path)
+	_, err = syncer.Write([]byte("connected\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "early\n", readFifoLine(t, r))
+	assert.Equal(t, "connected\n", readFifoLine(t, r))
+
+	// the reader goes away mid-stream
+	reader.Close()
+	_, err = syncer.Write([]byte("disconnected\n"))
+	assert.NoError(t, err)
+	_, err = syncer.Write([]byte("buffered\n"))
+	assert.NoError(t, err)
+
+	// a new reader receives the records written while it was away
+	reader, r = openFifoReader(t, path)
+	defer reader.Close()
+	assert.NoError(t, syncer.Sync())
+	assert.Equal(t, "disconnected\n", readFifoLine(t, r))
+	assert.Equal(t, "buffered\n", readFifoLine(t, r))
+	assert.Zero(t, syncer.Dropped())
+
+	assert.NoError(t, syncer.Close())
+	_, err = syncer.Write([]byte("closed\n"))
+	assert.EqualError(t, err, "fifo is closed")
+}
+
+func TestFifoSyncerBufferLimit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fifo")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "log.fifo")
+
+	syncer, err := flogging.NewFifoSyncer(path, 10)
+	require.NoError(t, err)
+	defer syncer.Close()
+
+	for _, record := range []string{"one\n", "two\n", "three\n", "four\n"} {
+		_, err = syncer.Write([]byte(record))
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, uint64(2), syncer.Dropped())
+
+	reader, r := openFifoReader(t, path)
+	defer reader.Close()
+	assert.NoError(t, syncer.Sync())
+	assert.Equal(t, "one\n", readFifoLine(t, r))
+	assert.Equal(t, "two\n", readFifoLine(t, r))
+}
+
+func TestFifoSyncerExistingPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fifo")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "log.fifo")
+	require.NoError(t, syscall.Mkfifo(path, 0600))
+	_, err = flogging.NewFifoSyncer(path, 10)
+	assert.NoError(t, err, "an existing fifo is reused")
+
+	file := filepath.Join(dir, "regular")
+	require.NoError(t, ioutil.WriteFile(file, nil, 0600))
+	_, err = flogging.NewFifoSyncer(file, 10)
+	assert.EqualError(t, err, file+" is not a named pipe")
+
+	_, err = flogging.NewFifoSyncer(filepath.Join(dir, "missing", "log.fifo"), 10)
+	assert.Error(t, err)
+}
+
+// disconnectFifoReader closes the reader once a write larger than the pipe
+// capacity is blocked on the full pipe.
+func disconnectFifoReader(t *testing.T, reader *os.File, r *bufio.Reader, write func()) {
+	done := make(chan struct{})
+	go func() {
+		write()
+		close(done)
+	}()
+	// the reader sees EOF until the writer has opened the pipe
+	_, err := r.ReadByte()
+	for err == io.EOF {
+		time.Sleep(10 * time.Millisecond)
+		_, err = r.ReadByte()
+	}
+	require.NoError(t, err)
+	time.Sleep(100 * time.Millisecond)
+	reader.Close()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out writing to fifo")
+	}
+}
+
+func TestFifoSyncerPartialRecord(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fifo")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "log.fifo")
+
+	syncer, err := flogging.NewFifoSyncer(path, 1<<20)
+	require.NoError(t, err)
+	defer syncer.Close()
+	large := append(bytes.Repeat([]byte("x"), 256<<10), '\n')
+
+	// the reader disconnects while a record is written
+	reader, r := openFifoReader(t, path)
+	_, err = syncer.Write([]byte("connected\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "connected\n", readFifoLine(t, r))
+	disconnectFifoReader(t, reader, r, func() {
+		_, err := syncer.Write(large)
+		assert.NoError(t, err)
+	})
+	assert.Equal(t, uint64(1), syncer.Dropped(), "the partially written record should be dropped")
+
+	// the reader disconnects while a buffered record is written
+	_, err = syncer.Write(large)
+	require.NoError(t, err)
+	_, err = syncer.Write([]byte("buffered\n"))
+	require.NoError(t, err)
+	reader, r = openFifoReader(t, path)
+	disconnectFifoReader(t, reader, r, func() {
+		assert.NoError(t, syncer.Sync())
+	})
+	assert.Equal(t, uint64(2), syncer.Dropped(), "the partially written record should be dropped")
+
+	// only whole records are written to the next reader
+	reader, r = openFifoReader(t, path)
+	defer reader.Close()
+	assert.NoError(t, syncer.Sync())
+	assert.Equal(t, "buffered\n", readFifoLine(t, r))
+}
@@ -0,0 +1,203 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// The limits imposed by Firehose on PutRecordBatch requests.
+const (
+	FirehoseMaxBatchRecords = 500
+	FirehoseMaxBatchBytes   = 4 * 1024 * 1024
+	FirehoseMaxRecordBytes  = 1000 * 1024
+)
+
+// A FirehoseClient delivers records to a Firehose delivery stream. It is
+// implemented by an adapter around the PutRecordBatch operation of the AWS
+// SDK so the syncer can be used without depending on the SDK.
+type FirehoseClient interface {
+	// PutRecordBatch sends the records to the stream. It returns the indexes
+	// of the records that were rejected or an error when the request failed
+	// as a whole.
+	PutRecordBatch(stream string, records [][]byte) (failed []int, err error)
+}
+
+// The number of full batches that can wait for delivery before Write blocks.
+const firehoseMaxQueuedBatches = 4
+
+// A FirehoseSyncer is a zapcore.WriteSyncer that batches log records and
+// delivers them to a Firehose delivery stream. A batch is sent when adding a
+// record would exceed the Firehose limits on the number of records or bytes
+// in a request, and when Sync is called.
+//
+// Full batches are delivered by a background goroutine so Write does not wait
+// for the request or its retries. Write only blocks when the queue of batches
+// waiting for delivery is full.
+//
+// Records rejected by Firehose are retried with exponential backoff. Records
+// that cannot be delivered within MaxRetries retries are dropped and counted.
+type FirehoseSyncer struct {
+	// MaxRetries is the number of times undelivered records are resent.
+	MaxRetries int
+	// Backoff is the time to wait before the first retry. The wait doubles
+	// with every retry.
+	Backoff time.Duration
+
+	dropped uint64 // accessed atomically
+
+	mutex        sync.Mutex
+	cond         *sync.Cond
+	client       FirehoseClient
+	stream       string
+	pending      [][]byte
+	pendingBytes int
+	queue        [][][]byte
+	sending      bool
+	err          error
+	sleep        func(time.Duration)
+}
+
+// NewFirehoseSyncer creates a FirehoseSyncer that delivers records to the
+// stream with the client. Undelivered records are retried three times,
+// starting after 100ms.
+func NewFirehoseSyncer(client FirehoseClient, stream string) *FirehoseSyncer {
+	f := &FirehoseSyncer{
+		MaxRetries: 3,
+		Backoff:    100 * time.Millisecond,
+		client:     client,
+		stream:     stream,
+		sleep:      time.Sleep,
+	}
+	f.cond = sync.NewCond(&f.mutex)
+	return f
+}
+
+// SetSleep sets the function used to wait between retries.
+func (f *FirehoseSyncer) SetSleep(sleep func(time.Duration)) {
+	f.mutex.Lock()
+	f.sleep = sleep
+	f.mutex.Unlock()
+}
+
+// Write adds a copy of the record to the pending batch, queueing the batch
+// for delivery first when the record would not fit. Records larger than the
+// Firehose record limit are dropped. A failure to deliver the batch is
+// returned by the next call to Sync.
+func (f *FirehoseSyncer) Write(b []byte) (int, error) {
+	if len(b) > FirehoseMaxRecordBytes {
+		atomic.AddUint64(&f.dropped, 1)
+		return 0, errors.Errorf("record of %d bytes exceeds the firehose record limit", len(b))
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if len(f.pending) == FirehoseMaxBatchRecords || f.pendingBytes+len(b) > FirehoseMaxBatchBytes {
+		for len(f.queue) >= firehoseMaxQueuedBatches {
+			f.cond.Wait()
+		}
+		f.enqueue()
+	}
+	f.pending = append(f.pending, append([]byte(nil), b...))
+	f.pendingBytes += len(b)
+	return len(b), nil
+}
+
+// Sync sends the pending batch and waits for the queued batches to be
+// delivered. It returns the first delivery failure since the previous call
+// to Sync.
+func (f *FirehoseSyncer) Sync() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.enqueue()
+	for f.sending {
+		f.cond.Wait()
+	}
+	err := f.err
+	f.err = nil
+	return err
+}
+
+// Dropped returns the number of records that could not be delivered.
+func (f *FirehoseSyncer) Dropped() uint64 {
+	return atomic.LoadUint64(&f.dropped)
+}
+
+// enqueue queues the pending batch for delivery and starts the background
+// sender when it is not running. It must be called with the mutex held.
+func (f *FirehoseSyncer) enqueue() {
+	if len(f.pending) == 0 {
+		return
+	}
+	f.queue = append(f.queue, f.pending)
+	f.pending, f.pendingBytes = nil, 0
+	if !f.sending {
+		f.sending = true
+		go f.send()
+	}
+}
+
+// send delivers the queued batches, in order, until the queue is empty. The
+// mutex is not held while a batch is delivered.
+func (f *FirehoseSyncer) send() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	for len(f.queue) > 0 {
+		records, sleep := f.queue[0], f.sleep
+		f.queue[0] = nil
+		f.queue = f.queue[1:]
+
+		f.mutex.Unlock()
+		err := f.deliver(records, sleep)
+		f.mutex.Lock()
+
+		if err != nil && f.err == nil {
+			f.err = err
+		}
+		f.cond.Broadcast()
+	}
+	f.queue = nil
+	f.sending = false
+	f.cond.Broadcast()
+}
+
+// deliver sends a batch, retrying the records that are not delivered. Indexes
+// of rejected records that are not in the batch are ignored.
+func (f *FirehoseSyncer) deliver(records [][]byte, sleep func(time.Duration)) error {
+	backoff := f.Backoff
+	for attempt := 0; len(records) > 0; attempt++ {
+		failed, err := f.client.PutRecordBatch(f.stream, records)
+		if err == nil {
+			retry := make([][]byte, 0, len(failed))
+			for _, i := range failed {
+				if i >= 0 && i < len(records) {
+					retry = append(retry, records[i])
+				}
+			}
+			records = retry
+			if len(records) == 0 {
+				return nil
+			}
+			err = errors.Errorf("%d records were rejected", len(records))
+		}
+
+		if attempt == f.MaxRetries {
+			atomic.AddUint64(&f.dropped, uint64(len(records)))
+			return errors.Wrapf(err, "failed to deliver %d records to firehose stream %s", len(records), f.stream)
+		}
+		sleep(backoff)
+		backoff *= 2
+	}
+	return nil
+}
@@ -0,0 +1,253 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeFirehose records the batches it receives. Each call consumes the next
+// response; once they are exhausted every record is accepted. When release is
+// set, each call signals called and waits for release before it returns.
+type fakeFirehose struct {
+	mutex     sync.Mutex
+	streams   []string
+	batches   [][][]byte
+	responses []firehoseResponse
+	called    chan struct{}
+	release   chan struct{}
+}
+
+type firehoseResponse struct {
+	failed []int
+	err    error
+}
+
+func (f *fakeFirehose) PutRecordBatch(stream string, records [][]byte) ([]int, error) {
+	if f.release != nil {
+		f.called <- struct{}{}
+		<-f.release
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.streams = append(f.streams, stream)
+	f.batches = append(f.batches, append([][]byte(nil), records...))
+	if len(f.responses) == 0 {
+		return nil, nil
+	}
+	resp := f.responses[0]
+	f.responses = f.responses[1:]
+	return resp.failed, resp.err
+}
+
+func batchStrings(batch [][]byte) []string {
+	var s []string
+	for _, r := range batch {
+		s = append(s, string(r))
+	}
+	return s
+}
+
+func TestFirehoseSyncerSync(t *testing.T) {
+	client := &fakeFirehose{}
+	syncer := flogging.NewFirehoseSyncer(client, "logs")
+
+	record := []byte("first\n")
+	n, err := syncer.Write(record)
+	assert.NoError(t, err)
+	assert.Equal(t, 6, n)
+	copy(record, "xxxxx\n")
+	_, err = syncer.Write([]byte("second\n"))
+	assert.NoError(t, err)
+	assert.Empty(t, client.batches, "records should be batched until Sync")
+
+	assert.NoError(t, syncer.Sync())
+	require.Len(t, client.batches, 1)
+	assert.Equal(t, []string{"logs"}, client.streams)
+	assert.Equal(t, []string{"first\n", "second\n"}, batchStrings(client.batches[0]))
+
+	assert.NoError(t, syncer.Sync())
+	assert.Len(t, client.batches, 1, "an empty batch should not be sent")
+}
+
+func TestFirehoseSyncerRecordLimit(t *testing.T) {
+	client := &fakeFirehose{}
+	syncer := flogging.NewFirehoseSyncer(client, "logs")
+
+	for i := 0; i < flogging.FirehoseMaxBatchRecords+1; i++ {
+		_, err := syncer.Write([]byte(fmt.Sprintf("record-%d\n", i)))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, syncer.Sync())
+	require.Len(t, client.batches, 2)
+	assert.Len(t, client.batches[0], flogging.FirehoseMaxBatchRecords)
+	assert.Equal(t, []string{"record-500\n"}, batchStrings(client.batches[1]))
+}
+
+func TestFirehoseSyncerByteLimit(t *testing.T) {
+	client := &fakeFirehose{}
+	syncer := flogging.NewFirehoseSyncer(client, "logs")
+
+	record := bytes.Repeat([]byte("x"), flogging.FirehoseMaxRecordBytes)
+	for i := 0; i < 5; i++ {
+		_, err := syncer.Write(record)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, syncer.Sync())
+
+	require.Len(t, client.batches, 2)
+	for _, batch := range client.batches {
+		size := 0
+		for _, r := range batch {
+			size += len(r)
+		}
+		assert.True(t, size <= flogging.FirehoseMaxBatchBytes, "batch of %d bytes exceeds the limit", size)
+	}
+	assert.Len(t, client.batches[0], 4)
+	assert.Len(t, client.batches[1], 1)
+
+	_, err := syncer.Write(append(record, 'x'))
+	assert.EqualError(t, err, fmt.Sprintf("record of %d bytes exceeds the firehose record limit", flogging.FirehoseMaxRecordBytes+1))
+	assert.Equal(t, uint64(1), syncer.Dropped())
+}
+
+func TestFirehoseSyncerRetry(t *testing.T) {
+	client := &fakeFirehose{
+		responses: []firehoseResponse{
+			{err: errors.New("throttled")},
+			{failed: []int{0, 2}},
+		},
+	}
+	var waits []time.Duration
+	syncer := flogging.NewFirehoseSyncer(client, "logs")
+	syncer.SetSleep(func(d time.Duration) { waits = append(waits, d) })
+
+	for _, r := range []string{"one\n", "two\n", "three\n"} {
+		_, err := syncer.Write([]byte(r))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, syncer.Sync())
+
+	require.Len(t, client.batches, 3)
+	assert.Equal(t, []string{"one\n", "two\n", "three\n"}, batchStrings(client.batches[0]))
+	assert.Equal(t, []string{"one\n", "two\n", "three\n"}, batchStrings(client.batches[1]))
+	assert.Equal(t, []string{"one\n", "three\n"}, batchStrings(client.batches[2]), "only rejected records are resent")
+	assert.Equal(t, []time.Duration{100 * time.Millisecond, 200 * time.Millisecond}, waits)
+	assert.Zero(t, syncer.Dropped())
+}
+
+func TestFirehoseSyncerRetriesExhausted(t *testing.T) {
+	client := &fakeFirehose{
+		responses: []firehoseResponse{
+			{failed: []int{1}},
+			{failed: []int{0}},
+			{err: errors.New("throttled")},
+		},
+	}
+	var waits []time.Duration
+	syncer := flogging.NewFirehoseSyncer(client, "logs")
+	syncer.MaxRetries = 2
+	syncer.Backoff = time.Second
+	syncer.SetSleep(func(d time.Duration) { waits = append(waits, d) })
+
+	_, err := syncer.Write([]byte("one\n"))
+	assert.NoError(t, err)
+	_, err = syncer.Write([]byte("two\n"))
+	assert.NoError(t, err)
+	assert.EqualError(t, syncer.Sync(), "failed to deliver 1 records to firehose stream logs: throttled")
+	assert.Equal(t, uint64(1), syncer.Dropped())
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second}, waits)
+	assert.Len(t, client.batches, 3)
+
+	// the dropped records are not resent
+	assert.NoError(t, syncer.Sync())
+	assert.Len(t, client.batches, 3)
+}
+
+func TestFirehoseSyncerWriteFailure(t *testing.T) {
+	client := &fakeFirehose{
+		responses: []firehoseResponse{{failed: []int{0, 1}}},
+	}
+	syncer := flogging.NewFirehoseSyncer(client, "logs")
+	syncer.MaxRetries = 0
+
+	record := bytes.Repeat([]byte("x"), flogging.FirehoseMaxRecordBytes)
+	for i := 0; i < 5; i++ {
+		_, err := syncer.Write(record)
+		assert.NoError(t, err, "delivery failures are reported by Sync")
+	}
+
+	assert.EqualError(t, syncer.Sync(), "failed to deliver 2 records to firehose stream logs: 2 records were rejected")
+	assert.Equal(t, uint64(2), syncer.Dropped())
+	assert.NoError(t, syncer.Sync())
+	assert.Len(t, client.batches, 2)
+}
+
+func TestFirehoseSyncerBackground(t *testing.T) {
+	client := &fakeFirehose{
+		called:  make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	syncer := flogging.NewFirehoseSyncer(client, "logs")
+
+	for i := 0; i < flogging.FirehoseMaxBatchRecords+1; i++ {
+		_, err := syncer.Write([]byte(fmt.Sprintf("record-%d\n", i)))
+		assert.NoError(t, err)
+	}
+	<-client.called
+
+	// writes are not blocked while the full batch is delivered
+	written := make(chan struct{})
+	go func() {
+		_, err := syncer.Write([]byte("written\n"))
+		assert.NoError(t, err)
+		close(written)
+	}()
+	select {
+	case <-written:
+	case <-time.After(5 * time.Second):
+		t.Fatal("write blocked by delivery")
+	}
+
+	synced := make(chan error)
+	go func() { synced <- syncer.Sync() }()
+	close(client.release)
+	<-client.called
+	assert.NoError(t, <-synced)
+
+	require.Len(t, client.batches, 2)
+	assert.Len(t, client.batches[0], flogging.FirehoseMaxBatchRecords)
+	assert.Equal(t, []string{"record-500\n", "written\n"}, batchStrings(client.batches[1]))
+}
+
+func TestFirehoseSyncerInvalidIndexes(t *testing.T) {
+	client := &fakeFirehose{
+		responses: []firehoseResponse{{failed: []int{-1, 1, 2}}},
+	}
+	syncer := flogging.NewFirehoseSyncer(client, "logs")
+	syncer.SetSleep(func(time.Duration) {})
+
+	_, err := syncer.Write([]byte("one\n"))
+	assert.NoError(t, err)
+	_, err = syncer.Write([]byte("two\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, syncer.Sync())
+
+	require.Len(t, client.batches, 2)
+	assert.Equal(t, []string{"two\n"}, batchStrings(client.batches[1]), "indexes outside the batch are ignored")
+}
@@ -0,0 +1,52 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package floggingtest
+
+import (
+	"io"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/pkg/errors"
+	"go.uber.org/zap/zapcore"
+)
+
+// CoreFormat is the format used by cores created with NewTestCore for the
+// CONSOLE encoding. It excludes the caller and sequence number so that the
+// output does not depend on where or in which order entries are written.
+const CoreFormat = "[%{module}] %{level:.4s} %{message}"
+
+var encodingFormats = map[flogging.Encoding]string{
+	flogging.CONSOLE:            CoreFormat,
+	flogging.JSON:               "json",
+	flogging.LOGFMT:             "logfmt",
+	flogging.CBOR:               "cbor",
+	flogging.DETERMINISTIC_JSON: "deterministic-json",
+	flogging.ECS:                "ecs",
+	flogging.MSGPACK:            "msgpack",
+}
+
+// NewTestCore creates a core that writes entries enabled by the logging spec
+// to buf using the requested encoding. The CONSOLE encoding uses CoreFormat.
+func NewTestCore(spec string, encoding flogging.Encoding, buf io.Writer) (zapcore.Core, error) {
+	format, ok := encodingFormats[encoding]
+	if !ok {
+		return nil, errors.Errorf("unknown encoding %d", encoding)
+	}
+	if spec == "" {
+		spec = "debug"
+	}
+
+	logging, err := flogging.New(flogging.Config{
+		Format:  format,
+		LogSpec: spec,
+		Writer:  buf,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return logging.ZapLogger("floggingtest").Core(), nil
+}
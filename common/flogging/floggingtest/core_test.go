@@ -0,0 +1,68 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package floggingtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+)
+
+func TestNewTestCoreLevels(t *testing.T) {
+	gt := NewGomegaWithT(t)
+
+	buf := &bytes.Buffer{}
+	core, err := NewTestCore("warn:peer.gossip=debug", flogging.CONSOLE, buf)
+	gt.Expect(err).NotTo(HaveOccurred())
+
+	logger := zap.New(core)
+	logger.Named("peer.gossip").Debug("gossip debug")
+	logger.Named("peer.gossip").Info("gossip info")
+	logger.Named("peer").Info("peer info")
+	logger.Named("peer").Warn("peer warn")
+
+	gt.Expect(buf.String()).To(Equal("" +
+		"[peer.gossip] DEBU gossip debug\n" +
+		"[peer.gossip] INFO gossip info\n" +
+		"[peer] WARN peer warn\n",
+	))
+}
+
+func TestNewTestCoreEncoding(t *testing.T) {
+	gt := NewGomegaWithT(t)
+
+	buf := &bytes.Buffer{}
+	core, err := NewTestCore("info", flogging.JSON, buf)
+	gt.Expect(err).NotTo(HaveOccurred())
+	zap.New(core).Named("component").Info("message", zap.String("key", "value"))
+
+	var entry map[string]interface{}
+	gt.Expect(json.Unmarshal(buf.Bytes(), &entry)).To(Succeed())
+	gt.Expect(entry).To(HaveKeyWithValue("msg", "message"))
+	gt.Expect(entry).To(HaveKeyWithValue("name", "component"))
+	gt.Expect(entry).To(HaveKeyWithValue("key", "value"))
+
+	buf.Reset()
+	core, err = NewTestCore("info", flogging.LOGFMT, buf)
+	gt.Expect(err).NotTo(HaveOccurred())
+	zap.New(core).Info("message", zap.String("key", "value"))
+	gt.Expect(buf.String()).To(ContainSubstring("msg=message key=value"))
+}
+
+func TestNewTestCoreErrors(t *testing.T) {
+	gt := NewGomegaWithT(t)
+
+	_, err := NewTestCore("info", flogging.Encoding(-1), &bytes.Buffer{})
+	gt.Expect(err).To(MatchError("unknown encoding -1"))
+
+	_, err = NewTestCore("=info", flogging.CONSOLE, &bytes.Buffer{})
+	gt.Expect(err).To(MatchError(`invalid logging specification '=info': no logger specified in segment '=info'`))
+}
@@ -0,0 +1,135 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging/fabenc"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// prettyFormat is the CONSOLE format used when rendering captured log lines.
+// It matches the default format without colorization, the sequence number,
+// and the calling function; none of those can be recovered from a captured
+// line.
+const prettyFormat = "%{time:2006-01-02 15:04:05.000 MST} [%{module}] -> %{level:.4s} %{message}"
+
+// FormatLine renders a log line that was captured in the provided encoding in
+// the human readable CONSOLE form. It is intended for use by log viewing
+// utilities.
+//
+// Only lines captured with the JSON encoding can be decoded. Fields that are
+// not part of the entry itself are appended in key order.
+func FormatLine(encoding Encoding, line []byte) (string, error) {
+	if encoding != JSON {
+		return "", errors.Errorf("unsupported encoding: %d", encoding)
+	}
+
+	entry, fields, err := decodeJSONLine(line)
+	if err != nil {
+		return "", err
+	}
+
+	formatters, err := fabenc.ParseFormat(prettyFormat)
+	if err != nil {
+		return "", err
+	}
+	buf, err := fabenc.NewFormatEncoder(formatters...).EncodeEntry(entry, fields)
+	if err != nil {
+		return "", err
+	}
+	defer buf.Free()
+
+	return buf.String(), nil
+}
+
+// decodeJSONLine decodes a line written by the JSON encoder into an entry
+// and the fields associated with it.
+func decodeJSONLine(line []byte) (zapcore.Entry, []zapcore.Field, error) {
+	decoder := json.NewDecoder(bytes.NewReader(line))
+	decoder.UseNumber()
+
+	var record map[string]interface{}
+	if err := decoder.Decode(&record); err != nil {
+		return zapcore.Entry{}, nil, errors.Wrap(err, "failed to decode log line")
+	}
+
+	var entry zapcore.Entry
+	cfg := newEncoderConfig()
+	for key, value := range record {
+		var err error
+		switch key {
+		case cfg.TimeKey:
+			entry.Time, err = decodeTime(value)
+		case cfg.LevelKey:
+			entry.Level, err = decodeLevel(value)
+		case cfg.NameKey:
+			entry.LoggerName = fmt.Sprint(value)
+		case cfg.MessageKey:
+			entry.Message = fmt.Sprint(value)
+		case cfg.StacktraceKey:
+			entry.Stack = fmt.Sprint(value)
+		default:
+			continue
+		}
+		if err != nil {
+			return zapcore.Entry{}, nil, errors.WithMessagef(err, "invalid value for %s", key)
+		}
+		delete(record, key)
+	}
+	// the caller cannot be restored to a program counter
+	delete(record, cfg.CallerKey)
+
+	keys := make([]string, 0, len(record))
+	for key := range record {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fields := make([]zapcore.Field, 0, len(keys))
+	for _, key := range keys {
+		fields = append(fields, zap.Any(key, record[key]))
+	}
+
+	return entry, fields, nil
+}
+
+func decodeTime(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return time.Time{}, err
+		}
+		sec, frac := math.Modf(f)
+		return time.Unix(int64(sec), int64(frac*1e9)), nil
+	case string:
+		return time.Parse(time.RFC3339Nano, v)
+	default:
+		return time.Time{}, errors.Errorf("unexpected type %T", value)
+	}
+}
+
+func decodeLevel(value interface{}) (zapcore.Level, error) {
+	s, ok := value.(string)
+	if !ok {
+		return DisabledLevel, errors.Errorf("unexpected type %T", value)
+	}
+	var level zapcore.Level
+	if err := level.UnmarshalText([]byte(s)); err == nil {
+		return level, nil
+	}
+	return nameToLevel(s)
+}
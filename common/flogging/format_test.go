@@ -0,0 +1,57 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatLine(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logging, err := flogging.New(flogging.Config{
+		Format:  "json",
+		LogSpec: "debug",
+		Writer:  buf,
+	})
+	assert.NoError(t, err)
+
+	logger := logging.Logger("test.logger")
+	logger.Warnw("this is a message", "zkey", "zvalue", "count", 3)
+
+	formatted, err := flogging.FormatLine(flogging.JSON, buf.Bytes())
+	assert.NoError(t, err)
+	assert.Regexp(t, `^\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}\.\d{3} \S+ \[test\.logger\] -> WARN this is a message count=3 zkey=zvalue\n$`, formatted)
+}
+
+func TestFormatLineTime(t *testing.T) {
+	ts := time.Date(2020, 6, 1, 12, 30, 45, 250000000, time.Local)
+	line := fmt.Sprintf(`{"level":"info","ts":%d.25,"name":"name","msg":"message"}`, ts.Unix())
+
+	formatted, err := flogging.FormatLine(flogging.JSON, []byte(line))
+	assert.NoError(t, err)
+	assert.Equal(t, ts.Format("2006-01-02 15:04:05.000 MST")+" [name] -> INFO message\n", formatted)
+}
+
+func TestFormatLineErrors(t *testing.T) {
+	_, err := flogging.FormatLine(flogging.CONSOLE, []byte("line"))
+	assert.EqualError(t, err, "unsupported encoding: 0")
+
+	_, err = flogging.FormatLine(flogging.JSON, []byte("{"))
+	assert.EqualError(t, err, "failed to decode log line: unexpected EOF")
+
+	_, err = flogging.FormatLine(flogging.JSON, []byte(`{"level":"bogus"}`))
+	assert.EqualError(t, err, "invalid value for level: invalid log level: bogus")
+
+	_, err = flogging.FormatLine(flogging.JSON, []byte(`{"ts":true}`))
+	assert.EqualError(t, err, "invalid value for ts: unexpected type bool")
+}
@@ -8,6 +8,7 @@ package flogging
 
 import (
 	"io"
+	"os"
 
 	"go.uber.org/zap/zapcore"
 	"google.golang.org/grpc/grpclog"
@@ -31,6 +32,34 @@ func init() {
 	grpclog.SetLogger(NewGRPCLogger(grpcLogger))
 }
 
+// BootstrapCore returns the core that defines how entries are written before
+// logging has been configured. It writes entries as uncolored JSON to the
+// current os.Stderr with INFO enabled for all loggers. Components that must
+// log before a configured core is available, such as during flag parsing,
+// should use it rather than an ad-hoc logger so early entries are encoded
+// consistently. It is also the default emergency core of a DeferredCore,
+// which receives the buffered entries when the process panics or exits
+// before the configured core is installed.
+//
+// The returned core is independent of Global; it is not affected by Init or
+// ActivateSpec.
+func BootstrapCore() *Core {
+	levels := &LoggerLevels{defaultLevel: defaultLevel}
+	if err := levels.ActivateSpec(defaultLevel.String()); err != nil {
+		panic(err)
+	}
+
+	return &Core{
+		LevelEnabler: levels,
+		Levels:       levels,
+		Encoders: map[Encoding]zapcore.Encoder{
+			JSON: zapcore.NewJSONEncoder(newEncoderConfig()),
+		},
+		Selector: fixedEncoding(JSON),
+		Output:   zapcore.Lock(os.Stderr),
+	}
+}
+
 // Init initializes logging with the provided config.
 func Init(config Config) {
 	err := Global.Apply(config)
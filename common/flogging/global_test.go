@@ -8,11 +8,17 @@ package flogging_test
 
 import (
 	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
 	"testing"
 
 	"github.com/hyperledger/fabric/common/flogging"
 	"github.com/hyperledger/fabric/common/flogging/mock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 )
 
 func TestGlobalReset(t *testing.T) {
@@ -160,3 +166,42 @@ func TestGlobalSetWriter(t *testing.T) {
 
 	assert.Exactly(t, old, original)
 }
+
+func TestBootstrapCore(t *testing.T) {
+	flogging.Reset()
+	defer flogging.Reset()
+
+	stderr, err := ioutil.TempFile("", "stderr")
+	require.NoError(t, err)
+	defer os.Remove(stderr.Name())
+	defer stderr.Close()
+	defer func(f *os.File) { os.Stderr = f }(os.Stderr)
+	os.Stderr = stderr
+
+	core := flogging.BootstrapCore()
+	flogging.Init(flogging.Config{Format: "json", LogSpec: "debug", Writer: &bytes.Buffer{}})
+
+	logger := zap.New(core).Named("early")
+	logger.Debug("debug message")
+	logger.Info("starting", zap.String("phase", "boot"))
+	logger.Warn("warning")
+
+	output, err := ioutil.ReadFile(stderr.Name())
+	require.NoError(t, err)
+	assert.NotContains(t, string(output), "\x1b[", "bootstrap entries must not be colorized")
+	lines := strings.Split(strings.TrimSuffix(string(output), "\n"), "\n")
+	require.Len(t, lines, 2, "unexpected output: %q", output)
+
+	var entries []map[string]interface{}
+	for _, line := range lines {
+		entry := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry), "entries must be JSON: %s", line)
+		assert.NotNil(t, entry["ts"])
+		delete(entry, "ts")
+		entries = append(entries, entry)
+	}
+	assert.Equal(t, []map[string]interface{}{
+		{"level": "info", "name": "early", "msg": "starting", "phase": "boot"},
+		{"level": "warn", "name": "early", "msg": "warning"},
+	}, entries)
+}
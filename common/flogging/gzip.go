@@ -0,0 +1,240 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"compress/gzip"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap/zapcore"
+)
+
+// A GzipStreamSyncer is a zapcore.WriteSyncer that compresses log records into
+// a single gzip stream written to an underlying writer. When used with the
+// JSON encoder, the output is a continuously compressed .json.gz archive.
+//
+// Sync flushes the pending compressed data to the writer so that everything
+// written before the Sync can be decompressed, even if the process exits
+// before Close is called. Close terminates the gzip stream and closes the
+// underlying writer when it implements io.Closer.
+type GzipStreamSyncer struct {
+	mutex  sync.Mutex
+	writer io.Writer
+	gzip   *gzip.Writer
+	closed bool
+}
+
+// NewGzipStreamSyncer creates a GzipStreamSyncer that writes a gzip stream to
+// w using the default compression level.
+func NewGzipStreamSyncer(w io.Writer) *GzipStreamSyncer {
+	return &GzipStreamSyncer{
+		writer: w,
+		gzip:   gzip.NewWriter(w),
+	}
+}
+
+// Write compresses b into the gzip stream.
+func (g *GzipStreamSyncer) Write(b []byte) (int, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.closed {
+		return 0, errors.New("gzip stream is closed")
+	}
+	return g.gzip.Write(b)
+}
+
+// Sync flushes the compressed data to the underlying writer and syncs the
+// writer when it is a zapcore.WriteSyncer.
+func (g *GzipStreamSyncer) Sync() error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.closed {
+		return nil
+	}
+	if err := g.gzip.Flush(); err != nil {
+		return errors.Wrap(err, "failed to flush gzip stream")
+	}
+	if ws, ok := g.writer.(zapcore.WriteSyncer); ok {
+		return ws.Sync()
+	}
+	return nil
+}
+
+// Close writes the gzip trailer and closes the underlying writer when it
+// implements io.Closer. Writes after Close fail.
+func (g *GzipStreamSyncer) Close() error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.closed {
+		return nil
+	}
+	g.closed = true
+	if err := g.gzip.Close(); err != nil {
+		return errors.Wrap(err, "failed to close gzip stream")
+	}
+	if c, ok := g.writer.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// An IdleGzipSyncer is a zapcore.WriteSyncer that produces the same gzip
+// stream as a GzipStreamSyncer without compressing on the write path. Writes
+// are appended to an uncompressed buffer that is compressed and flushed to the
+// underlying writer in the background once no writes have been made for the
+// idle delay, or as soon as the buffer reaches its size limit.
+//
+// Sync compresses and flushes the buffered records before returning so that
+// everything written before the Sync can be decompressed, even if the process
+// exits before Close is called. Records that have not been synced or
+// compressed in the background are lost when the process exits without
+// calling Sync or Close. Close compresses the buffered records, terminates the
+// gzip stream, and closes the underlying writer when it implements io.Closer.
+type IdleGzipSyncer struct {
+	// compressMutex serializes compression so buffered records reach the
+	// stream in the order they were written. It is acquired before mutex.
+	compressMutex sync.Mutex
+	writer        io.Writer
+	gzip          *gzip.Writer
+	err           error // first background compression error
+
+	mutex      sync.Mutex
+	pending    []byte
+	idle       time.Duration
+	maxPending int
+	timer      *time.Timer
+	closed     bool
+}
+
+// NewIdleGzipSyncer creates an IdleGzipSyncer that writes a gzip stream to w.
+// Buffered records are compressed once no writes have been made for idle or
+// when more than maxPending bytes are buffered.
+func NewIdleGzipSyncer(w io.Writer, idle time.Duration, maxPending int) *IdleGzipSyncer {
+	return &IdleGzipSyncer{
+		writer:     w,
+		gzip:       gzip.NewWriter(w),
+		idle:       idle,
+		maxPending: maxPending,
+	}
+}
+
+// Write buffers b for compression.
+func (g *IdleGzipSyncer) Write(b []byte) (int, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.closed {
+		return 0, errors.New("gzip stream is closed")
+	}
+	g.pending = append(g.pending, b...)
+
+	delay := g.idle
+	if len(g.pending) >= g.maxPending {
+		delay = 0
+	}
+	if g.timer == nil {
+		g.timer = time.AfterFunc(delay, g.compressIdle)
+	} else {
+		g.timer.Reset(delay)
+	}
+	return len(b), nil
+}
+
+// Sync compresses the buffered records, flushes the compressed data to the
+// underlying writer, and syncs the writer when it is a zapcore.WriteSyncer. An
+// error from a background compression is returned by the next Sync.
+func (g *IdleGzipSyncer) Sync() error {
+	g.compressMutex.Lock()
+	defer g.compressMutex.Unlock()
+
+	if err := g.compress(); err != nil {
+		return err
+	}
+	if err := g.backgroundErr(); err != nil {
+		return err
+	}
+	if ws, ok := g.writer.(zapcore.WriteSyncer); ok {
+		return ws.Sync()
+	}
+	return nil
+}
+
+// Close compresses the buffered records, writes the gzip trailer, and closes
+// the underlying writer when it implements io.Closer. Writes after Close fail.
+func (g *IdleGzipSyncer) Close() error {
+	g.compressMutex.Lock()
+	defer g.compressMutex.Unlock()
+
+	g.mutex.Lock()
+	if g.closed {
+		g.mutex.Unlock()
+		return nil
+	}
+	g.closed = true
+	if g.timer != nil {
+		g.timer.Stop()
+	}
+	g.mutex.Unlock()
+
+	if err := g.compress(); err != nil {
+		return err
+	}
+	if err := g.backgroundErr(); err != nil {
+		return err
+	}
+	if err := g.gzip.Close(); err != nil {
+		return errors.Wrap(err, "failed to close gzip stream")
+	}
+	if c, ok := g.writer.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// compressIdle compresses the buffered records in the background.
+func (g *IdleGzipSyncer) compressIdle() {
+	g.compressMutex.Lock()
+	defer g.compressMutex.Unlock()
+
+	if err := g.compress(); err != nil && g.err == nil {
+		g.err = err
+	}
+}
+
+// compress compresses and flushes the buffered records. It must be called
+// with the compressMutex held.
+func (g *IdleGzipSyncer) compress() error {
+	g.mutex.Lock()
+	pending := g.pending
+	g.pending = nil
+	g.mutex.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+	if _, err := g.gzip.Write(pending); err != nil {
+		return errors.Wrap(err, "failed to compress log records")
+	}
+	if err := g.gzip.Flush(); err != nil {
+		return errors.Wrap(err, "failed to flush gzip stream")
+	}
+	return nil
+}
+
+// backgroundErr returns and clears the background compression error. It must
+// be called with the compressMutex held.
+func (g *IdleGzipSyncer) backgroundErr() error {
+	err := g.err
+	g.err = nil
+	return err
+}
@@ -0,0 +1,224 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging_test
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type closeBuffer struct {
+	sw
+	closed bool
+}
+
+func (c *closeBuffer) Close() error {
+	c.closed = true
+	return nil
+}
+
+// gunzipLines decompresses the lines that are readable from a possibly
+// unterminated gzip stream.
+func gunzipLines(t *testing.T, compressed []byte) ([]string, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	require.NoError(t, err)
+
+	var lines []string
+	scanner := bufio.NewScanner(zr)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
+
+func newGzipJSONLogger(t *testing.T, syncer zapcore.WriteSyncer) *zap.Logger {
+	levels := &flogging.LoggerLevels{}
+	err := levels.ActivateSpec("debug")
+	require.NoError(t, err)
+
+	return zap.New(&flogging.Core{
+		LevelEnabler: levels,
+		Levels:       levels,
+		Encoders: map[flogging.Encoding]zapcore.Encoder{
+			flogging.JSON: zapcore.NewJSONEncoder(zapcore.EncoderConfig{MessageKey: "msg"}),
+		},
+		Selector: fixedSelector(flogging.JSON),
+		Output:   syncer,
+	})
+}
+
+func TestGzipStreamSyncer(t *testing.T) {
+	output := &closeBuffer{}
+	syncer := flogging.NewGzipStreamSyncer(output)
+	logger := newGzipJSONLogger(t, syncer)
+
+	logger.Info("first", zap.Int("n", 1))
+	logger.Info("second", zap.Int("n", 2))
+	err := logger.Sync()
+	require.NoError(t, err)
+	assert.True(t, output.syncCalled)
+
+	// the flushed portion is readable before the stream is terminated
+	lines, err := gunzipLines(t, output.Bytes())
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+	assert.Equal(t, []string{`{"msg":"first","n":1}`, `{"msg":"second","n":2}`}, lines)
+
+	logger.Info("third", zap.Int("n", 3))
+	err = syncer.Close()
+	require.NoError(t, err)
+	assert.True(t, output.closed)
+
+	lines, err = gunzipLines(t, output.Bytes())
+	assert.NoError(t, err)
+	require.Len(t, lines, 3)
+	for i, line := range lines {
+		var entry struct {
+			Msg string `json:"msg"`
+			N   int    `json:"n"`
+		}
+		err := json.Unmarshal([]byte(line), &entry)
+		assert.NoError(t, err)
+		assert.Equal(t, i+1, entry.N)
+	}
+
+	_, err = syncer.Write([]byte("late"))
+	assert.EqualError(t, err, "gzip stream is closed")
+	assert.NoError(t, syncer.Sync())
+	assert.NoError(t, syncer.Close())
+}
+
+func TestGzipStreamSyncerErrors(t *testing.T) {
+	output := &sw{}
+	syncer := flogging.NewGzipStreamSyncer(output)
+	_, err := syncer.Write([]byte("entry\n"))
+	require.NoError(t, err)
+
+	output.writeErr = errors.New("disk full")
+	err = syncer.Sync()
+	assert.EqualError(t, err, "failed to flush gzip stream: disk full")
+
+	err = syncer.Close()
+	assert.EqualError(t, err, "failed to close gzip stream: disk full")
+}
+
+// compressed returns a copy of the bytes written to the buffer.
+func (c *closeBuffer) compressed() []byte {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return append([]byte(nil), c.Bytes()...)
+}
+
+func TestIdleGzipSyncer(t *testing.T) {
+	output := &closeBuffer{}
+	syncer := flogging.NewIdleGzipSyncer(output, time.Hour, 1<<20)
+	logger := newGzipJSONLogger(t, syncer)
+
+	logger.Info("first", zap.Int("n", 1))
+	logger.Info("second", zap.Int("n", 2))
+	assert.Empty(t, output.compressed(), "records must not be compressed on the write path")
+
+	err := logger.Sync()
+	require.NoError(t, err)
+	assert.True(t, output.syncCalled)
+	lines, err := gunzipLines(t, output.compressed())
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+	assert.Equal(t, []string{`{"msg":"first","n":1}`, `{"msg":"second","n":2}`}, lines)
+
+	logger.Info("third", zap.Int("n", 3))
+	err = syncer.Close()
+	require.NoError(t, err)
+	assert.True(t, output.closed)
+	lines, err = gunzipLines(t, output.compressed())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`{"msg":"first","n":1}`, `{"msg":"second","n":2}`, `{"msg":"third","n":3}`}, lines)
+
+	_, err = syncer.Write([]byte("late"))
+	assert.EqualError(t, err, "gzip stream is closed")
+	assert.NoError(t, syncer.Close())
+	assert.NoError(t, syncer.Sync())
+}
+
+func TestIdleGzipSyncerCompressesWhenIdle(t *testing.T) {
+	output := &closeBuffer{}
+	syncer := flogging.NewIdleGzipSyncer(output, 10*time.Millisecond, 1<<20)
+	logger := newGzipJSONLogger(t, syncer)
+
+	logger.Info("idle", zap.Int("n", 1))
+	assert.Eventually(t, func() bool { return len(output.compressed()) > 0 }, time.Second, 5*time.Millisecond)
+
+	// abandoning the stream without Close leaves the compressed records readable
+	lines, err := gunzipLines(t, output.compressed())
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+	assert.Equal(t, []string{`{"msg":"idle","n":1}`}, lines)
+	assert.False(t, output.syncCalled)
+}
+
+func TestIdleGzipSyncerMaxPending(t *testing.T) {
+	output := &closeBuffer{}
+	syncer := flogging.NewIdleGzipSyncer(output, time.Hour, 64)
+	logger := newGzipJSONLogger(t, syncer)
+
+	for i := 0; i < 10; i++ {
+		logger.Info("busy", zap.Int("n", i))
+	}
+	assert.Eventually(t, func() bool {
+		lines, _ := gunzipLines(t, output.compressed())
+		return len(lines) == 10
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestIdleGzipSyncerConcurrentShutdown(t *testing.T) {
+	output := &closeBuffer{}
+	syncer := flogging.NewIdleGzipSyncer(output, time.Microsecond, 128)
+	logger := newGzipJSONLogger(t, syncer)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				logger.Info("concurrent", zap.Int("g", g), zap.Int("n", i))
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	// close while background compression may still be running
+	require.NoError(t, syncer.Close())
+	lines, err := gunzipLines(t, output.compressed())
+	assert.NoError(t, err)
+	assert.Len(t, lines, 400)
+	for _, line := range lines {
+		var entry map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(line), &entry), "corrupt line %q", line)
+	}
+}
+
+func TestIdleGzipSyncerWriteError(t *testing.T) {
+	output := &closeBuffer{}
+	output.writeErr = errors.New("disk full")
+	syncer := flogging.NewIdleGzipSyncer(output, time.Hour, 1<<20)
+
+	_, err := syncer.Write([]byte("record\n"))
+	assert.NoError(t, err)
+	err = syncer.Sync()
+	assert.EqualError(t, err, "failed to compress log records: disk full")
+}
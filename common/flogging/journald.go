@@ -0,0 +1,297 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap/zapcore"
+)
+
+// JournalSocket is the path of the systemd journal's native protocol socket.
+const JournalSocket = "/run/systemd/journal/socket"
+
+// A JournaldCore is a zapcore.Core that sends entries to the systemd journal
+// using the native protocol. The message, priority, identifier, logger name,
+// and caller are sent as the standard journal fields and structured fields are
+// sent as journal fields with upper case names. Nested objects are flattened
+// by joining the keys with underscores. Structured fields whose names collide
+// with the fields set by the core or reserved by the journal, such as MESSAGE
+// or CODE_FILE, are prefixed with F_.
+//
+// Entries that are too large to be sent as a single datagram are written to
+// a sealed memory file that is passed to the journal. This is only supported
+// on Linux; elsewhere such entries cannot be sent.
+//
+// The journal protocol requires access to the entry and its fields so, unlike
+// the SyslogSyncer, the JournaldCore is a core rather than a WriteSyncer.
+type JournaldCore struct {
+	zapcore.LevelEnabler
+	// Levels, when set, is consulted for the level of the entry's logger.
+	Levels *LoggerLevels
+	// Identifier is sent as the SYSLOG_IDENTIFIER of every entry.
+	Identifier string
+
+	conn   *journalConn
+	fields []zapcore.Field
+}
+
+type journalConn struct {
+	mutex sync.Mutex
+	path  string
+	conn  net.Conn
+}
+
+// NewJournaldCore creates a JournaldCore that sends enabled entries to the
+// journal socket at path. An error is returned when the journal cannot be
+// reached so callers can fall back to another sink.
+func NewJournaldCore(enabler zapcore.LevelEnabler, path, identifier string) (*JournaldCore, error) {
+	jc := &journalConn{path: path}
+	if err := jc.connect(); err != nil {
+		return nil, err
+	}
+	return &JournaldCore{
+		LevelEnabler: enabler,
+		Identifier:   identifier,
+		conn:         jc,
+	}, nil
+}
+
+func (j *JournaldCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *j
+	clone.fields = append(j.fields[:len(j.fields):len(j.fields)], fields...)
+	return &clone
+}
+
+func (j *JournaldCore) Check(e zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !j.Enabled(e.Level) {
+		return ce
+	}
+	if j.Levels != nil && !j.Levels.Level(e.LoggerName).Enabled(e.Level) {
+		return ce
+	}
+	return ce.AddCore(e, j)
+}
+
+func (j *JournaldCore) Write(e zapcore.Entry, fields []zapcore.Field) error {
+	enc := zapcore.NewMapObjectEncoder()
+	addFields(enc, j.fields)
+	addFields(enc, fields)
+
+	var buf bytes.Buffer
+	appendJournalField(&buf, "MESSAGE", e.Message)
+	appendJournalField(&buf, "PRIORITY", strconv.Itoa(journalPriority(e.Level)))
+	if j.Identifier != "" {
+		appendJournalField(&buf, "SYSLOG_IDENTIFIER", j.Identifier)
+	}
+	if e.LoggerName != "" {
+		appendJournalField(&buf, "LOGGER", e.LoggerName)
+	}
+	if e.Caller.Defined {
+		appendJournalField(&buf, "CODE_FILE", e.Caller.File)
+		appendJournalField(&buf, "CODE_LINE", strconv.Itoa(e.Caller.Line))
+	}
+	if e.Stack != "" {
+		appendJournalField(&buf, "STACKTRACE", e.Stack)
+	}
+	appendJournalFields(&buf, "", enc.Fields)
+
+	return j.conn.send(buf.Bytes())
+}
+
+// Sync is a no-op as entries are not buffered.
+func (j *JournaldCore) Sync() error {
+	return nil
+}
+
+// Close closes the connection to the journal.
+func (j *JournaldCore) Close() error {
+	return j.conn.close()
+}
+
+func (c *journalConn) connect() error {
+	conn, err := net.Dial("unixgram", c.path)
+	if err != nil {
+		return errors.Wrap(err, "journal is not available")
+	}
+	c.conn = conn
+	return nil
+}
+
+// send writes a datagram to the journal, reconnecting once if the write
+// fails.
+func (c *journalConn) send(b []byte) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.conn != nil {
+		_, err := c.conn.Write(b)
+		if err == nil {
+			return nil
+		}
+		if isMessageTooLarge(err) {
+			return c.sendLarge(b)
+		}
+		c.conn.Close()
+		c.conn = nil
+	}
+	if err := c.connect(); err != nil {
+		return err
+	}
+	_, err := c.conn.Write(b)
+	if isMessageTooLarge(err) {
+		return c.sendLarge(b)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to write to journal")
+	}
+	return nil
+}
+
+// sendLarge passes an entry that is too large for a datagram to the journal
+// as a file descriptor. It must be called with the mutex held.
+func (c *journalConn) sendLarge(b []byte) error {
+	conn, ok := c.conn.(*net.UnixConn)
+	if !ok {
+		return errors.New("journal connection cannot pass file descriptors")
+	}
+	return errors.Wrap(sendJournalFD(conn, b), "failed to write large entry to journal")
+}
+
+func isMessageTooLarge(err error) bool {
+	switch errno(err) {
+	case syscall.EMSGSIZE, syscall.ENOBUFS:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *journalConn) close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+// journalPriority maps a level to a syslog priority.
+func journalPriority(l zapcore.Level) int {
+	switch {
+	case l <= zapcore.DebugLevel:
+		return 7 // debug
+	case l == zapcore.InfoLevel:
+		return 6 // info
+	case l == zapcore.WarnLevel:
+		return 4 // warning
+	case l == zapcore.ErrorLevel:
+		return 3 // err
+	default:
+		return 2 // crit
+	}
+}
+
+// appendJournalFields appends the fields sorted by name, flattening nested
+// objects.
+func appendJournalFields(buf *bytes.Buffer, prefix string, fields map[string]interface{}) {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		name := journalFieldName(prefix + k)
+		if _, ok := fields[k].(map[string]interface{}); !ok {
+			name = unreservedJournalFieldName(name)
+		}
+		switch v := fields[k].(type) {
+		case map[string]interface{}:
+			appendJournalFields(buf, name+"_", v)
+		case string:
+			appendJournalField(buf, name, v)
+		case []interface{}:
+			encoded, err := json.Marshal(v)
+			if err != nil {
+				encoded = []byte(fmt.Sprint(v))
+			}
+			appendJournalField(buf, name, string(encoded))
+		default:
+			appendJournalField(buf, name, fmt.Sprint(v))
+		}
+	}
+}
+
+// journalFieldName converts a key to a valid journal field name. Names may
+// only contain upper case letters, digits, and underscores, must not start
+// with an underscore or digit, and are limited to 64 characters.
+func journalFieldName(key string) string {
+	name := []byte(strings.ToUpper(key))
+	for i, c := range name {
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			name[i] = '_'
+		}
+	}
+	s := strings.TrimLeft(string(name), "_")
+	if s == "" || (s[0] >= '0' && s[0] <= '9') {
+		s = "F_" + s
+	}
+	if len(s) > 64 {
+		s = s[:64]
+	}
+	return s
+}
+
+// unreservedJournalFieldName prefixes the name of a structured field with F_
+// when it collides with a field set by the core or reserved by the journal.
+func unreservedJournalFieldName(name string) string {
+	switch {
+	case name == "MESSAGE", name == "MESSAGE_ID", name == "PRIORITY", name == "LOGGER",
+		name == "STACKTRACE", name == "ERRNO", name == "INVOCATION_ID",
+		strings.HasPrefix(name, "SYSLOG_"), strings.HasPrefix(name, "CODE_"):
+	default:
+		return name
+	}
+	name = "F_" + name
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
+
+// appendJournalField appends a field using the native protocol. Values that
+// contain a newline are length prefixed.
+func appendJournalField(buf *bytes.Buffer, name, value string) {
+	buf.WriteString(name)
+	if !strings.Contains(value, "\n") {
+		buf.WriteByte('=')
+		buf.WriteString(value)
+		buf.WriteByte('\n')
+		return
+	}
+
+	var size [8]byte
+	binary.LittleEndian.PutUint64(size[:], uint64(len(value)))
+	buf.WriteByte('\n')
+	buf.Write(size[:])
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
@@ -0,0 +1,55 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging_test
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestJournaldCoreLargeEntry(t *testing.T) {
+	path, journal := newMockJournal(t)
+	core, err := flogging.NewJournaldCore(zapcore.DebugLevel, path, "peer")
+	require.NoError(t, err)
+	defer core.Close()
+
+	message := strings.Repeat("x", 4<<20)
+	zap.New(core).Info(message)
+
+	require.NoError(t, journal.SetReadDeadline(time.Now().Add(5*time.Second)))
+	b, oob := make([]byte, 16), make([]byte, syscall.CmsgSpace(4))
+	n, oobn, _, _, err := journal.ReadMsgUnix(b, oob)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n, "the datagram must only carry the descriptor")
+
+	msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	fds, err := syscall.ParseUnixRights(&msgs[0])
+	require.NoError(t, err)
+	require.Len(t, fds, 1)
+
+	f := os.NewFile(uintptr(fds[0]), "journal-entry")
+	defer f.Close()
+	_, err = f.Seek(0, 0)
+	require.NoError(t, err)
+	entry, err := ioutil.ReadAll(f)
+	require.NoError(t, err)
+	fields := parseJournalEntry(t, entry)
+	assert.Equal(t, message, fields["MESSAGE"])
+	assert.Equal(t, "peer", fields["SYSLOG_IDENTIFIER"])
+}
@@ -0,0 +1,21 @@
+//go:build !linux
+// +build !linux
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"net"
+
+	"github.com/pkg/errors"
+)
+
+// sendJournalFD is only supported on Linux, where the journal runs.
+func sendJournalFD(conn *net.UnixConn, b []byte) error {
+	return errors.New("entry exceeds the maximum journal datagram size")
+}
@@ -0,0 +1,192 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// newMockJournal creates a datagram socket that stands in for the journal.
+func newMockJournal(t *testing.T) (string, *net.UnixConn) {
+	dir, err := ioutil.TempDir("", "journal")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "socket")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return path, conn
+}
+
+// readJournalEntry reads a datagram and decodes the native protocol fields.
+func readJournalEntry(t *testing.T, conn *net.UnixConn) map[string]string {
+	b := make([]byte, 65536)
+	n, err := conn.Read(b)
+	require.NoError(t, err)
+	return parseJournalEntry(t, b[:n])
+}
+
+// parseJournalEntry decodes the native protocol fields of an entry.
+func parseJournalEntry(t *testing.T, b []byte) map[string]string {
+	fields := map[string]string{}
+	for len(b) > 0 {
+		end := bytes.IndexAny(b, "=\n")
+		require.True(t, end > 0, "malformed entry: %q", b)
+		name := string(b[:end])
+		if b[end] == '=' {
+			b = b[end+1:]
+			nl := bytes.IndexByte(b, '\n')
+			require.True(t, nl >= 0)
+			fields[name], b = string(b[:nl]), b[nl+1:]
+			continue
+		}
+		size := binary.LittleEndian.Uint64(b[end+1 : end+9])
+		b = b[end+9:]
+		fields[name] = string(b[:size])
+		require.Equal(t, byte('\n'), b[size])
+		b = b[size+1:]
+	}
+	return fields
+}
+
+func TestJournaldCore(t *testing.T) {
+	path, journal := newMockJournal(t)
+	core, err := flogging.NewJournaldCore(zapcore.DebugLevel, path, "peer")
+	require.NoError(t, err)
+	defer core.Close()
+
+	logger := zap.New(core, zap.AddCaller()).Named("gossip").With(zap.String("channel", "testchannel"))
+	logger.Warn("multi\nline message",
+		zap.Int("block-number", 7),
+		zap.Strings("peers", []string{"p0", "p1"}),
+		zap.Object("_request", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+			enc.AddString("id", "r1")
+			return nil
+		})),
+		zap.Bool("9lives", true),
+	)
+
+	fields := readJournalEntry(t, journal)
+	assert.Regexp(t, `journald_test\.go$`, fields["CODE_FILE"])
+	assert.NotEmpty(t, fields["CODE_LINE"])
+	delete(fields, "CODE_FILE")
+	delete(fields, "CODE_LINE")
+	assert.Equal(t, map[string]string{
+		"MESSAGE":           "multi\nline message",
+		"PRIORITY":          "4",
+		"SYSLOG_IDENTIFIER": "peer",
+		"LOGGER":            "gossip",
+		"CHANNEL":           "testchannel",
+		"BLOCK_NUMBER":      "7",
+		"PEERS":             `["p0","p1"]`,
+		"REQUEST_ID":        "r1",
+		"F_9LIVES":          "true",
+	}, fields)
+}
+
+func TestJournaldCoreReservedFields(t *testing.T) {
+	path, journal := newMockJournal(t)
+	core, err := flogging.NewJournaldCore(zapcore.DebugLevel, path, "peer")
+	require.NoError(t, err)
+	defer core.Close()
+
+	zap.New(core).Info("real message",
+		zap.String("message", "user message"),
+		zap.String("priority", "high"),
+		zap.String("syslog_identifier", "other"),
+		zap.String("code_file", "user.go"),
+		zap.Object("message", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+			enc.AddString("id", "m1")
+			return nil
+		})),
+		zap.String("messages", "not reserved"),
+	)
+
+	assert.Equal(t, map[string]string{
+		"MESSAGE":             "real message",
+		"PRIORITY":            "6",
+		"SYSLOG_IDENTIFIER":   "peer",
+		"F_MESSAGE_ID":        "m1",
+		"F_PRIORITY":          "high",
+		"F_SYSLOG_IDENTIFIER": "other",
+		"F_CODE_FILE":         "user.go",
+		"MESSAGES":            "not reserved",
+	}, readJournalEntry(t, journal))
+}
+
+func TestJournaldCorePriority(t *testing.T) {
+	path, journal := newMockJournal(t)
+	core, err := flogging.NewJournaldCore(zap.NewAtomicLevelAt(flogging.PayloadLevel), path, "")
+	require.NoError(t, err)
+
+	var tests = []struct {
+		level    zapcore.Level
+		priority string
+	}{
+		{flogging.PayloadLevel, "7"},
+		{zapcore.DebugLevel, "7"},
+		{zapcore.InfoLevel, "6"},
+		{zapcore.WarnLevel, "4"},
+		{zapcore.ErrorLevel, "3"},
+		{zapcore.DPanicLevel, "2"},
+		{zapcore.PanicLevel, "2"},
+		{zapcore.FatalLevel, "2"},
+	}
+	for _, tc := range tests {
+		err := core.Write(zapcore.Entry{Level: tc.level, Message: "message"}, nil)
+		require.NoError(t, err)
+		fields := readJournalEntry(t, journal)
+		assert.Equal(t, tc.priority, fields["PRIORITY"], "level %s", tc.level)
+		assert.NotContains(t, fields, "SYSLOG_IDENTIFIER")
+	}
+}
+
+func TestJournaldCoreLevels(t *testing.T) {
+	path, _ := newMockJournal(t)
+	core, err := flogging.NewJournaldCore(zapcore.DebugLevel, path, "peer")
+	require.NoError(t, err)
+	core.Levels = &flogging.LoggerLevels{}
+	err = core.Levels.ActivateSpec("info:noisy=error")
+	require.NoError(t, err)
+
+	assert.NotNil(t, core.Check(zapcore.Entry{LoggerName: "quiet", Level: zapcore.InfoLevel}, nil))
+	assert.Nil(t, core.Check(zapcore.Entry{LoggerName: "noisy", Level: zapcore.WarnLevel}, nil))
+	assert.Nil(t, core.Check(zapcore.Entry{LoggerName: "quiet", Level: zapcore.DebugLevel}, nil))
+}
+
+func TestJournaldCoreUnavailable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = flogging.NewJournaldCore(zapcore.DebugLevel, filepath.Join(dir, "missing"), "peer")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "journal is not available")
+
+	path, journal := newMockJournal(t)
+	core, err := flogging.NewJournaldCore(zapcore.DebugLevel, path, "peer")
+	require.NoError(t, err)
+	journal.Close()
+	os.Remove(path)
+
+	err = core.Write(zapcore.Entry{Message: "lost"}, nil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "journal is not available")
+}
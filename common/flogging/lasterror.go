@@ -0,0 +1,52 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// A LastErrorObserver is an Observer that records the message and time of the
+// most recent entry written at ErrorLevel or above.
+type LastErrorObserver struct {
+	mutex   sync.RWMutex
+	message string
+	time    time.Time
+}
+
+// NewLastErrorObserver creates an observer that has not recorded any errors.
+func NewLastErrorObserver() *LastErrorObserver {
+	return &LastErrorObserver{}
+}
+
+// Check is a no-op for the LastErrorObserver.
+func (l *LastErrorObserver) Check(e zapcore.Entry, ce *zapcore.CheckedEntry) {}
+
+// WriteEntry records the message and time of entries at ErrorLevel or above.
+func (l *LastErrorObserver) WriteEntry(e zapcore.Entry, fields []zapcore.Field) {
+	if e.Level < zapcore.ErrorLevel {
+		return
+	}
+
+	l.mutex.Lock()
+	if !e.Time.Before(l.time) {
+		l.message = e.Message
+		l.time = e.Time
+	}
+	l.mutex.Unlock()
+}
+
+// LastError returns the message and time of the most recent error entry. The
+// returned bool is false if no errors have been recorded.
+func (l *LastErrorObserver) LastError() (message string, t time.Time, ok bool) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	return l.message, l.time, !l.time.IsZero()
+}
@@ -0,0 +1,67 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging_test
+
+import (
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLastErrorObserver(t *testing.T) {
+	observer := flogging.NewLastErrorObserver()
+	_, _, ok := observer.LastError()
+	assert.False(t, ok)
+
+	now := time.Now()
+	observer.WriteEntry(zapcore.Entry{Level: zapcore.InfoLevel, Message: "info", Time: now}, nil)
+	_, _, ok = observer.LastError()
+	assert.False(t, ok, "info entries must be ignored")
+
+	observer.WriteEntry(zapcore.Entry{Level: zapcore.ErrorLevel, Message: "first", Time: now}, nil)
+	observer.WriteEntry(zapcore.Entry{Level: zapcore.WarnLevel, Message: "warning", Time: now.Add(time.Second)}, nil)
+	message, ts, ok := observer.LastError()
+	assert.True(t, ok)
+	assert.Equal(t, "first", message)
+	assert.True(t, now.Equal(ts))
+
+	observer.WriteEntry(zapcore.Entry{Level: zapcore.DPanicLevel, Message: "second", Time: now.Add(2 * time.Second)}, nil)
+	observer.WriteEntry(zapcore.Entry{Level: zapcore.ErrorLevel, Message: "stale", Time: now.Add(time.Second)}, nil)
+	message, ts, ok = observer.LastError()
+	assert.True(t, ok)
+	assert.Equal(t, "second", message)
+	assert.True(t, now.Add(2*time.Second).Equal(ts))
+}
+
+func TestLastErrorObserverLogging(t *testing.T) {
+	logging, err := flogging.New(flogging.Config{Writer: ioutil.Discard})
+	assert.NoError(t, err)
+	observer := flogging.NewLastErrorObserver()
+	logging.SetObserver(observer)
+
+	logger := logging.Logger("last-error")
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logger.Info("all is well")
+			logger.Errorf("something broke")
+		}()
+	}
+	wg.Wait()
+
+	message, ts, ok := observer.LastError()
+	assert.True(t, ok)
+	assert.Equal(t, "something broke", message)
+	assert.False(t, ts.IsZero())
+}
@@ -0,0 +1,118 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// A lazyEncoder is a zapcore.Encoder that defers creating the encoder it
+// delegates to until an entry is encoded. Until then, the fields added to it
+// are recorded and cloning only copies the record, so the encoders of a Core
+// that are never selected do not make With more expensive. The recorded
+// fields are added to the encoder when it is created, so values such as
+// objects and reflected values are marshaled at that time.
+type lazyEncoder struct {
+	newEncoder func() zapcore.Encoder
+	fields     []zapcore.Field
+
+	mutex sync.Mutex
+	enc   zapcore.Encoder
+}
+
+func newLazyEncoder(newEncoder func() zapcore.Encoder) *lazyEncoder {
+	return &lazyEncoder{newEncoder: newEncoder}
+}
+
+// encoder returns the delegate, creating it when it does not exist.
+func (l *lazyEncoder) encoder() zapcore.Encoder {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.enc == nil {
+		l.enc = l.newEncoder()
+		addFields(l.enc, l.fields)
+	}
+	return l.enc
+}
+
+// created returns the delegate or nil if it has not been created.
+func (l *lazyEncoder) created() zapcore.Encoder {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.enc
+}
+
+func (l *lazyEncoder) add(f zapcore.Field) {
+	if enc := l.created(); enc != nil {
+		addField(enc, f)
+	}
+	l.fields = append(l.fields, f)
+}
+
+// AddField records the field itself so that the delegate receives typed
+// fields unchanged.
+func (l *lazyEncoder) AddField(f zapcore.Field) { l.add(f) }
+
+// Clone copies the recorded fields and, when the delegate has been created,
+// clones the delegate.
+func (l *lazyEncoder) Clone() zapcore.Encoder {
+	clone := &lazyEncoder{
+		newEncoder: l.newEncoder,
+		fields:     l.fields[:len(l.fields):len(l.fields)],
+	}
+	if enc := l.created(); enc != nil {
+		clone.enc = enc.Clone()
+	}
+	return clone
+}
+
+func (l *lazyEncoder) EncodeEntry(e zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	return l.encoder().EncodeEntry(e, fields)
+}
+
+func (l *lazyEncoder) AddArray(k string, v zapcore.ArrayMarshaler) error {
+	l.add(zap.Array(k, v))
+	return nil
+}
+
+func (l *lazyEncoder) AddObject(k string, v zapcore.ObjectMarshaler) error {
+	l.add(zap.Object(k, v))
+	return nil
+}
+
+func (l *lazyEncoder) AddReflected(k string, v interface{}) error {
+	l.add(zap.Reflect(k, v))
+	return nil
+}
+
+func (l *lazyEncoder) AddBinary(k string, v []byte)          { l.add(zap.Binary(k, v)) }
+func (l *lazyEncoder) AddByteString(k string, v []byte)      { l.add(zap.ByteString(k, v)) }
+func (l *lazyEncoder) AddBool(k string, v bool)              { l.add(zap.Bool(k, v)) }
+func (l *lazyEncoder) AddComplex128(k string, v complex128)  { l.add(zap.Complex128(k, v)) }
+func (l *lazyEncoder) AddComplex64(k string, v complex64)    { l.add(zap.Complex64(k, v)) }
+func (l *lazyEncoder) AddDuration(k string, v time.Duration) { l.add(zap.Duration(k, v)) }
+func (l *lazyEncoder) AddFloat64(k string, v float64)        { l.add(zap.Float64(k, v)) }
+func (l *lazyEncoder) AddFloat32(k string, v float32)        { l.add(zap.Float32(k, v)) }
+func (l *lazyEncoder) AddInt(k string, v int)                { l.add(zap.Int(k, v)) }
+func (l *lazyEncoder) AddInt64(k string, v int64)            { l.add(zap.Int64(k, v)) }
+func (l *lazyEncoder) AddInt32(k string, v int32)            { l.add(zap.Int32(k, v)) }
+func (l *lazyEncoder) AddInt16(k string, v int16)            { l.add(zap.Int16(k, v)) }
+func (l *lazyEncoder) AddInt8(k string, v int8)              { l.add(zap.Int8(k, v)) }
+func (l *lazyEncoder) AddString(k, v string)                 { l.add(zap.String(k, v)) }
+func (l *lazyEncoder) AddTime(k string, v time.Time)         { l.add(zap.Time(k, v)) }
+func (l *lazyEncoder) AddUint(k string, v uint)              { l.add(zap.Uint(k, v)) }
+func (l *lazyEncoder) AddUint64(k string, v uint64)          { l.add(zap.Uint64(k, v)) }
+func (l *lazyEncoder) AddUint32(k string, v uint32)          { l.add(zap.Uint32(k, v)) }
+func (l *lazyEncoder) AddUint16(k string, v uint16)          { l.add(zap.Uint16(k, v)) }
+func (l *lazyEncoder) AddUint8(k string, v uint8)            { l.add(zap.Uint8(k, v)) }
+func (l *lazyEncoder) AddUintptr(k string, v uintptr)        { l.add(zap.Uintptr(k, v)) }
+func (l *lazyEncoder) OpenNamespace(k string)                { l.add(zap.Namespace(k)) }
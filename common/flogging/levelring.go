@@ -0,0 +1,95 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// An EntrySnapshot is a copy of a written log entry and its fields.
+type EntrySnapshot struct {
+	zapcore.Entry
+	Fields map[string]interface{}
+}
+
+// A PerLevelRingObserver is an Observer that retains the most recent entries
+// written at each level. Each level has its own bounded ring so a burst of
+// entries at one level does not displace the entries retained at another.
+type PerLevelRingObserver struct {
+	mutex sync.RWMutex
+	size  int
+	rings map[zapcore.Level]*entryRing
+}
+
+type entryRing struct {
+	entries []EntrySnapshot
+	next    int
+}
+
+// NewPerLevelRingObserver creates an observer that retains up to size
+// entries per level. A size of zero or less retains nothing.
+func NewPerLevelRingObserver(size int) *PerLevelRingObserver {
+	return &PerLevelRingObserver{
+		size:  size,
+		rings: map[zapcore.Level]*entryRing{},
+	}
+}
+
+// Check is a no-op for the PerLevelRingObserver.
+func (p *PerLevelRingObserver) Check(e zapcore.Entry, ce *zapcore.CheckedEntry) {}
+
+// WriteEntry adds a snapshot of the entry to the ring for its level,
+// replacing the oldest snapshot when the ring is full.
+func (p *PerLevelRingObserver) WriteEntry(e zapcore.Entry, fields []zapcore.Field) {
+	if p.size <= 0 {
+		return
+	}
+
+	enc := zapcore.NewMapObjectEncoder()
+	for i := range fields {
+		fields[i].AddTo(enc)
+	}
+	snapshot := EntrySnapshot{Entry: e, Fields: enc.Fields}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	r, ok := p.rings[e.Level]
+	if !ok {
+		r = &entryRing{}
+		p.rings[e.Level] = r
+	}
+	if len(r.entries) < p.size {
+		r.entries = append(r.entries, snapshot)
+		return
+	}
+	r.entries[r.next] = snapshot
+	r.next = (r.next + 1) % p.size
+}
+
+// Recent returns up to n of the most recent snapshots retained for the level
+// in the order they were written. All of the retained snapshots are returned
+// when n is zero or less.
+func (p *PerLevelRingObserver) Recent(level zapcore.Level, n int) []EntrySnapshot {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	r, ok := p.rings[level]
+	if !ok {
+		return nil
+	}
+	count := len(r.entries)
+	if n <= 0 || n > count {
+		n = count
+	}
+	recent := make([]EntrySnapshot, 0, n)
+	for i := count - n; i < count; i++ {
+		recent = append(recent, r.entries[(r.next+i)%count])
+	}
+	return recent
+}
@@ -0,0 +1,95 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func recentMessages(snapshots []flogging.EntrySnapshot) []string {
+	var messages []string
+	for _, s := range snapshots {
+		messages = append(messages, s.Message)
+	}
+	return messages
+}
+
+func TestPerLevelRingObserver(t *testing.T) {
+	observer := flogging.NewPerLevelRingObserver(3)
+	for i := 0; i < 5; i++ {
+		observer.WriteEntry(zapcore.Entry{Level: zapcore.ErrorLevel, Message: fmt.Sprintf("error-%d", i)}, nil)
+		if i < 2 {
+			observer.WriteEntry(zapcore.Entry{Level: zapcore.WarnLevel, Message: fmt.Sprintf("warn-%d", i)}, nil)
+		}
+	}
+
+	assert.Equal(t, []string{"error-2", "error-3", "error-4"}, recentMessages(observer.Recent(zapcore.ErrorLevel, 0)))
+	assert.Equal(t, []string{"error-3", "error-4"}, recentMessages(observer.Recent(zapcore.ErrorLevel, 2)))
+	assert.Equal(t, []string{"error-2", "error-3", "error-4"}, recentMessages(observer.Recent(zapcore.ErrorLevel, 10)))
+	assert.Equal(t, []string{"warn-0", "warn-1"}, recentMessages(observer.Recent(zapcore.WarnLevel, 10)))
+	assert.Equal(t, []string{"warn-1"}, recentMessages(observer.Recent(zapcore.WarnLevel, 1)))
+	assert.Empty(t, observer.Recent(zapcore.InfoLevel, 10))
+}
+
+func TestPerLevelRingObserverFields(t *testing.T) {
+	observer := flogging.NewPerLevelRingObserver(1)
+	fields := []zapcore.Field{zap.String("key", "value"), zap.Int("count", 3)}
+	observer.WriteEntry(zapcore.Entry{Level: zapcore.InfoLevel, LoggerName: "logger", Message: "message"}, fields)
+
+	recent := observer.Recent(zapcore.InfoLevel, 1)
+	assert.Len(t, recent, 1)
+	assert.Equal(t, "logger", recent[0].LoggerName)
+	assert.Equal(t, map[string]interface{}{"key": "value", "count": int64(3)}, recent[0].Fields)
+}
+
+func TestPerLevelRingObserverZeroSize(t *testing.T) {
+	observer := flogging.NewPerLevelRingObserver(0)
+	observer.WriteEntry(zapcore.Entry{Level: zapcore.ErrorLevel, Message: "message"}, nil)
+	assert.Empty(t, observer.Recent(zapcore.ErrorLevel, 1))
+}
+
+func TestPerLevelRingObserverCore(t *testing.T) {
+	observer := flogging.NewPerLevelRingObserver(2)
+	core := newTestConsoleCore(t, "info", &sw{})
+	core.Observer = observer
+
+	logger := zap.New(core)
+	logger.Debug("debug")
+	logger.Info("info")
+	logger.Error("error")
+
+	assert.Empty(t, observer.Recent(zapcore.DebugLevel, 0))
+	assert.Equal(t, []string{"info"}, recentMessages(observer.Recent(zapcore.InfoLevel, 0)))
+	assert.Equal(t, []string{"error"}, recentMessages(observer.Recent(zapcore.ErrorLevel, 0)))
+}
+
+func TestPerLevelRingObserverConcurrency(t *testing.T) {
+	observer := flogging.NewPerLevelRingObserver(10)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				observer.WriteEntry(zapcore.Entry{Level: zapcore.Level(j%3 - 1), Message: "message"}, nil)
+				observer.Recent(zapcore.Level(i%3-1), 5)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for _, level := range []zapcore.Level{zapcore.DebugLevel, zapcore.InfoLevel, zapcore.WarnLevel} {
+		assert.Len(t, observer.Recent(level, 0), 10)
+	}
+}
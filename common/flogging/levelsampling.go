@@ -0,0 +1,98 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"math"
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// A LevelSamplingCore is a zapcore.Core that samples entries below a
+// threshold level and passes every entry at or above the threshold to the
+// wrapped core. This keeps all of the INFO and higher entries while limiting
+// the volume of DEBUG entries.
+//
+// Sampling is applied to the sequence of entries written below the threshold
+// so exactly Rate of them are kept, spread evenly over the sequence. Cores
+// derived with With share the sequence of the core they were derived from.
+type LevelSamplingCore struct {
+	zapcore.Core
+
+	// Level is the threshold; entries at or above this level are never
+	// sampled.
+	Level zapcore.Level
+	// Rate is the fraction, between 0 and 1, of entries below Level that are
+	// kept.
+	Rate float64
+
+	counts *levelSamplingCounts
+}
+
+type levelSamplingCounts struct {
+	seen    uint64 // accessed atomically
+	dropped uint64 // accessed atomically
+}
+
+// NewLevelSamplingCore creates a LevelSamplingCore that keeps the fraction
+// of entries below InfoLevel specified by rate and delegates to the provided
+// core.
+func NewLevelSamplingCore(core zapcore.Core, rate float64) *LevelSamplingCore {
+	return &LevelSamplingCore{
+		Core:   core,
+		Level:  zapcore.InfoLevel,
+		Rate:   rate,
+		counts: &levelSamplingCounts{},
+	}
+}
+
+func (l *LevelSamplingCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *l
+	clone.Core = l.Core.With(fields)
+	return &clone
+}
+
+func (l *LevelSamplingCore) Check(e zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if e.Level >= l.Level {
+		return l.Core.Check(e, ce)
+	}
+	// Only entries that the wrapped core would write are sampled.
+	return checkWrapped(l.Core, e, ce, l, l.write)
+}
+
+func (l *LevelSamplingCore) Write(e zapcore.Entry, fields []zapcore.Field) error {
+	return l.write(e, fields, l.Core.Write)
+}
+
+func (l *LevelSamplingCore) write(e zapcore.Entry, fields []zapcore.Field, next writeFunc) error {
+	if e.Level < l.Level && !l.sample() {
+		atomic.AddUint64(&l.counts.dropped, 1)
+		return nil
+	}
+	return next(e, fields)
+}
+
+// Dropped returns the number of entries that have been dropped by the
+// sampler.
+func (l *LevelSamplingCore) Dropped() uint64 {
+	return atomic.LoadUint64(&l.counts.dropped)
+}
+
+// sample returns true when the next entry in the sequence should be kept.
+// The nth entry is kept when it moves the number of entries that should have
+// been kept, n*Rate, past a whole number.
+func (l *LevelSamplingCore) sample() bool {
+	switch {
+	case l.Rate <= 0:
+		return false
+	case l.Rate >= 1:
+		return true
+	}
+	n := atomic.AddUint64(&l.counts.seen, 1)
+	return math.Floor(float64(n)*l.Rate) > math.Floor(float64(n-1)*l.Rate)
+}
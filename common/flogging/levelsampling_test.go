@@ -0,0 +1,106 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLevelSamplingCore(t *testing.T) {
+	output := &sw{}
+	sampler := flogging.NewLevelSamplingCore(newTestConsoleCore(t, "debug", output), 0.25)
+	assert.Equal(t, zapcore.InfoLevel, sampler.Level)
+
+	logger := zap.New(sampler)
+	for i := 0; i < 100; i++ {
+		logger.Debug("debug-entry")
+		logger.Info("info-entry")
+		logger.Warn("warn-entry")
+		logger.Error("error-entry")
+	}
+
+	assert.Equal(t, 25, strings.Count(output.String(), "debug-entry"))
+	assert.Equal(t, 100, strings.Count(output.String(), "info-entry"))
+	assert.Equal(t, 100, strings.Count(output.String(), "warn-entry"))
+	assert.Equal(t, 100, strings.Count(output.String(), "error-entry"))
+	assert.Equal(t, uint64(75), sampler.Dropped())
+}
+
+func TestLevelSamplingCoreLevel(t *testing.T) {
+	output := &sw{}
+	sampler := flogging.NewLevelSamplingCore(newTestConsoleCore(t, "debug", output), 0.5)
+	sampler.Level = zapcore.WarnLevel
+
+	logger := zap.New(sampler)
+	for i := 0; i < 10; i++ {
+		logger.Debug("debug-entry")
+		logger.Info("info-entry")
+		logger.Warn("warn-entry")
+	}
+
+	// debug and info entries share the sampled sequence
+	assert.Equal(t, 10, strings.Count(output.String(), "debug-entry")+strings.Count(output.String(), "info-entry"))
+	assert.Equal(t, 10, strings.Count(output.String(), "warn-entry"))
+}
+
+func TestLevelSamplingCoreWith(t *testing.T) {
+	output := &sw{}
+	sampler := flogging.NewLevelSamplingCore(newTestConsoleCore(t, "debug", output), 0.5)
+
+	logger := zap.New(sampler)
+	derived := logger.With(zap.String("key", "value"))
+	for i := 0; i < 5; i++ {
+		logger.Debug("debug-entry")
+		derived.Debug("debug-entry")
+	}
+	derived.Info("info-entry")
+
+	assert.Equal(t, 5, strings.Count(output.String(), "debug-entry"))
+	assert.Equal(t, uint64(5), sampler.Dropped())
+	assert.Contains(t, output.String(), "info-entry key=value")
+}
+
+func TestLevelSamplingCoreRates(t *testing.T) {
+	tests := []struct {
+		rate     float64
+		expected int
+	}{
+		{rate: 0, expected: 0},
+		{rate: -1, expected: 0},
+		{rate: 1, expected: 10},
+		{rate: 2, expected: 10},
+		{rate: 0.1, expected: 1},
+	}
+	for _, tt := range tests {
+		output := &sw{}
+		logger := zap.New(flogging.NewLevelSamplingCore(newTestConsoleCore(t, "debug", output), tt.rate))
+		for i := 0; i < 10; i++ {
+			logger.Debug("debug-entry")
+		}
+		logger.Info("info-entry")
+		assert.Equal(t, tt.expected, strings.Count(output.String(), "debug-entry"), "rate %v", tt.rate)
+		assert.Contains(t, output.String(), "info-entry", "rate %v", tt.rate)
+	}
+}
+
+func TestLevelSamplingCoreDisabled(t *testing.T) {
+	output := &sw{}
+	sampler := flogging.NewLevelSamplingCore(newTestConsoleCore(t, "info", output), 0.5)
+
+	logger := zap.New(sampler)
+	for i := 0; i < 4; i++ {
+		logger.Debug("debug-entry")
+	}
+	assert.Empty(t, output.String())
+	assert.Zero(t, sampler.Dropped(), "disabled entries are not sampled")
+}
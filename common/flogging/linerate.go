@@ -0,0 +1,153 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// A LineRatePolicy determines what a LineRateCore does with entries that
+// exceed the configured rate.
+type LineRatePolicy int
+
+const (
+	// DropExcessLines drops entries that exceed the rate and counts them.
+	DropExcessLines LineRatePolicy = iota
+	// BlockExcessLines delays the writer until the entry can be written
+	// without exceeding the rate.
+	BlockExcessLines
+)
+
+// A LineRateCore is a zapcore.Core that limits the number of entries written
+// to the wrapped core per second. The limit is enforced with a token bucket
+// that allows short bursts above the rate. Write syncers only see encoded
+// bytes so the limit is applied to entries before they are encoded. Entries at
+// PanicLevel and above are not limited.
+//
+// Cores derived with With share the limit of the core they were derived from.
+type LineRateCore struct {
+	zapcore.Core
+
+	// Policy determines how entries that exceed the rate are handled.
+	Policy LineRatePolicy
+
+	limiter *lineLimiter
+}
+
+// NewLineRateCore creates a LineRateCore that writes at most linesPerSecond
+// entries per second to the provided core, with bursts of up to burst
+// entries. A linesPerSecond of zero or less disables the limit.
+func NewLineRateCore(core zapcore.Core, linesPerSecond float64, burst int, policy LineRatePolicy) *LineRateCore {
+	if burst < 1 {
+		burst = 1
+	}
+	return &LineRateCore{
+		Core:   core,
+		Policy: policy,
+		limiter: &lineLimiter{
+			rate:   linesPerSecond,
+			burst:  float64(burst),
+			tokens: float64(burst),
+			now:    time.Now,
+		},
+	}
+}
+
+func (l *LineRateCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *l
+	clone.Core = l.Core.With(fields)
+	return &clone
+}
+
+func (l *LineRateCore) Check(e zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	// Entries at PanicLevel and above are never limited as the logger panics
+	// or exits the process after writing them.
+	if e.Level >= zapcore.PanicLevel {
+		return l.Core.Check(e, ce)
+	}
+	// Only entries that the wrapped core would write consume tokens.
+	return checkWrapped(l.Core, e, ce, l, l.write)
+}
+
+func (l *LineRateCore) Write(e zapcore.Entry, fields []zapcore.Field) error {
+	return l.write(e, fields, l.Core.Write)
+}
+
+func (l *LineRateCore) write(e zapcore.Entry, fields []zapcore.Field, next writeFunc) error {
+	if e.Level >= zapcore.PanicLevel {
+		return next(e, fields)
+	}
+	wait, ok := l.limiter.reserve(l.Policy == BlockExcessLines)
+	if !ok {
+		atomic.AddUint64(&l.limiter.dropped, 1)
+		return nil
+	}
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+	return next(e, fields)
+}
+
+// Dropped returns the number of entries that have been dropped because they
+// exceeded the rate.
+func (l *LineRateCore) Dropped() uint64 {
+	return atomic.LoadUint64(&l.limiter.dropped)
+}
+
+// SetClock sets the function used to obtain the current time.
+func (l *LineRateCore) SetClock(now func() time.Time) {
+	l.limiter.mutex.Lock()
+	l.limiter.now = now
+	l.limiter.last = time.Time{}
+	l.limiter.mutex.Unlock()
+}
+
+type lineLimiter struct {
+	dropped uint64 // accessed atomically
+
+	mutex  sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+// reserve takes a token from the bucket. When the bucket is empty and block
+// is true, a token is borrowed from the future and the time to wait before it
+// becomes available is returned; otherwise reserve returns false.
+func (l *lineLimiter) reserve(block bool) (time.Duration, bool) {
+	if l.rate <= 0 {
+		return 0, true
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0, true
+	}
+	if !block {
+		return 0, false
+	}
+	l.tokens--
+	return time.Duration(-l.tokens / l.rate * float64(time.Second)), true
+}
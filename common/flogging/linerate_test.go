@@ -0,0 +1,136 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type fakeClock struct {
+	mutex sync.Mutex
+	now   time.Time
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.now
+}
+
+func (f *fakeClock) Advance(d time.Duration) {
+	f.mutex.Lock()
+	f.now = f.now.Add(d)
+	f.mutex.Unlock()
+}
+
+func TestLineRateCoreDrop(t *testing.T) {
+	output := &sw{}
+	core := flogging.NewLineRateCore(newTestConsoleCore(t, "info", output), 10, 10, flogging.DropExcessLines)
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	core.SetClock(clock.Now)
+	logger := zap.New(core)
+
+	// the burst is written immediately and the rest are dropped
+	for i := 0; i < 20; i++ {
+		logger.Info("line")
+	}
+	assert.Equal(t, 10, strings.Count(output.String(), "line"))
+	assert.Equal(t, uint64(10), core.Dropped())
+
+	// disabled entries do not consume tokens
+	clock.Advance(500 * time.Millisecond)
+	for i := 0; i < 10; i++ {
+		logger.Debug("disabled")
+	}
+	for i := 0; i < 10; i++ {
+		logger.Info("refilled")
+	}
+	assert.NotContains(t, output.String(), "disabled")
+	assert.Equal(t, 5, strings.Count(output.String(), "refilled"))
+	assert.Equal(t, uint64(15), core.Dropped())
+}
+
+func TestLineRateCorePanic(t *testing.T) {
+	output := &sw{}
+	core := flogging.NewLineRateCore(newTestConsoleCore(t, "info", output), 1, 1, flogging.DropExcessLines)
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	core.SetClock(clock.Now)
+	logger := zap.New(core)
+
+	logger.Info("spent")
+	logger.Info("dropped")
+	assert.Panics(t, func() { logger.Panic("panic") })
+	assert.Equal(t, "[] INFO spent\n[] PANIC panic\n", output.String())
+	assert.Equal(t, uint64(1), core.Dropped())
+}
+
+func TestLineRateCoreSustainedRate(t *testing.T) {
+	output := &sw{}
+	core := flogging.NewLineRateCore(newTestConsoleCore(t, "info", output), 50, 5, flogging.DropExcessLines)
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	core.SetClock(clock.Now)
+	logger := zap.New(core)
+
+	// attempt 1000 lines per second for ten seconds
+	for i := 0; i < 10000; i++ {
+		logger.Info("line")
+		clock.Advance(time.Millisecond)
+	}
+	written := strings.Count(output.String(), "line")
+	assert.InDelta(t, 5+50*10, written, 1)
+	assert.Equal(t, uint64(10000-written), core.Dropped())
+}
+
+func TestLineRateCoreBlock(t *testing.T) {
+	output := &sw{}
+	core := flogging.NewLineRateCore(newTestConsoleCore(t, "info", output), 200, 1, flogging.BlockExcessLines)
+	logger := zap.New(core)
+
+	start := time.Now()
+	for i := 0; i < 21; i++ {
+		logger.Info("line")
+	}
+	elapsed := time.Since(start)
+
+	assert.Equal(t, 21, strings.Count(output.String(), "line"))
+	assert.Equal(t, uint64(0), core.Dropped())
+	assert.True(t, elapsed >= 90*time.Millisecond, "expected writes to be delayed, took %s", elapsed)
+}
+
+func TestLineRateCoreWith(t *testing.T) {
+	output := &sw{}
+	core := flogging.NewLineRateCore(newTestConsoleCore(t, "info", output), 1, 2, flogging.DropExcessLines)
+	core.SetClock((&fakeClock{now: time.Unix(1000, 0)}).Now)
+
+	logger := zap.New(core)
+	derived := logger.With(zap.String("key", "value"))
+	logger.Info("parent")
+	derived.Info("derived")
+	derived.Info("dropped")
+	logger.Info("dropped")
+
+	assert.Equal(t, "[] INFO parent\n[] INFO derived key=value\n", output.String())
+	assert.Equal(t, uint64(2), core.Dropped())
+}
+
+func TestLineRateCoreUnlimited(t *testing.T) {
+	output := &sw{}
+	core := flogging.NewLineRateCore(newTestConsoleCore(t, "info", output), 0, 1, flogging.DropExcessLines)
+	logger := zap.New(core)
+	for i := 0; i < 100; i++ {
+		logger.Info("line")
+	}
+	assert.Equal(t, 100, strings.Count(output.String(), "line"))
+	assert.Equal(t, uint64(0), core.Dropped())
+}
@@ -8,10 +8,12 @@ package flogging
 
 import (
 	"fmt"
+	"hash/fnv"
 	"regexp"
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 	"go.uber.org/zap/zapcore"
@@ -24,8 +26,15 @@ type LoggerLevels struct {
 	specs        map[string]zapcore.Level
 	defaultLevel zapcore.Level
 	minLevel     zapcore.Level
+	muted        map[string]*time.Timer
+	specHash     string
 }
 
+// MutedLevel is the level reported for muted loggers. Entries at PanicLevel
+// and above are still enabled as the logger panics or exits the process after
+// writing them.
+const MutedLevel = zapcore.PanicLevel
+
 // DefaultLevel returns the default logging level for loggers that do not have
 // an explicit level set.
 func (l *LoggerLevels) DefaultLevel() zapcore.Level {
@@ -40,26 +49,121 @@ func (l *LoggerLevels) DefaultLevel() zapcore.Level {
 // The logging specification has the following form:
 //   [<logger>[,<logger>...]=]<level>[:[<logger>[,<logger>...]=]<level>...]
 func (l *LoggerLevels) ActivateSpec(spec string) error {
+	specs, err := ParseSpec(spec)
+	if err != nil {
+		return err
+	}
+	defaultLevel := specs[""]
+	delete(specs, "")
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	minLevel := defaultLevel
+	for _, lvl := range specs {
+		if lvl < minLevel {
+			minLevel = lvl
+		}
+	}
+
+	l.minLevel = minLevel
+	l.defaultLevel = defaultLevel
+	l.specs = specs
+	l.levelCache = map[string]zapcore.Level{}
+	l.specHash = hashSpec(l.spec())
+
+	return nil
+}
+
+// ResetToDefault discards the levels set for named loggers so that every
+// logger uses the default level. The change is applied atomically.
+func (l *LoggerLevels) ResetToDefault() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.minLevel = l.defaultLevel
+	l.specs = map[string]zapcore.Level{}
+	l.levelCache = map[string]zapcore.Level{}
+	l.specHash = hashSpec(l.spec())
+}
+
+// MuteLogger disables output below PanicLevel from the named logger and the
+// loggers below it for the provided duration. The logger name follows the rules of the
+// logging specification so a name that ends with a period mutes only the
+// exact logger. Muting a logger that is already muted replaces the duration.
+// Mutes are not affected by ActivateSpec or ResetToDefault and are not
+// reflected in Spec.
+func (l *LoggerLevels) MuteLogger(name string, ttl time.Duration) error {
+	if !isValidLoggerName(strings.TrimSuffix(name, ".")) {
+		return errors.Errorf("invalid logger name '%s'", name)
+	}
+	if ttl <= 0 {
+		return errors.Errorf("invalid mute duration %s for logger '%s'", ttl, name)
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.muted == nil {
+		l.muted = map[string]*time.Timer{}
+	}
+	if timer, ok := l.muted[name]; ok {
+		timer.Stop()
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(ttl, func() {
+		l.mutex.Lock()
+		if l.muted[name] == timer {
+			l.unmute(name)
+		}
+		l.mutex.Unlock()
+	})
+	l.muted[name] = timer
+	l.levelCache = map[string]zapcore.Level{}
+
+	return nil
+}
+
+// UnmuteLogger restores the output of a logger muted with MuteLogger before
+// its duration has elapsed.
+func (l *LoggerLevels) UnmuteLogger(name string) {
 	l.mutex.Lock()
 	defer l.mutex.Unlock()
 
-	defaultLevel := zapcore.InfoLevel
-	specs := map[string]zapcore.Level{}
+	if timer, ok := l.muted[name]; ok {
+		timer.Stop()
+		l.unmute(name)
+	}
+}
+
+// unmute removes a mute. It must be called with the mutex held.
+func (l *LoggerLevels) unmute(name string) {
+	delete(l.muted, name)
+	l.levelCache = map[string]zapcore.Level{}
+}
+
+// ParseSpec validates a logging specification and returns the level for each
+// logger it names. The default level is returned with the empty logger name.
+// The specification is not activated. Please see ActivateSpec for a
+// description of the specification format.
+func ParseSpec(spec string) (map[string]zapcore.Level, error) {
+	specs := map[string]zapcore.Level{"": zapcore.InfoLevel}
 	for _, field := range strings.Split(spec, ":") {
 		split := strings.Split(field, "=")
 		switch len(split) {
 		case 1: // level
 			if field != "" && !IsValidLevel(field) {
-				return errors.Errorf("invalid logging specification '%s': bad segment '%s'", spec, field)
+				return nil, errors.Errorf("invalid logging specification '%s': bad segment '%s'", spec, field)
 			}
-			defaultLevel = NameToLevel(field)
+			specs[""] = NameToLevel(field)
 
 		case 2: // <logger>[,<logger>...]=<level>
 			if split[0] == "" {
-				return errors.Errorf("invalid logging specification '%s': no logger specified in segment '%s'", spec, field)
+				return nil, errors.Errorf("invalid logging specification '%s': no logger specified in segment '%s'", spec, field)
 			}
 			if field != "" && !IsValidLevel(split[1]) {
-				return errors.Errorf("invalid logging specification '%s': bad segment '%s'", spec, field)
+				return nil, errors.Errorf("invalid logging specification '%s': bad segment '%s'", spec, field)
 			}
 
 			level := NameToLevel(split[1])
@@ -70,29 +174,17 @@ func (l *LoggerLevels) ActivateSpec(spec string) error {
 				// ending with a period signifies that this part of the
 				// spec refers to the exact logger name (i.e. is not a prefix)
 				if !isValidLoggerName(strings.TrimSuffix(logger, ".")) {
-					return errors.Errorf("invalid logging specification '%s': bad logger name '%s'", spec, logger)
+					return nil, errors.Errorf("invalid logging specification '%s': bad logger name '%s'", spec, logger)
 				}
 				specs[logger] = level
 			}
 
 		default:
-			return errors.Errorf("invalid logging specification '%s': bad segment '%s'", spec, field)
+			return nil, errors.Errorf("invalid logging specification '%s': bad segment '%s'", spec, field)
 		}
 	}
 
-	minLevel := defaultLevel
-	for _, lvl := range specs {
-		if lvl < minLevel {
-			minLevel = lvl
-		}
-	}
-
-	l.minLevel = minLevel
-	l.defaultLevel = defaultLevel
-	l.specs = specs
-	l.levelCache = map[string]zapcore.Level{}
-
-	return nil
+	return specs, nil
 }
 
 // logggerNameRegexp defines the valid logger names
@@ -125,6 +217,10 @@ func (l *LoggerLevels) Level(loggerName string) zapcore.Level {
 // calculateLevel walks the logger name back to find the appropriate
 // log level from the current spec.
 func (l *LoggerLevels) calculateLevel(loggerName string) zapcore.Level {
+	if l.isMuted(loggerName) {
+		return MutedLevel
+	}
+
 	candidate := loggerName + "."
 	for {
 		if lvl, ok := l.specs[candidate]; ok {
@@ -139,6 +235,26 @@ func (l *LoggerLevels) calculateLevel(loggerName string) zapcore.Level {
 	}
 }
 
+// isMuted walks the logger name back to find whether the logger is muted.
+func (l *LoggerLevels) isMuted(loggerName string) bool {
+	if len(l.muted) == 0 {
+		return false
+	}
+
+	candidate := loggerName + "."
+	for {
+		if _, ok := l.muted[candidate]; ok {
+			return true
+		}
+
+		idx := strings.LastIndex(candidate, ".")
+		if idx <= 0 {
+			return false
+		}
+		candidate = candidate[:idx]
+	}
+}
+
 // cachedLevel attempts to retrieve the effective log level for a logger from the
 // cache. If the logger is not found, ok will be false.
 func (l *LoggerLevels) cachedLevel(loggerName string) (lvl zapcore.Level, ok bool) {
@@ -152,7 +268,20 @@ func (l *LoggerLevels) cachedLevel(loggerName string) (lvl zapcore.Level, ok boo
 func (l *LoggerLevels) Spec() string {
 	l.mutex.RLock()
 	defer l.mutex.RUnlock()
+	return l.spec()
+}
+
+// SpecHash returns a short hash of the normalized active logging spec. The
+// hash is computed when the spec changes so that it is cheap to retrieve.
+// Muted loggers do not contribute to the hash.
+func (l *LoggerLevels) SpecHash() string {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	return l.specHash
+}
 
+// spec returns the normalized spec. It must be called with the mutex held.
+func (l *LoggerLevels) spec() string {
 	var fields []string
 	for k, v := range l.specs {
 		fields = append(fields, fmt.Sprintf("%s=%s", k, v))
@@ -164,6 +293,12 @@ func (l *LoggerLevels) Spec() string {
 	return strings.Join(fields, ":")
 }
 
+func hashSpec(spec string) string {
+	h := fnv.New32a()
+	h.Write([]byte(spec))
+	return fmt.Sprintf("%08x", h.Sum32())
+}
+
 // Enabled function is an enabled check that evaluates the minimum active logging level.
 // It serves as a fast check before the (relatively) expensive Check call in the core.
 func (l *LoggerLevels) Enabled(lvl zapcore.Level) bool {
@@ -9,10 +9,13 @@ package flogging_test
 import (
 	"errors"
 	"strconv"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/hyperledger/fabric/common/flogging"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
@@ -134,6 +137,222 @@ func TestLoggerLevelsActivateSpecErrors(t *testing.T) {
 	}
 }
 
+func TestParseSpec(t *testing.T) {
+	var tests = []struct {
+		spec     string
+		expected map[string]zapcore.Level
+	}{
+		{spec: "", expected: map[string]zapcore.Level{"": zapcore.InfoLevel}},
+		{spec: "debug", expected: map[string]zapcore.Level{"": zapcore.DebugLevel}},
+		{
+			spec: "info:peer.gossip=debug:orderer=warn",
+			expected: map[string]zapcore.Level{
+				"":            zapcore.InfoLevel,
+				"peer.gossip": zapcore.DebugLevel,
+				"orderer":     zapcore.WarnLevel,
+			},
+		},
+		{
+			spec: "a.=PAYLOAD:b,c=error:fatal",
+			expected: map[string]zapcore.Level{
+				"":   zapcore.FatalLevel,
+				"a.": flogging.PayloadLevel,
+				"b":  zapcore.ErrorLevel,
+				"c":  zapcore.ErrorLevel,
+			},
+		},
+		{spec: "a=warn:a=error", expected: map[string]zapcore.Level{"": zapcore.InfoLevel, "a": zapcore.ErrorLevel}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.spec, func(t *testing.T) {
+			levels, err := flogging.ParseSpec(tc.spec)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, levels)
+		})
+	}
+}
+
+func TestParseSpecErrors(t *testing.T) {
+	var tests = []struct {
+		spec string
+		err  string
+	}{
+		{spec: "verbose", err: "invalid logging specification 'verbose': bad segment 'verbose'"},
+		{spec: "info:peer=loud", err: "invalid logging specification 'info:peer=loud': bad segment 'peer=loud'"},
+		{spec: "=debug", err: "invalid logging specification '=debug': no logger specified in segment '=debug'"},
+		{spec: "a=b=c", err: "invalid logging specification 'a=b=c': bad segment 'a=b=c'"},
+		{spec: "info:peer=debug,orderer=warn", err: "invalid logging specification 'info:peer=debug,orderer=warn': bad segment 'peer=debug,orderer=warn'"},
+		{spec: "peer,,orderer=debug", err: "invalid logging specification 'peer,,orderer=debug': bad logger name ''"},
+		{spec: "peer gossip=debug", err: "invalid logging specification 'peer gossip=debug': bad logger name 'peer gossip'"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.spec, func(t *testing.T) {
+			levels, err := flogging.ParseSpec(tc.spec)
+			assert.EqualError(t, err, tc.err)
+			assert.Nil(t, levels)
+		})
+	}
+}
+
+func TestLoggerLevelsResetToDefault(t *testing.T) {
+	ll := &flogging.LoggerLevels{}
+	err := ll.ActivateSpec("warn:a=debug:b.c=error")
+	assert.NoError(t, err)
+	assert.Equal(t, zapcore.DebugLevel, ll.Level("a.b"))
+
+	ll.ResetToDefault()
+	assert.Equal(t, "warn", ll.Spec())
+	assert.Equal(t, zapcore.WarnLevel, ll.DefaultLevel())
+	for _, name := range []string{"a", "a.b", "b.c", "other"} {
+		assert.Equal(t, zapcore.WarnLevel, ll.Level(name))
+	}
+	assert.False(t, ll.Enabled(zapcore.InfoLevel))
+	assert.True(t, ll.Enabled(zapcore.WarnLevel))
+}
+
+func TestLoggerLevelsResetToDefaultConcurrent(t *testing.T) {
+	ll := &flogging.LoggerLevels{}
+	err := ll.ActivateSpec("warn:a=debug")
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				spec := ll.Spec()
+				assert.Contains(t, []string{"a=debug:warn", "warn"}, spec)
+				assert.Contains(t, []zapcore.Level{zapcore.DebugLevel, zapcore.WarnLevel}, ll.Level("a"))
+				assert.Equal(t, zapcore.WarnLevel, ll.Level("b"))
+			}
+		}()
+	}
+
+	for i := 0; i < 100; i++ {
+		err := ll.ActivateSpec("warn:a=debug")
+		assert.NoError(t, err)
+		ll.ResetToDefault()
+		assert.Equal(t, zapcore.WarnLevel, ll.Level("a"), "reset must be visible immediately")
+		assert.False(t, ll.Enabled(zapcore.DebugLevel))
+	}
+	close(done)
+	wg.Wait()
+}
+
+func TestLoggerLevelsMuteLogger(t *testing.T) {
+	ll := &flogging.LoggerLevels{}
+	err := ll.ActivateSpec("info:a.b=debug")
+	assert.NoError(t, err)
+
+	err = ll.MuteLogger("a", time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, flogging.MutedLevel, ll.Level("a"))
+	assert.Equal(t, flogging.MutedLevel, ll.Level("a.b"))
+	assert.Equal(t, zapcore.InfoLevel, ll.Level("b"))
+	assert.Equal(t, "a.b=debug:info", ll.Spec())
+	assert.True(t, ll.Enabled(zapcore.DebugLevel))
+
+	err = ll.ActivateSpec("warn")
+	assert.NoError(t, err)
+	assert.Equal(t, flogging.MutedLevel, ll.Level("a"), "mutes survive spec changes")
+
+	ll.UnmuteLogger("a")
+	assert.Equal(t, zapcore.WarnLevel, ll.Level("a"))
+	assert.Equal(t, zapcore.WarnLevel, ll.Level("a.b"))
+
+	err = ll.MuteLogger("c.", time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, flogging.MutedLevel, ll.Level("c"))
+	assert.Equal(t, zapcore.WarnLevel, ll.Level("c.d"))
+}
+
+func TestLoggerLevelsMuteLoggerExpires(t *testing.T) {
+	output := &sw{}
+	core := newTestConsoleCore(t, "info", output)
+	logger := zap.New(core).Named("noisy")
+
+	err := core.Levels.MuteLogger("noisy", 50*time.Millisecond)
+	assert.NoError(t, err)
+	logger.Error("muted")
+	zap.New(core).Named("quiet").Info("not muted")
+	assert.Equal(t, "[quiet] INFO not muted\n", output.String())
+
+	assert.Eventually(t, func() bool { return core.Levels.Level("noisy") == zapcore.InfoLevel }, time.Second, 10*time.Millisecond)
+	output.Reset()
+	logger.Info("restored")
+	assert.Equal(t, "[noisy] INFO restored\n", output.String())
+}
+
+func TestLoggerLevelsMuteLoggerKeepsPanic(t *testing.T) {
+	output := &sw{}
+	core := newTestConsoleCore(t, "info", output)
+	logger := zap.New(core).Named("noisy")
+
+	err := core.Levels.MuteLogger("noisy", time.Hour)
+	assert.NoError(t, err)
+	logger.Error("muted")
+	logger.DPanic("muted")
+	assert.Empty(t, output.String())
+
+	assert.Panics(t, func() { logger.Panic("last words") })
+	assert.Equal(t, "[noisy] PANIC last words\n", output.String())
+}
+
+func TestLoggerLevelsMuteLoggerRenew(t *testing.T) {
+	ll := &flogging.LoggerLevels{}
+	err := ll.ActivateSpec("info")
+	assert.NoError(t, err)
+
+	err = ll.MuteLogger("a", 20*time.Millisecond)
+	assert.NoError(t, err)
+	err = ll.MuteLogger("a", time.Hour)
+	assert.NoError(t, err)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, flogging.MutedLevel, ll.Level("a"), "the first duration must not restore the logger")
+}
+
+func TestLoggerLevelsMuteLoggerErrors(t *testing.T) {
+	ll := &flogging.LoggerLevels{}
+	err := ll.ActivateSpec("info")
+	assert.NoError(t, err)
+
+	err = ll.MuteLogger("a..b", time.Second)
+	assert.EqualError(t, err, "invalid logger name 'a..b'")
+	err = ll.MuteLogger("a", 0)
+	assert.EqualError(t, err, "invalid mute duration 0s for logger 'a'")
+}
+
+func TestLoggerLevelsSpecHash(t *testing.T) {
+	ll := &flogging.LoggerLevels{}
+	err := ll.ActivateSpec("info:a=debug")
+	assert.NoError(t, err)
+	hash := ll.SpecHash()
+	assert.Len(t, hash, 8)
+	assert.Equal(t, hash, ll.SpecHash(), "the hash must be stable")
+
+	other := &flogging.LoggerLevels{}
+	err = other.ActivateSpec("a=debug:info")
+	assert.NoError(t, err)
+	assert.Equal(t, hash, other.SpecHash(), "equivalent specs must have the same hash")
+
+	err = ll.ActivateSpec("info:a=warn")
+	assert.NoError(t, err)
+	assert.NotEqual(t, hash, ll.SpecHash())
+
+	ll.ResetToDefault()
+	reset := &flogging.LoggerLevels{}
+	err = reset.ActivateSpec("info")
+	assert.NoError(t, err)
+	assert.Equal(t, reset.SpecHash(), ll.SpecHash())
+}
+
 func TestSpec(t *testing.T) {
 	var tests = []struct {
 		input  string
@@ -11,9 +11,9 @@ import (
 	"io"
 	"os"
 	"sync"
+	"time"
 
 	"github.com/hyperledger/fabric/common/flogging/fabenc"
-	zaplogfmt "github.com/sykesm/zap-logfmt"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -21,9 +21,14 @@ import (
 // Config is used to provide dependencies to a Logging instance.
 type Config struct {
 	// Format is the log record format specifier for the Logging instance. If the
-	// spec is the string "json", log records will be formatted as JSON. Any
-	// other string will be provided to the FormatEncoder. Please see
-	// fabenc.ParseFormat for details on the supported verbs.
+	// spec is the string "json", log records will be formatted as JSON. If the
+	// spec is the string "logfmt", "cbor", or "msgpack", log records will be
+	// encoded as logfmt, CBOR, or MessagePack respectively. If the spec is the
+	// string "deterministic-json", log records will be formatted as JSON with
+	// the fields sorted by key. If the spec is the string "ecs", log records will
+	// be formatted as Elastic Common Schema JSON. Any other string will be
+	// provided to the FormatEncoder. Please see fabenc.ParseFormat for details
+	// on the supported verbs.
 	//
 	// If Format is not provided, a default format that provides basic information will
 	// be used.
@@ -39,6 +44,96 @@ type Config struct {
 	//
 	// If a Writer is not provided, os.Stderr will be used as the log sink.
 	Writer io.Writer
+
+	// StringifyEncodings lists the encodings that should render all scalar
+	// field values as strings. This is intended for legacy consumers that
+	// are unable to handle numeric or boolean values.
+	//
+	// If StringifyEncodings is not provided, field values keep their native
+	// types in all encodings.
+	StringifyEncodings []Encoding
+
+	// MaxFields limits the number of fields encoded with each log entry.
+	// Fields beyond the limit are dropped and replaced with a single
+	// fields_truncated field that records how many were dropped.
+	//
+	// If MaxFields is not provided, the number of fields is not limited.
+	MaxFields int
+
+	// MaxWithFields limits the number of fields that can be accumulated by
+	// repeated calls to With on a logger. Fields beyond the limit are
+	// dropped and a warning is logged once.
+	//
+	// If MaxWithFields is not provided, the number of accumulated fields is
+	// not limited.
+	MaxWithFields int
+
+	// PrefixLoggerName, when true, renders the logger name in brackets at
+	// the start of the message in the CONSOLE encoding. The structured
+	// encodings continue to record the name in a separate field.
+	PrefixLoggerName bool
+
+	// ShortLevels, when true, renders levels as a single letter in the
+	// CONSOLE encoding. Other encodings keep the full level names.
+	ShortLevels bool
+
+	// ElideRepeatedTime, when true, replaces the timestamp of CONSOLE
+	// entries with blank space when it falls within the same second as the
+	// timestamp of the previous entry.
+	ElideRepeatedTime bool
+
+	// ConsoleColumns specifies the column widths used to align the time,
+	// level, logger name, and message of CONSOLE entries. Other encodings are
+	// not affected.
+	//
+	// If ConsoleColumns is not provided, CONSOLE entries are not aligned.
+	ConsoleColumns fabenc.Columns
+
+	// LevelStyles replaces the default colors written by the %{color} verb
+	// of the CONSOLE format for the levels in the map.
+	//
+	// If LevelStyles is not provided, every level uses its default color.
+	LevelStyles map[zapcore.Level]fabenc.Style
+
+	// SpecHash, when true, adds a spec_hash field to every entry that carries
+	// a short hash of the active logging spec.
+	SpecHash bool
+
+	// TimePrecision, when greater than zero, truncates entry timestamps to a
+	// multiple of the precision in every encoding. ParseTimePrecision returns
+	// the precisions named ns, us, ms, and s.
+	//
+	// If TimePrecision is not provided, timestamps are not truncated.
+	TimePrecision time.Duration
+
+	// ServiceField, when true, adds a service field to every entry that
+	// identifies the process that wrote it so multiplexed logs can be
+	// attributed.
+	ServiceField bool
+
+	// ServiceName is the value of the service field.
+	//
+	// If ServiceName is not provided, the name returned by DefaultServiceName
+	// is used.
+	ServiceName string
+
+	// EntryIDs, when true, adds a short random entry_id field to every entry
+	// that does not carry a correlation or trace id so each line can be
+	// referenced individually.
+	EntryIDs bool
+
+	// Uptime, when true, adds an uptime field to every entry that records the
+	// time elapsed since the Logging instance was created.
+	Uptime bool
+
+	// FlushTerminal, when true, syncs Writer after every record when it is a
+	// terminal so each line is visible immediately. Other writers, such as
+	// files, are not synced after every record.
+	FlushTerminal bool
+
+	// CRLFLineEndings, when true, converts the LF line endings of the records
+	// written to Writer to CRLF.
+	CRLFLineEndings bool
 }
 
 // Logging maintains the state associated with the fabric logging system. It is
@@ -53,20 +148,37 @@ type Logging struct {
 	multiFormatter *fabenc.MultiFormatter
 	writer         zapcore.WriteSyncer
 	observer       Observer
+	stringify      map[Encoding]bool
+	maxFields      int
+	maxWithFields  int
+	prefixName     bool
+	shortLevels    bool
+	elideTime      bool
+	timeElider     *fabenc.TimeElider
+	columns        fabenc.Columns
+	levelStyles    map[zapcore.Level]fabenc.Style
+	uptime         bool
+	specHash       bool
+	timePrecision  time.Duration
+	serviceField   bool
+	serviceName    string
+	entryIDs       bool
+	start          time.Time
+	defaultFields  *DefaultFields
 }
 
 // New creates a new logging system and initializes it with the provided
 // configuration.
 func New(c Config) (*Logging, error) {
-	encoderConfig := zap.NewProductionEncoderConfig()
-	encoderConfig.NameKey = "name"
-
 	l := &Logging{
 		LoggerLevels: &LoggerLevels{
 			defaultLevel: defaultLevel,
 		},
-		encoderConfig:  encoderConfig,
+		encoderConfig:  newEncoderConfig(),
 		multiFormatter: fabenc.NewMultiFormatter(),
+		start:          time.Now(),
+		defaultFields:  NewDefaultFields(),
+		timeElider:     fabenc.NewTimeElider(),
 	}
 
 	err := l.Apply(c)
@@ -76,6 +188,15 @@ func New(c Config) (*Logging, error) {
 	return l, nil
 }
 
+// newEncoderConfig returns the encoder configuration used for the structured
+// (JSON and LOGFMT) encodings.
+func newEncoderConfig() zapcore.EncoderConfig {
+	encoderConfig := zap.NewProductionEncoderConfig()
+	encoderConfig.NameKey = "name"
+	encoderConfig.EncodeDuration = millisDurationEncoder
+	return encoderConfig
+}
+
 // Apply applies the provided configuration to the logging system.
 func (l *Logging) Apply(c Config) error {
 	err := l.SetFormat(c.Format)
@@ -98,7 +219,27 @@ func (l *Logging) Apply(c Config) error {
 	if c.Writer == nil {
 		c.Writer = os.Stderr
 	}
+	if c.FlushTerminal {
+		c.Writer = NewLineFlushSyncer(c.Writer)
+	}
+	if c.CRLFLineEndings {
+		c.Writer = NewCRLFSyncer(c.Writer)
+	}
 	l.SetWriter(c.Writer)
+	l.SetStringifyEncodings(c.StringifyEncodings...)
+	l.SetMaxFields(c.MaxFields)
+	l.SetMaxWithFields(c.MaxWithFields)
+	l.SetPrefixLoggerName(c.PrefixLoggerName)
+	l.SetShortLevels(c.ShortLevels)
+	l.SetElideRepeatedTime(c.ElideRepeatedTime)
+	l.SetConsoleColumns(c.ConsoleColumns)
+	l.SetLevelStyles(c.LevelStyles)
+	l.SetUptime(c.Uptime)
+	l.SetSpecHash(c.SpecHash)
+	l.SetTimePrecision(c.TimePrecision)
+	l.SetServiceField(c.ServiceField)
+	l.SetServiceName(c.ServiceName)
+	l.SetEntryIDs(c.EntryIDs)
 
 	return nil
 }
@@ -124,6 +265,26 @@ func (l *Logging) SetFormat(format string) error {
 		return nil
 	}
 
+	if format == "cbor" {
+		l.encoding = CBOR
+		return nil
+	}
+
+	if format == "msgpack" {
+		l.encoding = MSGPACK
+		return nil
+	}
+
+	if format == "deterministic-json" {
+		l.encoding = DETERMINISTIC_JSON
+		return nil
+	}
+
+	if format == "ecs" {
+		l.encoding = ECS
+		return nil
+	}
+
 	formatters, err := fabenc.ParseFormat(format)
 	if err != nil {
 		return err
@@ -151,11 +312,153 @@ func (l *Logging) SetWriter(w io.Writer) io.Writer {
 	l.mutex.Lock()
 	ow := l.writer
 	l.writer = sw
+	l.timeElider.Reset()
 	l.mutex.Unlock()
 
 	return ow
 }
 
+// SetStringifyEncodings sets the encodings that render scalar field values as
+// strings. Loggers created after this method has completed will use the new
+// settings.
+func (l *Logging) SetStringifyEncodings(encodings ...Encoding) {
+	stringify := map[Encoding]bool{}
+	for _, e := range encodings {
+		stringify[e] = true
+	}
+
+	l.mutex.Lock()
+	l.stringify = stringify
+	l.mutex.Unlock()
+}
+
+// SetMaxFields sets the maximum number of fields encoded with each log entry.
+// A limit of zero disables the limit. The limit applies to loggers created
+// after this method has completed.
+func (l *Logging) SetMaxFields(max int) {
+	l.mutex.Lock()
+	l.maxFields = max
+	l.mutex.Unlock()
+}
+
+// SetMaxWithFields sets the maximum number of fields that can be accumulated
+// with With. A limit of zero disables the limit. The limit applies to loggers
+// created after this method has completed.
+func (l *Logging) SetMaxWithFields(max int) {
+	l.mutex.Lock()
+	l.maxWithFields = max
+	l.mutex.Unlock()
+}
+
+// SetPrefixLoggerName controls whether the CONSOLE encoding renders the
+// logger name as a bracketed prefix of the message. The setting applies to
+// loggers created after this method has completed.
+func (l *Logging) SetPrefixLoggerName(prefix bool) {
+	l.mutex.Lock()
+	l.prefixName = prefix
+	l.mutex.Unlock()
+}
+
+// SetShortLevels controls whether the CONSOLE encoding renders levels as a
+// single letter. The setting applies to loggers created after this method has
+// completed.
+func (l *Logging) SetShortLevels(short bool) {
+	l.mutex.Lock()
+	l.shortLevels = short
+	l.mutex.Unlock()
+}
+
+// SetElideRepeatedTime controls whether the CONSOLE encoding replaces
+// timestamps that repeat the second of the previous entry with blank space.
+// The setting applies to loggers created after this method has completed.
+func (l *Logging) SetElideRepeatedTime(elide bool) {
+	l.mutex.Lock()
+	l.elideTime = elide
+	l.mutex.Unlock()
+}
+
+// SetConsoleColumns sets the column widths used to align CONSOLE entries. The
+// setting applies to loggers created after this method has completed.
+func (l *Logging) SetConsoleColumns(columns fabenc.Columns) {
+	l.mutex.Lock()
+	l.columns = columns
+	l.mutex.Unlock()
+}
+
+// SetLevelStyles sets the styles used by the CONSOLE encoding for the colors
+// of the levels in the map. Levels that are not in the map use their default
+// color. The setting applies to loggers created after this method has
+// completed.
+func (l *Logging) SetLevelStyles(styles map[zapcore.Level]fabenc.Style) {
+	copied := map[zapcore.Level]fabenc.Style{}
+	for level, style := range styles {
+		copied[level] = style
+	}
+
+	l.mutex.Lock()
+	l.levelStyles = copied
+	l.mutex.Unlock()
+}
+
+// SetTimePrecision sets the precision that entry timestamps are truncated to.
+// A precision of zero or less disables truncation. The setting applies to
+// loggers created after this method has completed.
+func (l *Logging) SetTimePrecision(precision time.Duration) {
+	l.mutex.Lock()
+	l.timePrecision = precision
+	l.mutex.Unlock()
+}
+
+// SetServiceField controls whether a service field is added to every entry.
+// The setting applies to loggers created after this method has completed.
+func (l *Logging) SetServiceField(enabled bool) {
+	l.mutex.Lock()
+	l.serviceField = enabled
+	l.mutex.Unlock()
+}
+
+// SetServiceName sets the value of the service field. An empty name selects
+// the name returned by DefaultServiceName. The setting applies to loggers
+// created after this method has completed.
+func (l *Logging) SetServiceName(name string) {
+	l.mutex.Lock()
+	l.serviceName = name
+	l.mutex.Unlock()
+}
+
+// SetEntryIDs controls whether entries without a correlation or trace id
+// receive a random entry_id field. The setting applies to loggers created
+// after this method has completed.
+func (l *Logging) SetEntryIDs(enabled bool) {
+	l.mutex.Lock()
+	l.entryIDs = enabled
+	l.mutex.Unlock()
+}
+
+// SetUptime controls whether an uptime field is added to every entry. The
+// setting applies to loggers created after this method has completed.
+func (l *Logging) SetUptime(uptime bool) {
+	l.mutex.Lock()
+	l.uptime = uptime
+	l.mutex.Unlock()
+}
+
+// SetDefaultFields sets the fields that are added to the entries written by
+// the loggers below the logger name prefix. Fields provided when an entry is
+// written take precedence over the defaults. The defaults apply to loggers
+// created with New, even those created before this method was called.
+func (l *Logging) SetDefaultFields(prefix string, fields ...zapcore.Field) {
+	l.defaultFields.Set(prefix, fields...)
+}
+
+// SetSpecHash controls whether a spec_hash field is added to every entry. The
+// setting applies to loggers created after this method has completed.
+func (l *Logging) SetSpecHash(specHash bool) {
+	l.mutex.Lock()
+	l.specHash = specHash
+	l.mutex.Unlock()
+}
+
 // SetObserver is used to provide a log observer that will be called as log
 // levels are checked or written.. Only a single observer is supported.
 func (l *Logging) SetObserver(observer Observer) Observer {
@@ -167,6 +470,46 @@ func (l *Logging) SetObserver(observer Observer) Observer {
 	return so
 }
 
+// LoggingState is a snapshot of the logging spec and encoding of a Logging
+// instance. It is created by Snapshot and applied by Restore.
+type LoggingState struct {
+	Spec       string
+	Encoding   Encoding
+	formatters []fabenc.Formatter
+}
+
+// Snapshot returns the active logging spec and the encoding, including the
+// CONSOLE format, used by the logging system.
+func (l *Logging) Snapshot() LoggingState {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	return LoggingState{
+		Spec:       l.LoggerLevels.Spec(),
+		Encoding:   l.encoding,
+		formatters: l.multiFormatter.Formatters(),
+	}
+}
+
+// Restore reactivates the logging spec and encoding captured by Snapshot. The
+// state is restored while holding the configuration lock so that concurrent
+// calls to Snapshot, Restore, and SetFormat observe either the prior or the
+// restored state. An error is returned and nothing is changed when the spec
+// is invalid.
+func (l *Logging) Restore(state LoggingState) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if err := l.LoggerLevels.ActivateSpec(state.Spec); err != nil {
+		return err
+	}
+	l.encoding = state.Encoding
+	if state.formatters != nil {
+		l.multiFormatter.SetFormatters(state.formatters)
+	}
+	return nil
+}
+
 // Write satisfies the io.Write contract. It delegates to the writer argument
 // of SetWriter or the Writer field of Config. The Core uses this when encoding
 // log records.
@@ -205,23 +548,81 @@ func (l *Logging) ZapLogger(name string) *zap.Logger {
 	}
 
 	l.mutex.RLock()
-	core := &Core{
-		LevelEnabler: l.LoggerLevels,
-		Levels:       l.LoggerLevels,
-		Encoders: map[Encoding]zapcore.Encoder{
-			JSON:    zapcore.NewJSONEncoder(l.encoderConfig),
-			CONSOLE: fabenc.NewFormatEncoder(l.multiFormatter),
-			LOGFMT:  zaplogfmt.NewEncoder(l.encoderConfig),
+	multiFormatter, prefixName, shortLevels := l.multiFormatter, l.prefixName, l.shortLevels
+	columns, levelStyles, timeElider := l.columns, l.levelStyles, l.timeElider
+	if !l.elideTime {
+		timeElider = nil
+	}
+	encoderConfig := l.encoderConfig
+	newEncoders := map[Encoding]func() zapcore.Encoder{
+		JSON: func() zapcore.Encoder { return zapcore.NewJSONEncoder(encoderConfig) },
+		CONSOLE: func() zapcore.Encoder {
+			console := fabenc.NewFormatEncoder(multiFormatter)
+			console.PrefixLoggerName = prefixName
+			console.ShortLevels = shortLevels
+			console.Columns = columns
+			console.LevelStyles = levelStyles
+			if timeElider != nil {
+				console.TimeElider = timeElider
+			}
+			return console
 		},
-		Selector: l,
-		Output:   l,
-		Observer: l,
+		LOGFMT:             func() zapcore.Encoder { return fabenc.NewLogfmtEncoder(encoderConfig) },
+		CBOR:               func() zapcore.Encoder { return fabenc.NewCBOREncoder() },
+		MSGPACK:            func() zapcore.Encoder { return fabenc.NewMsgpackEncoder() },
+		DETERMINISTIC_JSON: func() zapcore.Encoder { return fabenc.NewSortedJSONEncoder(encoderConfig) },
+		ECS:                func() zapcore.Encoder { return fabenc.NewECSEncoder() },
+	}
+	// the encoders are created when they are first selected so With does
+	// not clone the encoders of encodings that are not in use
+	encoders := map[Encoding]zapcore.Encoder{}
+	for e, newEncoder := range newEncoders {
+		if l.stringify[e] {
+			newEncoder = stringifyEncoder(newEncoder)
+		}
+		encoders[e] = newLazyEncoder(newEncoder)
+	}
+	core := &Core{
+		LevelEnabler:  l.LoggerLevels,
+		Levels:        l.LoggerLevels,
+		Encoders:      encoders,
+		Selector:      l,
+		Output:        l,
+		Observer:      l,
+		MaxFields:     l.maxFields,
+		MaxWithFields: l.maxWithFields,
+	}
+	if l.defaultFields != nil {
+		core.Transformers = append(core.Transformers, l.defaultFields.Transform)
 	}
+	if l.serviceField {
+		core.Transformers = append(core.Transformers, NewServiceTransformer(l.serviceName))
+	}
+	if l.uptime {
+		core.Transformers = append(core.Transformers, NewUptimeTransformer(l.start))
+	}
+	if l.specHash {
+		core.Transformers = append(core.Transformers, NewSpecHashTransformer(l.LoggerLevels))
+	}
+	if l.timePrecision > 0 {
+		core.Transformers = append(core.Transformers, NewTimePrecisionTransformer(l.timePrecision))
+	}
+	core.Transformers = append(core.Transformers, EmbeddedMetricsTimestamp)
+	entryIDs := l.entryIDs
 	l.mutex.RUnlock()
 
+	if entryIDs {
+		return NewZapLogger(NewEntryIDCore(core)).Named(name)
+	}
 	return NewZapLogger(core).Named(name)
 }
 
+// stringifyEncoder wraps the encoders created by newEncoder with a
+// StringifyEncoder.
+func stringifyEncoder(newEncoder func() zapcore.Encoder) func() zapcore.Encoder {
+	return func() zapcore.Encoder { return fabenc.NewStringifyEncoder(newEncoder()) }
+}
+
 func (l *Logging) Check(e zapcore.Entry, ce *zapcore.CheckedEntry) {
 	l.mutex.RLock()
 	observer := l.observer
@@ -8,14 +8,22 @@ package flogging_test
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/common/flogging/fabenc"
 	"github.com/hyperledger/fabric/common/flogging/mock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
@@ -156,3 +164,500 @@ func TestLoggerCoreCheck(t *testing.T) {
 	assert.NoError(t, err)
 	assert.True(t, logger.Core().Enabled(zapcore.DebugLevel), "debug should now be enabled at debug level")
 }
+
+func TestLoggingStringifyEncodings(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logging, err := flogging.New(flogging.Config{
+		Format:             "json",
+		Writer:             buf,
+		StringifyEncodings: []flogging.Encoding{flogging.JSON},
+	})
+	assert.NoError(t, err)
+
+	logger := logging.Logger("stringify")
+	logger.Infow("message", "int", 1, "bool", true)
+	assert.Contains(t, buf.String(), `"int":"1","bool":"true"`)
+
+	buf.Reset()
+	logging.SetStringifyEncodings(flogging.LOGFMT)
+	logger = logging.Logger("stringify")
+	logger.Infow("message", "int", 1, "bool", true)
+	assert.Contains(t, buf.String(), `"int":1,"bool":true`)
+}
+
+func TestLoggingWithFieldsAcrossEncodings(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logging, err := flogging.New(flogging.Config{
+		Format: "json",
+		Writer: buf,
+	})
+	assert.NoError(t, err)
+
+	// fields added before an encoding is first selected are not lost
+	logger := logging.Logger("with").With("key", "value").With("count", 1)
+	logger.Info("json")
+	assert.Contains(t, buf.String(), `"msg":"json","key":"value","count":1}`)
+
+	buf.Reset()
+	err = logging.SetFormat("%{module} %{message}")
+	assert.NoError(t, err)
+	logger.Info("console")
+	assert.Equal(t, "with console key=value count=1\n", buf.String())
+
+	buf.Reset()
+	err = logging.SetFormat("logfmt")
+	assert.NoError(t, err)
+	derived := logger.With("derived", true)
+	logger.Info("parent")
+	derived.Info("derived")
+	assert.Contains(t, buf.String(), "msg=parent key=value count=1\n")
+	assert.Contains(t, buf.String(), "msg=derived key=value count=1 derived=true\n")
+}
+
+func TestLoggingWithConcurrentWrites(t *testing.T) {
+	logging, err := flogging.New(flogging.Config{
+		Format: "json",
+		Writer: ioutil.Discard,
+	})
+	assert.NoError(t, err)
+	logger := logging.Logger("concurrent").With("key", "value")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				logger.With("goroutine", i).Info("message")
+				logger.Info("message")
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestLoggingMaxFields(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logging, err := flogging.New(flogging.Config{
+		Format:    "json",
+		Writer:    buf,
+		MaxFields: 2,
+	})
+	assert.NoError(t, err)
+
+	logger := logging.Logger("max-fields").With("with", "value")
+	logger.Infow("message", "one", 1, "two", 2, "three", 3)
+	assert.Contains(t, buf.String(), `"with":"value","one":1,"fields_truncated":2}`)
+
+	buf.Reset()
+	logging.SetMaxFields(0)
+	logger = logging.Logger("max-fields")
+	logger.Infow("message", "one", 1, "two", 2, "three", 3)
+	assert.Contains(t, buf.String(), `"one":1,"two":2,"three":3}`)
+	assert.NotContains(t, buf.String(), "fields_truncated")
+}
+
+func TestLoggingMaxWithFields(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logging, err := flogging.New(flogging.Config{
+		Format:        "json",
+		Writer:        buf,
+		MaxWithFields: 2,
+	})
+	assert.NoError(t, err)
+
+	logger := logging.Logger("max-with-fields")
+	for i := 0; i < 5; i++ {
+		logger = logger.With(fmt.Sprintf("key%d", i), i)
+	}
+	assert.Empty(t, buf.String(), "the warning is written with the first entry")
+
+	logger.Info("message")
+	logger.Info("message")
+	assert.Equal(t, 1, strings.Count(buf.String(), "Persistent field limit reached"))
+	assert.Contains(t, buf.String(), `"name":"max-with-fields","caller":`)
+	assert.Contains(t, buf.String(), `"key0":0,"key1":1}`)
+	assert.NotContains(t, buf.String(), "key2")
+
+	// the warning is subject to the levels of the logger
+	buf.Reset()
+	err = logging.ActivateSpec("max-with-fields=error")
+	assert.NoError(t, err)
+	quiet := logging.Logger("max-with-fields")
+	for i := 0; i < 5; i++ {
+		quiet = quiet.With(fmt.Sprintf("key%d", i), i)
+	}
+	quiet.Error("message")
+	assert.NotContains(t, buf.String(), "Persistent field limit reached")
+	assert.Equal(t, 1, strings.Count(buf.String(), `"msg":"message"`))
+}
+
+func TestLoggingPrefixLoggerName(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logging, err := flogging.New(flogging.Config{
+		Format:           "%{level} %{message}",
+		Writer:           buf,
+		PrefixLoggerName: true,
+	})
+	assert.NoError(t, err)
+
+	logging.Logger("peer.gossip").Info("message")
+	assert.Equal(t, "INFO [peer.gossip] message\n", buf.String())
+
+	buf.Reset()
+	logging.SetFormat("json")
+	logging.Logger("peer.gossip").Info("message")
+	assert.Contains(t, buf.String(), `"name":"peer.gossip"`)
+	assert.Contains(t, buf.String(), `"msg":"message"`)
+
+	buf.Reset()
+	logging.SetFormat("%{level} %{message}")
+	logging.SetPrefixLoggerName(false)
+	logging.Logger("peer.gossip").Info("message")
+	assert.Equal(t, "INFO message\n", buf.String())
+}
+
+func TestLoggingShortLevels(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logging, err := flogging.New(flogging.Config{
+		Format:      "%{level} %{message}",
+		Writer:      buf,
+		ShortLevels: true,
+		LogSpec:     "debug",
+	})
+	assert.NoError(t, err)
+
+	logger := logging.Logger("short")
+	logger.Debug("debug")
+	logger.Info("info")
+	logger.Warn("warn")
+	logger.Error("error")
+	assert.Equal(t, "D debug\nI info\nW warn\nE error\n", buf.String())
+
+	buf.Reset()
+	logging.SetFormat("json")
+	logging.Logger("short").Warn("warn")
+	assert.Contains(t, buf.String(), `"level":"warn"`)
+
+	buf.Reset()
+	logging.SetFormat("%{level} %{message}")
+	logging.SetShortLevels(false)
+	logging.Logger("short").Warn("warn")
+	assert.Equal(t, "WARN warn\n", buf.String())
+}
+
+func TestLoggingConsoleColumns(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logging, err := flogging.New(flogging.Config{
+		Format:         "%{level} %{module} %{message}",
+		Writer:         buf,
+		ConsoleColumns: fabenc.Columns{Level: fabenc.AutoWidth, Logger: 6},
+	})
+	assert.NoError(t, err)
+
+	logging.Logger("peer").Info("first")
+	logging.Logger("gossip").Warn("second")
+	assert.Equal(t, "INFO   peer   first\nWARN   gossip second\n", buf.String())
+
+	buf.Reset()
+	logging.SetFormat("json")
+	logging.Logger("peer").Info("json")
+	assert.Contains(t, buf.String(), `"name":"peer"`)
+
+	buf.Reset()
+	logging.SetFormat("%{level} %{module} %{message}")
+	logging.SetConsoleColumns(fabenc.Columns{})
+	logging.Logger("peer").Info("unaligned")
+	assert.Equal(t, "INFO peer unaligned\n", buf.String())
+}
+
+func TestLoggingLevelStyles(t *testing.T) {
+	buf := &bytes.Buffer{}
+	styles := map[zapcore.Level]fabenc.Style{
+		zapcore.ErrorLevel: {Color: fabenc.ColorMagenta, Underline: true},
+	}
+	logging, err := flogging.New(flogging.Config{
+		Format:      "%{color}%{level}%{color:reset} %{message}",
+		Writer:      buf,
+		LevelStyles: styles,
+	})
+	assert.NoError(t, err)
+	styles[zapcore.InfoLevel] = fabenc.Style{Color: fabenc.ColorGreen}
+
+	logger := logging.Logger("styles")
+	logger.Error("error")
+	logger.Info("info")
+	assert.Equal(t, "\x1b[35;4mERROR\x1b[0m error\n\x1b[34mINFO\x1b[0m info\n", buf.String())
+
+	buf.Reset()
+	logging.SetLevelStyles(nil)
+	logging.Logger("styles").Error("error")
+	assert.Equal(t, "\x1b[31mERROR\x1b[0m error\n", buf.String())
+}
+
+func TestLoggingElideRepeatedTime(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logging, err := flogging.New(flogging.Config{
+		Format:            "%{time:2006-01-02 15:04:05} %{message}",
+		Writer:            buf,
+		ElideRepeatedTime: true,
+	})
+	assert.NoError(t, err)
+
+	logger := logging.Logger("elide")
+	for i := 0; i < 100; i++ {
+		logger.Info("message")
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	require.Len(t, lines, 100)
+	blank := strings.Repeat(" ", len("2006-01-02 15:04:05")) + " message"
+	elided := 0
+	for _, line := range lines {
+		if line == blank {
+			elided++
+		}
+	}
+	assert.NotEqual(t, blank, lines[0], "the first timestamp is always written")
+	assert.True(t, elided >= 90, "expected most timestamps to be elided, got %d", elided)
+
+	buf.Reset()
+	logging.SetWriter(buf)
+	logging.Logger("elide").Info("message")
+	assert.NotEqual(t, blank+"\n", buf.String(), "a new writer starts with a timestamp")
+
+	buf.Reset()
+	logging.SetFormat("json")
+	logging.Logger("elide").Info("message")
+	assert.Contains(t, buf.String(), `"ts":`)
+}
+
+func TestLoggingSnapshotRestore(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logging, err := flogging.New(flogging.Config{
+		Format:  "%{level} %{message}",
+		LogSpec: "peer.gossip=debug:warn",
+		Writer:  buf,
+	})
+	assert.NoError(t, err)
+
+	state := logging.Snapshot()
+	assert.Equal(t, "peer.gossip=debug:warn", state.Spec)
+	assert.EqualValues(t, flogging.CONSOLE, state.Encoding)
+
+	err = logging.ActivateSpec("debug")
+	assert.NoError(t, err)
+	err = logging.SetFormat("json")
+	assert.NoError(t, err)
+	assert.EqualValues(t, flogging.JSON, logging.Encoding())
+
+	err = logging.Restore(state)
+	assert.NoError(t, err)
+	assert.Equal(t, state, logging.Snapshot())
+	assert.Equal(t, "peer.gossip=debug:warn", logging.Spec())
+	assert.EqualValues(t, flogging.CONSOLE, logging.Encoding())
+
+	logging.Logger("peer").Info("dropped")
+	logging.Logger("peer.gossip").Debug("message")
+	assert.Equal(t, "DEBUG message\n", buf.String())
+
+	// a console format is restored after switching formats
+	err = logging.SetFormat("%{message}")
+	assert.NoError(t, err)
+	err = logging.Restore(state)
+	assert.NoError(t, err)
+	buf.Reset()
+	logging.Logger("peer").Warn("warning")
+	assert.Equal(t, "WARN warning\n", buf.String())
+
+	err = logging.Restore(flogging.LoggingState{Spec: "=info", Encoding: flogging.JSON})
+	assert.EqualError(t, err, "invalid logging specification '=info': no logger specified in segment '=info'")
+	assert.Equal(t, state, logging.Snapshot())
+}
+
+func TestLoggingSnapshotRestoreConcurrent(t *testing.T) {
+	logging, err := flogging.New(flogging.Config{Writer: ioutil.Discard})
+	assert.NoError(t, err)
+	debug := logging.Snapshot()
+	err = logging.ActivateSpec("warn")
+	assert.NoError(t, err)
+	err = logging.SetFormat("json")
+	assert.NoError(t, err)
+	warn := logging.Snapshot()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				state := debug
+				if (i+j)%2 == 0 {
+					state = warn
+				}
+				assert.NoError(t, logging.Restore(state))
+				logging.Logger("concurrent").Warn("message")
+				snapshot := logging.Snapshot()
+				assert.Contains(t, []string{debug.Spec, warn.Spec}, snapshot.Spec)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestLoggingDeterministicJSONFormat(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logging, err := flogging.New(flogging.Config{
+		Format: "deterministic-json",
+		Writer: buf,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, flogging.Encoding(flogging.DETERMINISTIC_JSON), logging.Encoding())
+
+	logging.Logger("sorted").With("zulu", 1).Infow("message", "bravo", 2, "alpha", 3)
+	assert.Contains(t, buf.String(), `"msg":"message","alpha":3,"bravo":2,"zulu":1}`)
+}
+
+func TestLoggingECSFormat(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logging, err := flogging.New(flogging.Config{
+		Format: "ecs",
+		Writer: buf,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, flogging.Encoding(flogging.ECS), logging.Encoding())
+
+	logging.Logger("peer.gossip").With("pod", "peer0").Infow("message", "channel", "testchannel")
+
+	var doc map[string]interface{}
+	err = json.Unmarshal(buf.Bytes(), &doc)
+	assert.NoError(t, err)
+	assert.Equal(t, "message", doc["message"])
+	assert.Equal(t, "testchannel", doc["channel"])
+	assert.Equal(t, map[string]interface{}{"version": "1.6.0"}, doc["ecs"])
+	assert.Equal(t, map[string]interface{}{"pod": map[string]interface{}{"name": "peer0"}}, doc["kubernetes"])
+	assert.Contains(t, doc, "@timestamp")
+
+	log := doc["log"].(map[string]interface{})
+	assert.Equal(t, "info", log["level"])
+	assert.Equal(t, "peer.gossip", log["logger"])
+	assert.Contains(t, log, "origin")
+}
+
+func TestLoggingCBORFormat(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logging, err := flogging.New(flogging.Config{
+		Format: "cbor",
+		Writer: buf,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, flogging.Encoding(flogging.CBOR), logging.Encoding())
+
+	logging.Logger("cbor").With("key", "value").Info("message")
+	assert.Equal(t, byte(0xa6), buf.Bytes()[0], "expected a map with six entries")
+	assert.Contains(t, buf.String(), "\x63keyevalue")
+}
+
+func TestLoggingMsgpackFormat(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logging, err := flogging.New(flogging.Config{
+		Format: "msgpack",
+		Writer: buf,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, flogging.Encoding(flogging.MSGPACK), logging.Encoding())
+
+	logging.Logger("msgpack").With("key", "value").Info("message")
+	assert.Equal(t, byte(0x86), buf.Bytes()[0], "expected a map with six entries")
+	assert.Contains(t, buf.String(), "\xa3key\xa5value")
+}
+
+func TestLoggingDefaultFields(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logging, err := flogging.New(flogging.Config{Format: "%{module} %{message}", Writer: buf})
+	assert.NoError(t, err)
+
+	logger := logging.Logger("gossip.state")
+	logging.SetDefaultFields("gossip", zap.String("component", "gossip"))
+	logger.Info("message")
+	logger.Infow("call site", "component", "caller")
+	logging.Logger("peer").Info("message")
+	assert.Equal(t, "gossip.state message component=gossip\n"+
+		"gossip.state call site component=caller\n"+
+		"peer message\n", buf.String())
+}
+
+func TestLoggingSpecHash(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logging, err := flogging.New(flogging.Config{Format: "json", Writer: buf, SpecHash: true})
+	assert.NoError(t, err)
+
+	logger := logging.Logger("spechash")
+	logger.Info("message")
+	assert.Contains(t, buf.String(), `"spec_hash":"`+logging.SpecHash()+`"`)
+
+	buf.Reset()
+	previous := logging.SpecHash()
+	assert.NoError(t, logging.ActivateSpec("debug"))
+	logger.Info("message")
+	assert.NotEqual(t, previous, logging.SpecHash())
+	assert.Contains(t, buf.String(), `"spec_hash":"`+logging.SpecHash()+`"`)
+}
+
+func TestLoggingTimePrecision(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logging, err := flogging.New(flogging.Config{
+		Format:        "%{time:05.000000000} %{message}",
+		Writer:        buf,
+		TimePrecision: time.Millisecond,
+	})
+	assert.NoError(t, err)
+
+	logging.Logger("precision").Info("message")
+	assert.Regexp(t, `^\d\d\.\d{3}000000 message\n$`, buf.String())
+
+	buf.Reset()
+	logging.SetTimePrecision(time.Second)
+	logging.Logger("precision").Info("message")
+	assert.Regexp(t, `^\d\d\.000000000 message\n$`, buf.String())
+
+	buf.Reset()
+	logging.SetFormat("json")
+	logging.Logger("precision").Info("message")
+	var entry map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	ts, ok := entry["ts"].(float64)
+	assert.True(t, ok, "ts should be a number")
+	assert.Equal(t, float64(int64(ts)), ts, "ts should be whole seconds")
+
+	buf.Reset()
+	logging.SetFormat("ecs")
+	logging.Logger("precision").Info("message")
+	assert.Regexp(t, `"@timestamp":"[^"]+\.000Z"`, buf.String())
+}
+
+func TestLoggingUptime(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logging, err := flogging.New(flogging.Config{Format: "json", Writer: buf, Uptime: true})
+	assert.NoError(t, err)
+
+	logger := logging.Logger("uptime")
+	var previous float64
+	for i := 0; i < 3; i++ {
+		buf.Reset()
+		time.Sleep(time.Millisecond)
+		logger.Info("message")
+
+		var entry map[string]interface{}
+		err := json.Unmarshal(buf.Bytes(), &entry)
+		assert.NoError(t, err)
+		uptime, ok := entry["uptime"].(float64)
+		assert.True(t, ok, "expected a numeric uptime in %s", buf.String())
+		assert.True(t, uptime > previous, "uptime %f should exceed %f", uptime, previous)
+		previous = uptime
+	}
+
+	buf.Reset()
+	logging.SetUptime(false)
+	logging.Logger("no-uptime").Info("message")
+	assert.NotContains(t, buf.String(), `"uptime":`)
+}
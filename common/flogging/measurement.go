@@ -0,0 +1,100 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// MeasurementLogger is the name of the logger used for measurement entries.
+// Measurement entries are written by loggers with this name or with names
+// that end in "." followed by this name.
+const MeasurementLogger = "measurement"
+
+// A Measurement is a metric sample recorded in a log entry.
+type Measurement struct {
+	Metric string
+	Value  float64
+	Unit   string
+	Labels map[string]string
+}
+
+// Fields returns the fields used to record the measurement in a log entry.
+func (m Measurement) Fields() []zapcore.Field {
+	fields := []zapcore.Field{
+		zap.String("metric", m.Metric),
+		zap.Float64("value", m.Value),
+		zap.String("unit", m.Unit),
+	}
+	if len(m.Labels) > 0 {
+		fields = append(fields, SortedMap("labels", m.Labels))
+	}
+	return fields
+}
+
+// LogMeasurement writes the measurement as an info entry to a measurement
+// logger derived from logger.
+func LogMeasurement(logger *zap.Logger, m Measurement) {
+	logger.Named(MeasurementLogger).Info("measurement", m.Fields()...)
+}
+
+// IsMeasurementLogger returns true when entries written by the named logger
+// are measurement entries.
+func IsMeasurementLogger(name string) bool {
+	return name == MeasurementLogger || strings.HasSuffix(name, "."+MeasurementLogger)
+}
+
+// A MeasurementObserver is an Observer that forwards the measurements
+// recorded by written measurement entries to a function, such as one that
+// records them in a metrics provider. Only entries that are written are
+// observed, so the measurement loggers must be enabled by the logging spec.
+// The entries continue to be written to the log output.
+type MeasurementObserver struct {
+	forward func(Measurement)
+}
+
+// NewMeasurementObserver creates a MeasurementObserver that calls forward
+// with every measurement entry that is written.
+func NewMeasurementObserver(forward func(Measurement)) *MeasurementObserver {
+	return &MeasurementObserver{forward: forward}
+}
+
+// Check is a no-op for the MeasurementObserver.
+func (m *MeasurementObserver) Check(e zapcore.Entry, ce *zapcore.CheckedEntry) {}
+
+// WriteEntry forwards the measurement recorded by measurement entries.
+// Entries that are missing the metric name or value are ignored.
+func (m *MeasurementObserver) WriteEntry(e zapcore.Entry, fields []zapcore.Field) {
+	if !IsMeasurementLogger(e.LoggerName) {
+		return
+	}
+
+	enc := zapcore.NewMapObjectEncoder()
+	for i := range fields {
+		fields[i].AddTo(enc)
+	}
+
+	metric, _ := enc.Fields["metric"].(string)
+	value, ok := enc.Fields["value"].(float64)
+	if metric == "" || !ok {
+		return
+	}
+	measurement := Measurement{Metric: metric, Value: value}
+	measurement.Unit, _ = enc.Fields["unit"].(string)
+	if labels, ok := enc.Fields["labels"].(map[string]interface{}); ok {
+		measurement.Labels = map[string]string{}
+		for k, v := range labels {
+			if s, ok := v.(string); ok {
+				measurement.Labels[k] = s
+			}
+		}
+	}
+	m.forward(measurement)
+}
@@ -0,0 +1,62 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestMeasurementObserver(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logging, err := flogging.New(flogging.Config{Format: "json", Writer: buf})
+	require.NoError(t, err)
+
+	var measurements []flogging.Measurement
+	logging.SetObserver(flogging.NewMeasurementObserver(func(m flogging.Measurement) {
+		measurements = append(measurements, m)
+	}))
+
+	logger := logging.ZapLogger("peer.endorser")
+	flogging.LogMeasurement(logger, flogging.Measurement{
+		Metric: "proposal_duration",
+		Value:  0.25,
+		Unit:   "seconds",
+		Labels: map[string]string{"channel": "testchannel", "chaincode": "mycc"},
+	})
+	flogging.LogMeasurement(logging.ZapLogger("gossip"), flogging.Measurement{Metric: "peers", Value: 3})
+	logger.Info("not a measurement", zap.String("metric", "ignored"), zap.Float64("value", 1))
+	logging.ZapLogger("measurement").Info("incomplete", zap.String("metric", "missing_value"))
+
+	assert.Equal(t, []flogging.Measurement{
+		{Metric: "proposal_duration", Value: 0.25, Unit: "seconds", Labels: map[string]string{"channel": "testchannel", "chaincode": "mycc"}},
+		{Metric: "peers", Value: 3},
+	}, measurements)
+
+	// measurement entries are also logged
+	assert.Contains(t, buf.String(), `"name":"peer.endorser.measurement"`)
+	assert.Contains(t, buf.String(), `"metric":"proposal_duration","value":0.25,"unit":"seconds","labels":{"chaincode":"mycc","channel":"testchannel"}`)
+}
+
+func TestIsMeasurementLogger(t *testing.T) {
+	assert.True(t, flogging.IsMeasurementLogger("measurement"))
+	assert.True(t, flogging.IsMeasurementLogger("peer.measurement"))
+	assert.False(t, flogging.IsMeasurementLogger("peer.measurements"))
+	assert.False(t, flogging.IsMeasurementLogger("peermeasurement"))
+	assert.False(t, flogging.IsMeasurementLogger("measurement.peer"))
+}
+
+func TestMeasurementFields(t *testing.T) {
+	fields := flogging.Measurement{Metric: "m", Value: 1.5, Unit: "bytes"}.Fields()
+	assert.Equal(t, []zapcore.Field{zap.String("metric", "m"), zap.Float64("value", 1.5), zap.String("unit", "bytes")}, fields)
+}
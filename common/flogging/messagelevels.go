@@ -0,0 +1,86 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap/zapcore"
+)
+
+// MessageLevels reclassifies entries by the prefix of their message. It is
+// intended for legacy code that writes messages such as "WARNING: ..." at
+// InfoLevel. When several prefixes match a message, the longest prefix takes
+// precedence.
+//
+// The logger decides whether to panic or exit the process from the level the
+// entry was logged at, so entries are never reclassified to or from DPanicLevel
+// and above.
+//
+// Its Transform method is an EntryTransformer for use with Core.Transformers.
+// Transformers are applied after the core has determined that an entry is
+// enabled so the reclassified level is not used to filter the entry; set
+// Core.MessageLevels instead to filter entries by their reclassified level.
+type MessageLevels struct {
+	prefixes []string // sorted from the longest to the shortest
+	levels   map[string]zapcore.Level
+}
+
+// NewMessageLevels creates a MessageLevels that reclassifies entries with
+// messages that start with a prefix of rules to the level of the prefix. An
+// error is returned when a rule reclassifies entries to DPanicLevel or above.
+func NewMessageLevels(rules map[string]zapcore.Level) (*MessageLevels, error) {
+	m := &MessageLevels{levels: map[string]zapcore.Level{}}
+	for prefix, level := range rules {
+		if level >= zapcore.DPanicLevel {
+			return nil, errors.Errorf("invalid level %s for message prefix '%s': entries cannot be reclassified to %s or above", level, prefix, zapcore.DPanicLevel)
+		}
+		m.levels[prefix] = level
+		m.prefixes = append(m.prefixes, prefix)
+	}
+	sort.Slice(m.prefixes, func(i, j int) bool {
+		if len(m.prefixes[i]) != len(m.prefixes[j]) {
+			return len(m.prefixes[i]) > len(m.prefixes[j])
+		}
+		return m.prefixes[i] < m.prefixes[j]
+	})
+	return m, nil
+}
+
+// Level returns the level of the entry after reclassification. The level of
+// the entry is returned when its message does not match a prefix or when the
+// entry is at DPanicLevel or above.
+func (m *MessageLevels) Level(e zapcore.Entry) zapcore.Level {
+	if e.Level >= zapcore.DPanicLevel {
+		return e.Level
+	}
+	for _, prefix := range m.prefixes {
+		if strings.HasPrefix(e.Message, prefix) {
+			return m.levels[prefix]
+		}
+	}
+	return e.Level
+}
+
+// enables returns true when the enabler enables a level that entries are
+// reclassified to.
+func (m *MessageLevels) enables(enabler zapcore.LevelEnabler) bool {
+	for _, level := range m.levels {
+		if enabler.Enabled(level) {
+			return true
+		}
+	}
+	return false
+}
+
+// Transform sets the level of the entry to its reclassified level.
+func (m *MessageLevels) Transform(e zapcore.Entry, fields []zapcore.Field) (zapcore.Entry, []zapcore.Field) {
+	e.Level = m.Level(e)
+	return e, fields
+}
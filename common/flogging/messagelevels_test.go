@@ -0,0 +1,129 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestMessageLevels(t *testing.T) {
+	levels, err := flogging.NewMessageLevels(map[string]zapcore.Level{
+		"WARNING:":       zapcore.WarnLevel,
+		"WARNING: FATAL": zapcore.ErrorLevel,
+		"DEBUG:":         zapcore.DebugLevel,
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		message  string
+		level    zapcore.Level
+		expected zapcore.Level
+	}{
+		{message: "WARNING: disk space low", level: zapcore.InfoLevel, expected: zapcore.WarnLevel},
+		{message: "WARNING: FATAL condition", level: zapcore.InfoLevel, expected: zapcore.ErrorLevel},
+		{message: "DEBUG: details", level: zapcore.InfoLevel, expected: zapcore.DebugLevel},
+		{message: "warning: lower case", level: zapcore.InfoLevel, expected: zapcore.InfoLevel},
+		{message: "no prefix WARNING:", level: zapcore.ErrorLevel, expected: zapcore.ErrorLevel},
+		{message: "WARNING: panicking", level: zapcore.DPanicLevel, expected: zapcore.DPanicLevel},
+		{message: "DEBUG: exiting", level: zapcore.FatalLevel, expected: zapcore.FatalLevel},
+	}
+	for _, tt := range tests {
+		e := zapcore.Entry{Level: tt.level, Message: tt.message}
+		assert.Equal(t, tt.expected, levels.Level(e), tt.message)
+
+		transformed, fields := levels.Transform(e, []zapcore.Field{zap.String("key", "value")})
+		assert.Equal(t, tt.expected, transformed.Level, tt.message)
+		assert.Equal(t, tt.message, transformed.Message)
+		assert.Equal(t, []zapcore.Field{zap.String("key", "value")}, fields)
+	}
+}
+
+func TestNewMessageLevelsRejectsTerminalLevels(t *testing.T) {
+	for _, level := range []zapcore.Level{zapcore.DPanicLevel, zapcore.PanicLevel, zapcore.FatalLevel} {
+		_, err := flogging.NewMessageLevels(map[string]zapcore.Level{"FATAL:": level})
+		assert.EqualError(t, err, fmt.Sprintf("invalid level %s for message prefix 'FATAL:': entries cannot be reclassified to dpanic or above", level))
+	}
+}
+
+func TestMessageLevelsTransformer(t *testing.T) {
+	output := &sw{}
+	core := newTestConsoleCore(t, "info", output)
+	levels, err := flogging.NewMessageLevels(map[string]zapcore.Level{"WARNING:": zapcore.WarnLevel})
+	require.NoError(t, err)
+	core.Transformers = []flogging.EntryTransformer{levels.Transform}
+
+	zap.New(core).Named("legacy").Info("WARNING: reclassified")
+	assert.Equal(t, "[legacy] WARN WARNING: reclassified\n", output.String())
+}
+
+func TestCoreMessageLevels(t *testing.T) {
+	output := &sw{}
+	core := newTestConsoleCore(t, "warn", output)
+	assert.False(t, core.Enabled(zapcore.InfoLevel))
+	levels, err := flogging.NewMessageLevels(map[string]zapcore.Level{
+		"WARNING:": zapcore.WarnLevel,
+		"DEBUG:":   zapcore.DebugLevel,
+	})
+	require.NoError(t, err)
+	core.MessageLevels = levels
+	assert.True(t, core.Enabled(zapcore.InfoLevel), "info entries may be upgraded")
+
+	logger := zap.New(core).Named("legacy").With(zap.String("key", "value"))
+	logger.Info("WARNING: upgraded")
+	logger.Info("not upgraded")
+	logger.Error("DEBUG: downgraded")
+	logger.Error("error")
+	assert.Equal(t, "[legacy] WARN WARNING: upgraded key=value\n[legacy] ERROR error key=value\n", output.String())
+}
+
+func TestCoreMessageLevelsFatal(t *testing.T) {
+	output := &sw{}
+	core := newTestConsoleCore(t, "info", output)
+	levels, err := flogging.NewMessageLevels(map[string]zapcore.Level{"WARNING:": zapcore.WarnLevel})
+	require.NoError(t, err)
+	core.MessageLevels = levels
+
+	type exitCode int
+	var fatal []string
+	core.OnFatal = func(e zapcore.Entry) {
+		fatal = append(fatal, e.Message)
+		panic(exitCode(1))
+	}
+	logger := zap.New(core).Named("legacy")
+
+	// fatal entries are not downgraded as the logger still exits
+	assert.PanicsWithValue(t, exitCode(1), func() { logger.Fatal("WARNING: fatal") })
+	assert.Equal(t, []string{"WARNING: fatal"}, fatal)
+	assert.Equal(t, "[legacy] FATAL WARNING: fatal\n", output.String())
+
+	// entries are not upgraded to fatal as the logger would not exit
+	output.Reset()
+	logger.Info("WARNING: upgraded")
+	assert.Equal(t, []string{"WARNING: fatal"}, fatal)
+	assert.Equal(t, "[legacy] WARN WARNING: upgraded\n", output.String())
+}
+
+func TestCoreMessageLevelsTee(t *testing.T) {
+	output := &sw{}
+	core := newTestConsoleCore(t, "info", output)
+	levels, err := flogging.NewMessageLevels(map[string]zapcore.Level{"WARNING:": zapcore.WarnLevel})
+	require.NoError(t, err)
+	core.MessageLevels = levels
+
+	other := &sw{}
+	logger := zap.New(zapcore.NewTee(newTestConsoleCore(t, "info", other), core))
+	logger.Info("WARNING: upgraded")
+	assert.Equal(t, "[] INFO WARNING: upgraded\n", other.String())
+	assert.Equal(t, "[] WARN WARNING: upgraded\n", output.String())
+}
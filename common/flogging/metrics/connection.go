@@ -0,0 +1,134 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/common/metrics"
+)
+
+var (
+	ReconnectsOpts = metrics.CounterOpts{
+		Namespace:    "logging",
+		Subsystem:    "sink",
+		Name:         "reconnects",
+		Help:         "Number of times a network log sink has reconnected",
+		LabelNames:   []string{"sink"},
+		StatsdFormat: "%{#fqname}.%{sink}",
+	}
+
+	ConnectedOpts = metrics.GaugeOpts{
+		Namespace:    "logging",
+		Subsystem:    "sink",
+		Name:         "connected",
+		Help:         "Indicates whether a network log sink is connected (1) or not (0)",
+		LabelNames:   []string{"sink"},
+		StatsdFormat: "%{#fqname}.%{sink}",
+	}
+
+	DisconnectedSecondsOpts = metrics.GaugeOpts{
+		Namespace:    "logging",
+		Subsystem:    "sink",
+		Name:         "disconnected_seconds",
+		Help:         "Number of seconds a network log sink has been disconnected",
+		LabelNames:   []string{"sink"},
+		StatsdFormat: "%{#fqname}.%{sink}",
+	}
+)
+
+// ConnectionMetrics are the metrics reported by network log sinks.
+type ConnectionMetrics struct {
+	Reconnects metrics.Counter
+	Connected  metrics.Gauge
+
+	// DisconnectedSeconds is set when a sink reports a connection state
+	// change. Sinks only report state changes when they write, so the gauge
+	// holds the duration as of the most recent failed write and is not
+	// updated while nothing is logged.
+	DisconnectedSeconds metrics.Gauge
+}
+
+func NewConnectionMetrics(provider metrics.Provider) *ConnectionMetrics {
+	return &ConnectionMetrics{
+		Reconnects:          provider.NewCounter(ReconnectsOpts),
+		Connected:           provider.NewGauge(ConnectedOpts),
+		DisconnectedSeconds: provider.NewGauge(DisconnectedSecondsOpts),
+	}
+}
+
+// A ConnectionTracker records the connection state transitions of a single
+// network log sink. A nil ConnectionTracker is valid and records nothing.
+type ConnectionTracker struct {
+	sink    string
+	metrics *ConnectionMetrics
+	now     func() time.Time
+
+	mutex          sync.Mutex
+	connected      bool
+	everConnected  bool
+	disconnectedAt time.Time
+}
+
+// NewConnectionTracker creates a ConnectionTracker that reports the state of
+// the named sink.
+func NewConnectionTracker(sink string, m *ConnectionMetrics) *ConnectionTracker {
+	return &ConnectionTracker{
+		sink:    sink,
+		metrics: m,
+		now:     time.Now,
+	}
+}
+
+// SetClock sets the function used to obtain the current time.
+func (c *ConnectionTracker) SetClock(now func() time.Time) {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	c.now = now
+	c.mutex.Unlock()
+}
+
+// Connected records that a connection has been established. Connections
+// established after the first are counted as reconnects.
+func (c *ConnectionTracker) Connected() {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.everConnected && !c.connected {
+		c.metrics.Reconnects.With("sink", c.sink).Add(1)
+	}
+	c.connected = true
+	c.everConnected = true
+	c.metrics.Connected.With("sink", c.sink).Set(1)
+	c.metrics.DisconnectedSeconds.With("sink", c.sink).Set(0)
+}
+
+// Disconnected records that the connection has been lost or that an attempt
+// to connect has failed. The disconnected duration is measured from the first
+// call after the connection was lost and is only updated when Disconnected is
+// called.
+func (c *ConnectionTracker) Disconnected() {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := c.now()
+	if c.connected || c.disconnectedAt.IsZero() {
+		c.disconnectedAt = now
+	}
+	c.connected = false
+	c.metrics.Connected.With("sink", c.sink).Set(0)
+	c.metrics.DisconnectedSeconds.With("sink", c.sink).Set(now.Sub(c.disconnectedAt).Seconds())
+}
@@ -0,0 +1,77 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package metrics_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging/metrics"
+	commonmetrics "github.com/hyperledger/fabric/common/metrics"
+	"github.com/hyperledger/fabric/common/metrics/metricsfakes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewConnectionMetrics(t *testing.T) {
+	provider := &metricsfakes.Provider{}
+	counter := &metricsfakes.Counter{}
+	connected := &metricsfakes.Gauge{}
+	disconnected := &metricsfakes.Gauge{}
+	provider.NewCounterReturns(counter)
+	provider.NewGaugeStub = func(o commonmetrics.GaugeOpts) commonmetrics.Gauge {
+		if o.Name == "connected" {
+			return connected
+		}
+		return disconnected
+	}
+
+	m := metrics.NewConnectionMetrics(provider)
+	assert.Equal(t, &metrics.ConnectionMetrics{
+		Reconnects:          counter,
+		Connected:           connected,
+		DisconnectedSeconds: disconnected,
+	}, m)
+	assert.Equal(t, metrics.ReconnectsOpts, provider.NewCounterArgsForCall(0))
+}
+
+func TestConnectionTracker(t *testing.T) {
+	reconnects := &metricsfakes.Counter{}
+	reconnects.WithReturns(reconnects)
+	connected := &metricsfakes.Gauge{}
+	connected.WithReturns(connected)
+	disconnected := &metricsfakes.Gauge{}
+	disconnected.WithReturns(disconnected)
+
+	now := time.Unix(1000, 0)
+	tracker := metrics.NewConnectionTracker("sink-name", &metrics.ConnectionMetrics{
+		Reconnects:          reconnects,
+		Connected:           connected,
+		DisconnectedSeconds: disconnected,
+	})
+	tracker.SetClock(func() time.Time { return now })
+
+	tracker.Connected()
+	assert.Equal(t, 0, reconnects.AddCallCount())
+	assert.Equal(t, []string{"sink", "sink-name"}, connected.WithArgsForCall(0))
+	assert.Equal(t, float64(1), connected.SetArgsForCall(0))
+
+	tracker.Disconnected()
+	now = now.Add(5 * time.Second)
+	tracker.Disconnected()
+	assert.Equal(t, float64(0), connected.SetArgsForCall(2))
+	assert.Equal(t, float64(5), disconnected.SetArgsForCall(2))
+
+	tracker.Connected()
+	assert.Equal(t, 1, reconnects.AddCallCount())
+	assert.Equal(t, float64(0), disconnected.SetArgsForCall(3))
+
+	// a nil tracker records nothing
+	var nilTracker *metrics.ConnectionTracker
+	nilTracker.SetClock(time.Now)
+	nilTracker.Connected()
+	nilTracker.Disconnected()
+}
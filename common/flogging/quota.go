@@ -0,0 +1,133 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// A QuotaBreach describes an entry level that exceeded its quota. Only the
+// entries needed to detect a breach are retained so Count is Limit+1.
+type QuotaBreach struct {
+	Level  zapcore.Level
+	Count  int
+	Limit  int
+	Window time.Duration
+}
+
+// A QuotaObserver is an Observer that counts the entries written at each
+// level over a sliding window and calls a function when the count for a level
+// exceeds its limit. After a breach is reported, further breaches of the same
+// level are not reported until the cooldown has elapsed. At most limit+1
+// timestamps are retained for each level so memory use does not grow with the
+// volume of entries.
+type QuotaObserver struct {
+	window   time.Duration
+	cooldown time.Duration
+	limits   map[zapcore.Level]int
+	onBreach func(QuotaBreach)
+
+	mutex     sync.Mutex
+	now       func() time.Time
+	written   map[zapcore.Level]*timeRing
+	lastAlert map[zapcore.Level]time.Time
+}
+
+// NewQuotaObserver creates a QuotaObserver that calls onBreach when more than
+// limits[level] entries are written at a level within the window. Levels that
+// are not in limits are not counted. A cooldown of zero or less uses the
+// window as the cooldown.
+func NewQuotaObserver(window, cooldown time.Duration, limits map[zapcore.Level]int, onBreach func(QuotaBreach)) *QuotaObserver {
+	if cooldown <= 0 {
+		cooldown = window
+	}
+	l := map[zapcore.Level]int{}
+	written := map[zapcore.Level]*timeRing{}
+	for level, limit := range limits {
+		if limit < 0 {
+			limit = 0
+		}
+		l[level] = limit
+		written[level] = &timeRing{times: make([]time.Time, limit+1)}
+	}
+	return &QuotaObserver{
+		window:    window,
+		cooldown:  cooldown,
+		limits:    l,
+		onBreach:  onBreach,
+		now:       time.Now,
+		written:   written,
+		lastAlert: map[zapcore.Level]time.Time{},
+	}
+}
+
+// SetClock sets the function used to obtain the current time.
+func (q *QuotaObserver) SetClock(now func() time.Time) {
+	q.mutex.Lock()
+	q.now = now
+	q.mutex.Unlock()
+}
+
+// Check is a no-op for the QuotaObserver.
+func (q *QuotaObserver) Check(e zapcore.Entry, ce *zapcore.CheckedEntry) {}
+
+// WriteEntry counts the entry and reports a breach when the count for its
+// level exceeds the limit.
+func (q *QuotaObserver) WriteEntry(e zapcore.Entry, fields []zapcore.Field) {
+	limit, ok := q.limits[e.Level]
+	if !ok {
+		return
+	}
+
+	q.mutex.Lock()
+	now := q.now()
+	written := q.written[e.Level]
+	written.expire(now.Add(-q.window))
+	written.add(now)
+
+	count := written.len
+	breached := count > limit
+	if last, ok := q.lastAlert[e.Level]; breached && ok && now.Sub(last) < q.cooldown {
+		breached = false
+	}
+	if breached {
+		q.lastAlert[e.Level] = now
+	}
+	q.mutex.Unlock()
+
+	if breached && q.onBreach != nil {
+		q.onBreach(QuotaBreach{Level: e.Level, Count: count, Limit: limit, Window: q.window})
+	}
+}
+
+// A timeRing holds the most recent times up to its capacity.
+type timeRing struct {
+	times []time.Time
+	start int
+	len   int
+}
+
+// add adds a time, replacing the oldest time when the ring is full.
+func (r *timeRing) add(t time.Time) {
+	if r.len == len(r.times) {
+		r.start = (r.start + 1) % len(r.times)
+		r.len--
+	}
+	r.times[(r.start+r.len)%len(r.times)] = t
+	r.len++
+}
+
+// expire removes the times that are not after the cutoff.
+func (r *timeRing) expire(cutoff time.Time) {
+	for r.len > 0 && !r.times[r.start].After(cutoff) {
+		r.start = (r.start + 1) % len(r.times)
+		r.len--
+	}
+}
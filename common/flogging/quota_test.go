@@ -0,0 +1,104 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestQuotaObserver(t *testing.T) {
+	var breaches []flogging.QuotaBreach
+	observer := flogging.NewQuotaObserver(time.Minute, 0, map[zapcore.Level]int{zapcore.ErrorLevel: 3}, func(b flogging.QuotaBreach) {
+		breaches = append(breaches, b)
+	})
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	observer.SetClock(clock.Now)
+
+	write := func(level zapcore.Level, n int, interval time.Duration) {
+		for i := 0; i < n; i++ {
+			observer.WriteEntry(zapcore.Entry{Level: level}, nil)
+			clock.Advance(interval)
+		}
+	}
+
+	// levels without a limit are not counted
+	write(zapcore.WarnLevel, 100, time.Millisecond)
+	assert.Empty(t, breaches)
+
+	// errors within the limit are not reported
+	write(zapcore.ErrorLevel, 3, time.Second)
+	assert.Empty(t, breaches)
+
+	// the first breach is reported and the rest of the storm is suppressed
+	write(zapcore.ErrorLevel, 20, time.Second)
+	assert.Equal(t, []flogging.QuotaBreach{{Level: zapcore.ErrorLevel, Count: 4, Limit: 3, Window: time.Minute}}, breaches)
+
+	// a sustained breach is reported once per cooldown
+	write(zapcore.ErrorLevel, 60, time.Second)
+	assert.Equal(t, []flogging.QuotaBreach{
+		{Level: zapcore.ErrorLevel, Count: 4, Limit: 3, Window: time.Minute},
+		{Level: zapcore.ErrorLevel, Count: 4, Limit: 3, Window: time.Minute},
+	}, breaches)
+
+	// the window slides so a low rate does not breach after the cooldown
+	clock.Advance(2 * time.Minute)
+	write(zapcore.ErrorLevel, 10, 30*time.Second)
+	assert.Len(t, breaches, 2)
+
+	// a breach is detected after a burst with a zero limit
+	zero := flogging.NewQuotaObserver(time.Minute, 0, map[zapcore.Level]int{zapcore.ErrorLevel: 0}, func(b flogging.QuotaBreach) {
+		breaches = append(breaches, b)
+	})
+	zero.SetClock(clock.Now)
+	zero.WriteEntry(zapcore.Entry{Level: zapcore.ErrorLevel}, nil)
+	assert.Equal(t, flogging.QuotaBreach{Level: zapcore.ErrorLevel, Count: 1, Limit: 0, Window: time.Minute}, breaches[2])
+}
+
+func TestQuotaObserverCooldown(t *testing.T) {
+	var breaches int
+	observer := flogging.NewQuotaObserver(10*time.Second, 30*time.Second, map[zapcore.Level]int{zapcore.ErrorLevel: 1}, func(flogging.QuotaBreach) {
+		breaches++
+	})
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	observer.SetClock(clock.Now)
+
+	for i := 0; i < 60; i++ {
+		observer.WriteEntry(zapcore.Entry{Level: zapcore.ErrorLevel}, nil)
+		observer.WriteEntry(zapcore.Entry{Level: zapcore.ErrorLevel}, nil)
+		clock.Advance(time.Second)
+	}
+	assert.Equal(t, 2, breaches)
+}
+
+func TestQuotaObserverConcurrent(t *testing.T) {
+	var mutex sync.Mutex
+	var breaches int
+	observer := flogging.NewQuotaObserver(time.Hour, 0, map[zapcore.Level]int{zapcore.ErrorLevel: 10}, func(flogging.QuotaBreach) {
+		mutex.Lock()
+		breaches++
+		mutex.Unlock()
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				observer.WriteEntry(zapcore.Entry{Level: zapcore.ErrorLevel}, nil)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, breaches)
+}
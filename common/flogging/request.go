@@ -0,0 +1,82 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// LatencyKey is the field key used to record the time taken to serve a
+// request.
+const LatencyKey = "latency"
+
+// A Request pairs the entries logged when a request is received and when its
+// response is sent. Both entries carry the same correlation id and the
+// response entry records the latency of the request.
+type Request struct {
+	ctx    context.Context
+	id     string
+	logger *zap.Logger
+	start  time.Time
+}
+
+// StartRequest logs the start of a request at InfoLevel and returns the
+// Request used to log its response. The correlation id carried by ctx is
+// used when present; otherwise a random id is generated.
+func StartRequest(ctx context.Context, logger *zap.Logger, msg string, fields ...zap.Field) *Request {
+	id, ok := CorrelationID(ctx)
+	if !ok {
+		id = newCorrelationID()
+	}
+
+	r := &Request{
+		ctx:    NewCorrelationContext(ctx, id),
+		id:     id,
+		logger: logger.With(zap.String(CorrelationIDKey, id)),
+		start:  time.Now(),
+	}
+	r.logger.Info(msg, fields...)
+	return r
+}
+
+// ID returns the correlation id of the request.
+func (r *Request) ID() string {
+	return r.id
+}
+
+// Context returns a copy of the context provided to StartRequest that carries
+// the correlation id of the request.
+func (r *Request) Context() context.Context {
+	return r.ctx
+}
+
+// End logs the response to the request at InfoLevel with the latency of the
+// request.
+func (r *Request) End(msg string, fields ...zap.Field) {
+	fields = append(fields[:len(fields):len(fields)], zap.Duration(LatencyKey, time.Since(r.start)))
+	r.logger.Info(msg, fields...)
+}
+
+// EndWithError logs a failed response to the request at ErrorLevel with the
+// error and the latency of the request.
+func (r *Request) EndWithError(msg string, err error, fields ...zap.Field) {
+	fields = append(fields[:len(fields):len(fields)], zap.Error(err), zap.Duration(LatencyKey, time.Since(r.start)))
+	r.logger.Error(msg, fields...)
+}
+
+func newCorrelationID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,72 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRequest(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger := zap.New(core)
+
+	r := flogging.StartRequest(context.Background(), logger, "request", zap.String("method", "Deliver"))
+	assert.Len(t, r.ID(), 32)
+	id, ok := flogging.CorrelationID(r.Context())
+	assert.True(t, ok)
+	assert.Equal(t, r.ID(), id)
+
+	time.Sleep(10 * time.Millisecond)
+	r.End("response", zap.Int("status", 200))
+
+	entries := logs.AllUntimed()
+	require.Len(t, entries, 2)
+	request, response := entries[0].ContextMap(), entries[1].ContextMap()
+	assert.Equal(t, "request", entries[0].Message)
+	assert.Equal(t, map[string]interface{}{"correlation_id": r.ID(), "method": "Deliver"}, request)
+	assert.Equal(t, "response", entries[1].Message)
+	assert.Equal(t, zapcore.InfoLevel, entries[1].Level)
+	assert.Equal(t, r.ID(), response["correlation_id"])
+	assert.Equal(t, int64(200), response["status"])
+	latency, ok := response["latency"].(time.Duration)
+	require.True(t, ok)
+	assert.True(t, latency >= 10*time.Millisecond, "latency %s is too short", latency)
+	assert.True(t, latency < time.Minute, "latency %s is too long", latency)
+}
+
+func TestRequestContextCorrelationID(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	ctx := flogging.NewCorrelationContext(context.Background(), "abc")
+
+	r := flogging.StartRequest(ctx, zap.New(core), "request")
+	r.EndWithError("response", errors.New("failed"))
+
+	entries := logs.AllUntimed()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "abc", entries[0].ContextMap()["correlation_id"])
+	assert.Equal(t, "abc", entries[1].ContextMap()["correlation_id"])
+	assert.Equal(t, zapcore.ErrorLevel, entries[1].Level)
+	assert.Equal(t, "failed", entries[1].ContextMap()["error"])
+	assert.Contains(t, entries[1].ContextMap(), "latency")
+}
+
+func TestRequestsAreDistinct(t *testing.T) {
+	logger := zap.NewNop()
+	first := flogging.StartRequest(context.Background(), logger, "request")
+	second := flogging.StartRequest(context.Background(), logger, "request")
+	assert.NotEqual(t, first.ID(), second.ID())
+}
@@ -0,0 +1,132 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"runtime"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// ResourceStatsLogger is the name of the logger used for entries written by
+// the ResourceReporter.
+const ResourceStatsLogger = "resources"
+
+// A ResourceReporter periodically writes an entry that records the goroutine
+// count, heap usage, and garbage collection statistics of the process. The
+// entries provide a time series of resource usage in environments that do
+// not have a metrics stack.
+//
+// The entries are written at InfoLevel by the ResourceStatsLogger so they can
+// be disabled by the logging spec.
+type ResourceReporter struct {
+	core     zapcore.Core
+	interval time.Duration
+
+	mutex     sync.Mutex
+	newTicker func(time.Duration) (<-chan time.Time, func())
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// NewResourceReporter creates a ResourceReporter that writes to the provided
+// core every interval once started. An interval of zero or less disables the
+// reporter.
+func NewResourceReporter(core zapcore.Core, interval time.Duration) *ResourceReporter {
+	return &ResourceReporter{
+		core:     core,
+		interval: interval,
+		newTicker: func(d time.Duration) (<-chan time.Time, func()) {
+			t := time.NewTicker(d)
+			return t.C, t.Stop
+		},
+	}
+}
+
+// SetTicker sets the function used to create the ticker that drives the
+// reporter. The function returns the channel that delivers the ticks and a
+// function that stops the ticker. The entries written by the reporter carry
+// the time of the tick. It must be called before Start.
+func (r *ResourceReporter) SetTicker(newTicker func(time.Duration) (<-chan time.Time, func())) {
+	r.mutex.Lock()
+	r.newTicker = newTicker
+	r.mutex.Unlock()
+}
+
+// Start begins reporting in the background. Start is a no-op when the
+// reporter is disabled or already running.
+func (r *ResourceReporter) Start() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.interval <= 0 || r.stop != nil {
+		return
+	}
+
+	ticks, stopTicker := r.newTicker(r.interval)
+	r.stop, r.done = make(chan struct{}), make(chan struct{})
+	go func(stop, done chan struct{}) {
+		defer close(done)
+		defer stopTicker()
+		for {
+			select {
+			case t := <-ticks:
+				r.report(t)
+			case <-stop:
+				return
+			}
+		}
+	}(r.stop, r.done)
+}
+
+// Stop ends reporting and waits for the background reporter to exit. The
+// reporter can be started again after it has been stopped.
+func (r *ResourceReporter) Stop() {
+	r.mutex.Lock()
+	stop, done := r.stop, r.done
+	r.stop, r.done = nil, nil
+	r.mutex.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
+
+// Report writes an entry with the current resource statistics.
+func (r *ResourceReporter) Report() {
+	r.report(time.Now())
+}
+
+func (r *ResourceReporter) report(t time.Time) {
+	entry := zapcore.Entry{
+		Level:      zapcore.InfoLevel,
+		Time:       t,
+		LoggerName: ResourceStatsLogger,
+		Message:    "Resource usage",
+	}
+	if ce := r.core.Check(entry, nil); ce != nil {
+		ce.Write(resourceStats()...)
+	}
+}
+
+// resourceStats returns the fields that record the resource usage of the
+// process.
+func resourceStats() []zapcore.Field {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	return []zapcore.Field{
+		zap.Int("goroutines", runtime.NumGoroutine()),
+		zap.Uint64("heap_alloc", m.HeapAlloc),
+		zap.Uint64("heap_objects", m.HeapObjects),
+		zap.Uint32("num_gc", m.NumGC),
+		zap.Duration("gc_pause_total", time.Duration(m.PauseTotalNs)),
+	}
+}
@@ -0,0 +1,135 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// fakeTicker delivers ticks at the times of a fakeClock.
+type fakeTicker struct {
+	mutex    sync.Mutex
+	clock    *fakeClock
+	interval time.Duration
+	ticks    chan time.Time
+	stopped  bool
+}
+
+func (f *fakeTicker) newTicker(d time.Duration) (<-chan time.Time, func()) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.interval = d
+	f.ticks = make(chan time.Time)
+	return f.ticks, func() {
+		f.mutex.Lock()
+		f.stopped = true
+		f.mutex.Unlock()
+	}
+}
+
+// Advance moves the clock forward by the interval and delivers a tick.
+func (f *fakeTicker) Advance() {
+	f.mutex.Lock()
+	interval, ticks := f.interval, f.ticks
+	f.mutex.Unlock()
+	f.clock.Advance(interval)
+	ticks <- f.clock.Now()
+}
+
+func TestResourceReporter(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	ticker := &fakeTicker{clock: clock}
+
+	reporter := flogging.NewResourceReporter(core, 30*time.Second)
+	reporter.SetTicker(ticker.newTicker)
+	reporter.Start()
+	reporter.Start()
+	assert.Equal(t, 30*time.Second, ticker.interval)
+
+	ticker.Advance()
+	ticker.Advance()
+	ticker.Advance()
+	reporter.Stop()
+	assert.True(t, ticker.stopped)
+
+	entries := logs.All()
+	require.Len(t, entries, 3)
+	for i, e := range entries {
+		assert.Equal(t, flogging.ResourceStatsLogger, e.LoggerName)
+		assert.Equal(t, zapcore.InfoLevel, e.Level)
+		assert.Equal(t, "Resource usage", e.Message)
+		assert.True(t, time.Unix(1000, 0).Add(time.Duration(i+1)*30*time.Second).Equal(e.Time), "entry %d at %s", i, e.Time)
+
+		fields := e.ContextMap()
+		assert.NotZero(t, fields["goroutines"])
+		assert.NotZero(t, fields["heap_alloc"])
+		assert.Contains(t, fields, "heap_objects")
+		assert.Contains(t, fields, "num_gc")
+		assert.Contains(t, fields, "gc_pause_total")
+	}
+}
+
+func TestResourceReporterDisabled(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	ticker := &fakeTicker{clock: &fakeClock{}}
+
+	reporter := flogging.NewResourceReporter(core, 0)
+	reporter.SetTicker(ticker.newTicker)
+	reporter.Start()
+	reporter.Stop()
+
+	assert.Nil(t, ticker.ticks, "a disabled reporter should not create a ticker")
+	assert.Zero(t, logs.Len())
+}
+
+func TestResourceReporterRestart(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	ticker := &fakeTicker{clock: &fakeClock{now: time.Unix(1000, 0)}}
+
+	reporter := flogging.NewResourceReporter(core, time.Minute)
+	reporter.SetTicker(ticker.newTicker)
+	reporter.Stop()
+	reporter.Start()
+	ticker.Advance()
+	reporter.Stop()
+	reporter.Start()
+	ticker.Advance()
+	reporter.Stop()
+	reporter.Stop()
+
+	assert.Equal(t, 2, logs.Len())
+}
+
+func TestResourceReporterSpec(t *testing.T) {
+	output := &sw{}
+	reporter := flogging.NewResourceReporter(newTestConsoleCore(t, "info:resources=warn", output), time.Minute)
+	reporter.Report()
+	assert.Empty(t, output.String(), "the logging spec should apply to the reporter")
+
+	reporter = flogging.NewResourceReporter(newTestConsoleCore(t, "info", output), time.Minute)
+	reporter.Report()
+	assert.True(t, strings.HasPrefix(output.String(), "[resources] INFO Resource usage goroutines="), output.String())
+}
+
+func TestResourceReporterTicker(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	reporter := flogging.NewResourceReporter(core, time.Millisecond)
+	reporter.Start()
+	defer reporter.Stop()
+
+	assert.Eventually(t, func() bool { return logs.Len() >= 2 }, 5*time.Second, time.Millisecond)
+}
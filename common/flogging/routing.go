@@ -0,0 +1,105 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"go.uber.org/zap/zapcore"
+)
+
+// A FieldRoutingCore is a zapcore.Core that writes entries to an output
+// selected by the value of a string field, such as a tenant identifier. The
+// field may be provided when the entry is written or added to the logger with
+// With; a value provided when the entry is written takes precedence. Entries
+// without the field, or with a value that has no route, are written to the
+// Output of the wrapped Core.
+type FieldRoutingCore struct {
+	*Core
+
+	key    string
+	routes map[string]zapcore.WriteSyncer
+	route  string // value of the routing field added with With
+	routed bool
+}
+
+// NewFieldRoutingCore creates a FieldRoutingCore that uses the value of the
+// field named key to select the output of entries encoded by a copy of the
+// provided core. The routes replace the core's Output, which continues to
+// receive the entries that are not routed, so the core must not use Sinks or
+// a Delegate.
+func NewFieldRoutingCore(core *Core, key string, routes map[string]zapcore.WriteSyncer) *FieldRoutingCore {
+	r := make(map[string]zapcore.WriteSyncer, len(routes))
+	for value, output := range routes {
+		r[value] = output
+	}
+
+	routing := *core
+	return &FieldRoutingCore{
+		Core:   &routing,
+		key:    key,
+		routes: r,
+	}
+}
+
+func (f *FieldRoutingCore) With(fields []zapcore.Field) zapcore.Core {
+	route, routed := f.route, f.routed
+	if value, ok := f.routingValue(fields); ok {
+		route, routed = value, true
+	}
+	return &FieldRoutingCore{
+		Core:   f.Core.With(fields).(*Core),
+		key:    f.key,
+		routes: f.routes,
+		route:  route,
+		routed: routed,
+	}
+}
+
+func (f *FieldRoutingCore) Check(e zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return checkWrapped(f.Core, e, ce, f, f.write)
+}
+
+func (f *FieldRoutingCore) Write(e zapcore.Entry, fields []zapcore.Field) error {
+	return f.write(e, fields, f.Core.Write)
+}
+
+func (f *FieldRoutingCore) write(e zapcore.Entry, fields []zapcore.Field, next writeFunc) error {
+	route, routed := f.route, f.routed
+	if value, ok := f.routingValue(fields); ok {
+		route, routed = value, true
+	}
+
+	output, ok := f.routes[route]
+	if !routed || !ok {
+		return next(e, fields)
+	}
+
+	core := *f.Core
+	core.Output = output
+	return core.Write(e, fields)
+}
+
+// Sync flushes the default output and the outputs of all routes.
+func (f *FieldRoutingCore) Sync() error {
+	err := f.Core.Sync()
+	for _, output := range f.routes {
+		if serr := output.Sync(); err == nil {
+			err = serr
+		}
+	}
+	return err
+}
+
+// routingValue returns the value of the last string field named by the
+// routing key.
+func (f *FieldRoutingCore) routingValue(fields []zapcore.Field) (value string, ok bool) {
+	for _, field := range fields {
+		if field.Key == f.key && field.Type == zapcore.StringType {
+			value, ok = field.String, true
+		}
+	}
+	return value, ok
+}
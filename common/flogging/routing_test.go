@@ -0,0 +1,57 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestFieldRoutingCore(t *testing.T) {
+	fallback, tenantA, tenantB := &sw{}, &sw{}, &sw{}
+	core := flogging.NewFieldRoutingCore(newTestConsoleCore(t, "info", fallback), "tenant", map[string]zapcore.WriteSyncer{
+		"a": tenantA,
+		"b": tenantB,
+	})
+	logger := zap.New(core).Named("routing")
+
+	logger.Info("to a", zap.String("tenant", "a"))
+	logger.Info("to b", zap.String("tenant", "b"))
+	logger.Info("unmatched", zap.String("tenant", "c"))
+	logger.Info("untagged")
+	logger.Info("not a string", zap.Int("tenant", 1))
+
+	withA := logger.With(zap.String("tenant", "a"))
+	withA.Info("with a")
+	withA.Info("overridden", zap.String("tenant", "b"))
+
+	assert.Equal(t, "[routing] INFO to a tenant=a\n[routing] INFO with a tenant=a\n", tenantA.String())
+	assert.Equal(t, "[routing] INFO to b tenant=b\n[routing] INFO overridden tenant=a tenant=b\n", tenantB.String())
+	assert.Equal(t, "[routing] INFO unmatched tenant=c\n[routing] INFO untagged\n[routing] INFO not a string tenant=1\n", fallback.String())
+
+	fallback.Reset()
+	logger.Debug("disabled", zap.String("tenant", "a"))
+	assert.Equal(t, "[routing] INFO to a tenant=a\n[routing] INFO with a tenant=a\n", tenantA.String())
+	assert.Empty(t, fallback.String())
+}
+
+func TestFieldRoutingCoreSync(t *testing.T) {
+	fallback, tenant := &sw{}, &sw{syncErr: errors.New("sync failed")}
+	core := flogging.NewFieldRoutingCore(newTestConsoleCore(t, "info", fallback), "tenant", map[string]zapcore.WriteSyncer{
+		"a": tenant,
+	})
+
+	err := core.Sync()
+	assert.EqualError(t, err, "sync failed")
+	assert.True(t, fallback.syncCalled)
+	assert.True(t, tenant.syncCalled)
+}
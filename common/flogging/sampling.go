@@ -0,0 +1,151 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// A SamplingCore is a zapcore.Core that keeps or drops all of the entries
+// associated with a key, such as a request id, as a unit. This allows the
+// complete set of log records for a sampled request to be retained while
+// limiting the total volume.
+//
+// The sampling decision is a deterministic function of the key value so every
+// core that observes the same value makes the same decision. When the key is
+// attached to a logger with With, the decision is made once and reused for
+// every entry written by the derived logger. Entries that are not associated
+// with the key are always passed to the wrapped core.
+type SamplingCore struct {
+	zapcore.Core
+
+	// Key is the name of the field that identifies related entries.
+	Key string
+	// Rate is the fraction, between 0 and 1, of key values that are kept.
+	Rate float64
+	// Annotate, when true, adds a ProcessedSampled annotation to the kept
+	// entries that are associated with the key.
+	Annotate bool
+	// Exempt lists the names of loggers that are never sampled. A name also
+	// exempts the loggers below it so orderer.consensus exempts
+	// orderer.consensus.etcdraft.
+	Exempt []string
+
+	decided bool
+	keep    bool
+}
+
+// NewSamplingCore creates a SamplingCore that keeps the fraction of key
+// values specified by rate and delegates to the provided core.
+func NewSamplingCore(core zapcore.Core, key string, rate float64) *SamplingCore {
+	return &SamplingCore{
+		Core: core,
+		Key:  key,
+		Rate: rate,
+	}
+}
+
+func (s *SamplingCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *s
+	clone.Core = s.Core.With(fields)
+	if value, ok := s.keyValue(fields); ok {
+		clone.decided = true
+		clone.keep = s.Sampled(value)
+	}
+	return &clone
+}
+
+func (s *SamplingCore) Check(e zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if s.exempt(e.LoggerName) {
+		return s.Core.Check(e, ce)
+	}
+	if s.decided {
+		if !s.keep {
+			return ce
+		}
+		return s.Core.Check(e, ce)
+	}
+
+	// The entry fields are not available until Write so the wrapped core is
+	// consulted to determine whether the entry would be written.
+	return checkWrapped(s.Core, e, ce, s, s.write)
+}
+
+func (s *SamplingCore) Write(e zapcore.Entry, fields []zapcore.Field) error {
+	return s.write(e, fields, s.Core.Write)
+}
+
+func (s *SamplingCore) write(e zapcore.Entry, fields []zapcore.Field, next writeFunc) error {
+	value, ok := s.keyValue(fields)
+	if ok && !s.decided && !s.Sampled(value) {
+		return nil
+	}
+	if s.Annotate && (ok || s.decided) {
+		fields = Annotate(fields, ProcessedSampled)
+	}
+	return next(e, fields)
+}
+
+// Sampled returns true when entries associated with the provided key value
+// should be kept.
+func (s *SamplingCore) Sampled(value string) bool {
+	switch {
+	case s.Rate <= 0:
+		return false
+	case s.Rate >= 1:
+		return true
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(value))
+	return float64(h.Sum32()) < s.Rate*math.MaxUint32
+}
+
+// exempt returns true when the logger name matches an entry in Exempt.
+func (s *SamplingCore) exempt(loggerName string) bool {
+	for _, name := range s.Exempt {
+		if loggerName == name || strings.HasPrefix(loggerName, name+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// keyValue returns the string representation of the sampling key from the
+// fields.
+func (s *SamplingCore) keyValue(fields []zapcore.Field) (string, bool) {
+	for i := len(fields) - 1; i >= 0; i-- {
+		f := fields[i]
+		if f.Key != s.Key {
+			continue
+		}
+		switch f.Type {
+		case zapcore.StringType:
+			return f.String, true
+		case zapcore.StringerType:
+			if value, ok := stringerValue(f.Interface); ok {
+				return value, true
+			}
+			return fmt.Sprint(f.Interface), true
+		default:
+			// the field is encoded so values held in Integer, such as
+			// bools, floats, durations, and times, are formatted by type
+			enc := zapcore.NewMapObjectEncoder()
+			f.AddTo(enc)
+			if value, ok := enc.Fields[f.Key]; ok {
+				return fmt.Sprint(value), true
+			}
+			return fmt.Sprint(f.Interface), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,158 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging_test
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// sampledKeys returns a key value that is kept and one that is dropped by
+// the sampler.
+func sampledKeys(t *testing.T, s *flogging.SamplingCore) (in, out string) {
+	for i := 0; i < 1000 && (in == "" || out == ""); i++ {
+		key := fmt.Sprintf("request-%d", i)
+		if s.Sampled(key) {
+			in = key
+		} else {
+			out = key
+		}
+	}
+	require.NotEmpty(t, in)
+	require.NotEmpty(t, out)
+	return in, out
+}
+
+func TestSamplingCoreWith(t *testing.T) {
+	output := &sw{}
+	sampler := flogging.NewSamplingCore(newTestConsoleCore(t, "debug", output), "reqid", 0.5)
+	in, out := sampledKeys(t, sampler)
+
+	logger := zap.New(sampler)
+	kept := logger.With(zap.String("reqid", in))
+	dropped := logger.With(zap.String("reqid", out))
+	for i := 0; i < 5; i++ {
+		kept.Info("kept")
+		dropped.Info("dropped")
+	}
+	logger.Info("unrelated")
+
+	assert.Equal(t, 5, strings.Count(output.String(), "kept"))
+	assert.NotContains(t, output.String(), "dropped")
+	assert.Contains(t, output.String(), "unrelated")
+}
+
+func TestSamplingCoreFields(t *testing.T) {
+	output := &sw{}
+	sampler := flogging.NewSamplingCore(newTestConsoleCore(t, "info", output), "reqid", 0.5)
+	in, out := sampledKeys(t, sampler)
+
+	logger := zap.New(sampler)
+	for i := 0; i < 5; i++ {
+		logger.Info("kept", zap.String("reqid", in))
+		logger.Info("dropped", zap.String("reqid", out))
+		logger.Debug("disabled", zap.String("reqid", in))
+	}
+
+	assert.Equal(t, 5, strings.Count(output.String(), "kept"))
+	assert.NotContains(t, output.String(), "dropped")
+	assert.NotContains(t, output.String(), "disabled")
+}
+
+func TestSamplingCoreDeterministic(t *testing.T) {
+	first := flogging.NewSamplingCore(nil, "reqid", 0.25)
+	second := flogging.NewSamplingCore(nil, "reqid", 0.25)
+
+	kept := 0
+	for i := 0; i < 10000; i++ {
+		key := fmt.Sprintf("%x", i)
+		assert.Equal(t, first.Sampled(key), second.Sampled(key))
+		if first.Sampled(key) {
+			kept++
+		}
+	}
+	assert.InDelta(t, 2500, kept, 250)
+
+	assert.False(t, flogging.NewSamplingCore(nil, "reqid", 0).Sampled("key"))
+	assert.True(t, flogging.NewSamplingCore(nil, "reqid", 1).Sampled("key"))
+}
+
+func TestSamplingCoreExempt(t *testing.T) {
+	output := &sw{}
+	sampler := flogging.NewSamplingCore(newTestConsoleCore(t, "info", output), "reqid", 0)
+	sampler.Exempt = []string{"orderer.consensus"}
+
+	logger := zap.New(sampler)
+	for _, name := range []string{"orderer.consensus", "orderer.consensus.etcdraft", "orderer.consensusx", "orderer", "peer"} {
+		named := logger.Named(name)
+		named.Info("fields", zap.String("reqid", "request"))
+		named.With(zap.String("reqid", "request")).Info("with")
+	}
+
+	assert.Equal(t, ""+
+		"[orderer.consensus] INFO fields reqid=request\n"+
+		"[orderer.consensus] INFO with reqid=request\n"+
+		"[orderer.consensus.etcdraft] INFO fields reqid=request\n"+
+		"[orderer.consensus.etcdraft] INFO with reqid=request\n",
+		output.String(),
+	)
+}
+
+func TestSamplingCoreNilStringer(t *testing.T) {
+	output := &sw{}
+	sampler := flogging.NewSamplingCore(newTestConsoleCore(t, "info", output), "reqid", 1)
+
+	logger := zap.New(sampler)
+	assert.NotPanics(t, func() {
+		logger.Info("nil key", zap.Stringer("reqid", (*pointerStringer)(nil)))
+		logger.With(zap.Stringer("reqid", (*pointerStringer)(nil))).Info("nil key")
+	})
+	assert.Equal(t, 2, strings.Count(output.String(), "nil key"))
+}
+
+func TestSamplingCoreTypedKeys(t *testing.T) {
+	output := &sw{}
+	sampler := flogging.NewSamplingCore(newTestConsoleCore(t, "info", output), "reqid", 0.5)
+	logger := zap.New(sampler)
+
+	tests := []struct {
+		field zap.Field
+		key   string
+	}{
+		{zap.Bool("reqid", true), "true"},
+		{zap.Bool("reqid", false), "false"},
+		{zap.Uint64("reqid", math.MaxUint64), "18446744073709551615"},
+		{zap.Duration("reqid", 1500*time.Millisecond), "1.5s"},
+		{zap.Time("reqid", time.Unix(0, 0).UTC()), time.Unix(0, 0).UTC().String()},
+	}
+	for i := 0; i < 50; i++ {
+		v := float64(i) + 0.5
+		tests = append(tests, struct {
+			field zap.Field
+			key   string
+		}{zap.Float64("reqid", v), fmt.Sprint(v)})
+	}
+
+	kept := 0
+	for _, tt := range tests {
+		output.Reset()
+		logger.Info("message", tt.field)
+		assert.Equal(t, sampler.Sampled(tt.key), output.String() != "", "unexpected sampling for %s", tt.key)
+		if output.String() != "" {
+			kept++
+		}
+	}
+	assert.True(t, kept > 0 && kept < len(tests), "distinct values must not share a sampling decision")
+}
@@ -0,0 +1,45 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// ServiceKey is the field key used to identify the service, such as peer or
+// orderer, that wrote an entry.
+const ServiceKey = "service"
+
+// DefaultServiceName returns the name of the process executable, such as peer
+// or orderer, which identifies the service when a name is not configured.
+func DefaultServiceName() string {
+	if len(os.Args) == 0 {
+		return ""
+	}
+	return strings.TrimSuffix(filepath.Base(os.Args[0]), ".exe")
+}
+
+// NewServiceTransformer creates an EntryTransformer that adds a service field
+// with the name to every entry. Entries that already carry a service field
+// keep it. When name is empty, DefaultServiceName is used.
+func NewServiceTransformer(name string) EntryTransformer {
+	if name == "" {
+		name = DefaultServiceName()
+	}
+	field := zap.String(ServiceKey, name)
+	return func(e zapcore.Entry, fields []zapcore.Field) (zapcore.Entry, []zapcore.Field) {
+		if hasKey(fields, ServiceKey) {
+			return e, fields
+		}
+		return e, append(fields[:len(fields):len(fields)], field)
+	}
+}
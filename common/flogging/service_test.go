@@ -0,0 +1,65 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestDefaultServiceName(t *testing.T) {
+	defer func(args []string) { os.Args = args }(os.Args)
+
+	os.Args = []string{"/usr/local/bin/peer", "node", "start"}
+	assert.Equal(t, "peer", flogging.DefaultServiceName())
+	os.Args = []string{`orderer.exe`}
+	assert.Equal(t, "orderer", flogging.DefaultServiceName())
+	os.Args = nil
+	assert.Equal(t, "", flogging.DefaultServiceName())
+}
+
+func TestServiceTransformer(t *testing.T) {
+	transform := flogging.NewServiceTransformer("orderer")
+
+	fields := []zapcore.Field{zap.String("key", "value")}
+	_, out := transform(zapcore.Entry{}, fields)
+	assert.Equal(t, []zapcore.Field{zap.String("key", "value"), zap.String("service", "orderer")}, out)
+	assert.Len(t, fields, 1, "the provided fields should not be modified")
+
+	_, out = transform(zapcore.Entry{}, []zapcore.Field{zap.String("service", "ca")})
+	assert.Equal(t, []zapcore.Field{zap.String("service", "ca")}, out, "a service field at the call site wins")
+
+	_, out = flogging.NewServiceTransformer("")(zapcore.Entry{}, nil)
+	assert.Equal(t, []zapcore.Field{zap.String("service", flogging.DefaultServiceName())}, out)
+}
+
+func TestLoggingServiceField(t *testing.T) {
+	output := &sw{}
+	logging, err := flogging.New(flogging.Config{Format: "%{message}", Writer: output, ServiceField: true})
+	assert.NoError(t, err)
+
+	logging.Logger("service").Info("default")
+	expected := strings.TrimSuffix(filepath.Base(os.Args[0]), ".exe")
+	assert.Equal(t, "default service="+expected+"\n", output.String())
+
+	output.Reset()
+	logging.SetServiceName("peer")
+	logging.Logger("service").Info("configured")
+	assert.Equal(t, "configured service=peer\n", output.String())
+
+	output.Reset()
+	logging.SetServiceField(false)
+	logging.Logger("service").Info("disabled")
+	assert.Equal(t, "disabled\n", output.String())
+}
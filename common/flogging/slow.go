@@ -0,0 +1,84 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// SlowKey is the field key used to mark entries that recorded a duration
+// above the slow threshold.
+const SlowKey = "slow"
+
+// SlowThresholds upgrades entries that record slow operations to WarnLevel so
+// they stand out. An operation is slow when the duration field identified by
+// Key exceeds the threshold of the entry's logger. Slow entries are marked
+// with a SlowKey field. Its Transform method is an EntryTransformer for use
+// with Core.Transformers.
+//
+// Transformers are applied after the core has determined that an entry is
+// enabled so only the entries enabled at their original level are upgraded.
+type SlowThresholds struct {
+	// Key is the name of the duration field that records the time taken by
+	// the operation.
+	Key string
+	// Default is the threshold of the loggers that do not have a threshold
+	// in Loggers. A Default of zero or less disables the upgrade for those
+	// loggers.
+	Default time.Duration
+	// Loggers maps logger names to thresholds. A name also applies to the
+	// loggers below it so gossip applies to gossip.state. When several names
+	// apply, the longest name takes precedence.
+	Loggers map[string]time.Duration
+}
+
+// Threshold returns the threshold that applies to the named logger.
+func (s SlowThresholds) Threshold(loggerName string) time.Duration {
+	threshold, matched := s.Default, -1
+	for name, t := range s.Loggers {
+		if loggerName != name && !strings.HasPrefix(loggerName, name+".") {
+			continue
+		}
+		if len(name) > matched {
+			threshold, matched = t, len(name)
+		}
+	}
+	return threshold
+}
+
+// Transform upgrades the entry to WarnLevel and adds a SlowKey field when the
+// duration field exceeds the threshold. Entries above WarnLevel keep their
+// level.
+func (s SlowThresholds) Transform(e zapcore.Entry, fields []zapcore.Field) (zapcore.Entry, []zapcore.Field) {
+	d, ok := s.duration(fields)
+	if !ok {
+		return e, fields
+	}
+	threshold := s.Threshold(e.LoggerName)
+	if threshold <= 0 || d <= threshold {
+		return e, fields
+	}
+
+	if e.Level < zapcore.WarnLevel {
+		e.Level = zapcore.WarnLevel
+	}
+	return e, append(fields[:len(fields):len(fields)], zap.Bool(SlowKey, true))
+}
+
+// duration returns the value of the last duration field with the key.
+func (s SlowThresholds) duration(fields []zapcore.Field) (time.Duration, bool) {
+	for i := len(fields) - 1; i >= 0; i-- {
+		if fields[i].Key == s.Key && fields[i].Type == zapcore.DurationType {
+			return time.Duration(fields[i].Integer), true
+		}
+	}
+	return 0, false
+}
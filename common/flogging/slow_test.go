@@ -0,0 +1,124 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestSlowThresholdsThreshold(t *testing.T) {
+	s := flogging.SlowThresholds{
+		Default: time.Second,
+		Loggers: map[string]time.Duration{
+			"gossip":       100 * time.Millisecond,
+			"gossip.state": time.Minute,
+			"ledger":       0,
+		},
+	}
+
+	assert.Equal(t, time.Second, s.Threshold(""))
+	assert.Equal(t, time.Second, s.Threshold("peer"))
+	assert.Equal(t, time.Second, s.Threshold("gossipy"))
+	assert.Equal(t, 100*time.Millisecond, s.Threshold("gossip"))
+	assert.Equal(t, 100*time.Millisecond, s.Threshold("gossip.comm"))
+	assert.Equal(t, time.Minute, s.Threshold("gossip.state.sync"))
+	assert.Equal(t, time.Duration(0), s.Threshold("ledger.blkstorage"))
+}
+
+func TestSlowThresholdsTransform(t *testing.T) {
+	s := flogging.SlowThresholds{
+		Key:     "duration",
+		Default: time.Second,
+		Loggers: map[string]time.Duration{"ledger": 0},
+	}
+
+	tests := []struct {
+		name     string
+		entry    zapcore.Entry
+		fields   []zapcore.Field
+		level    zapcore.Level
+		expected []zapcore.Field
+	}{
+		{
+			name:     "fast",
+			entry:    zapcore.Entry{Level: zapcore.InfoLevel},
+			fields:   []zapcore.Field{zap.Duration("duration", time.Second)},
+			level:    zapcore.InfoLevel,
+			expected: []zapcore.Field{zap.Duration("duration", time.Second)},
+		},
+		{
+			name:     "slow",
+			entry:    zapcore.Entry{Level: zapcore.DebugLevel},
+			fields:   []zapcore.Field{zap.Duration("duration", 2*time.Second)},
+			level:    zapcore.WarnLevel,
+			expected: []zapcore.Field{zap.Duration("duration", 2*time.Second), zap.Bool("slow", true)},
+		},
+		{
+			name:     "slow error",
+			entry:    zapcore.Entry{Level: zapcore.ErrorLevel},
+			fields:   []zapcore.Field{zap.Duration("duration", 2*time.Second)},
+			level:    zapcore.ErrorLevel,
+			expected: []zapcore.Field{zap.Duration("duration", 2*time.Second), zap.Bool("slow", true)},
+		},
+		{
+			name:     "not a duration",
+			entry:    zapcore.Entry{Level: zapcore.InfoLevel},
+			fields:   []zapcore.Field{zap.Int64("duration", int64(time.Hour))},
+			level:    zapcore.InfoLevel,
+			expected: []zapcore.Field{zap.Int64("duration", int64(time.Hour))},
+		},
+		{
+			name:     "no duration",
+			entry:    zapcore.Entry{Level: zapcore.InfoLevel},
+			fields:   []zapcore.Field{zap.Duration("elapsed", time.Hour)},
+			level:    zapcore.InfoLevel,
+			expected: []zapcore.Field{zap.Duration("elapsed", time.Hour)},
+		},
+		{
+			name:     "disabled logger",
+			entry:    zapcore.Entry{Level: zapcore.InfoLevel, LoggerName: "ledger.kvledger"},
+			fields:   []zapcore.Field{zap.Duration("duration", time.Hour)},
+			level:    zapcore.InfoLevel,
+			expected: []zapcore.Field{zap.Duration("duration", time.Hour)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, fields := s.Transform(tt.entry, tt.fields)
+			assert.Equal(t, tt.level, e.Level)
+			assert.Equal(t, tt.expected, fields)
+		})
+	}
+}
+
+func TestSlowThresholdsCore(t *testing.T) {
+	output := &sw{}
+	core := newTestConsoleCore(t, "info", output)
+	core.Transformers = []flogging.EntryTransformer{
+		flogging.SlowThresholds{
+			Key:     "duration",
+			Loggers: map[string]time.Duration{"ledger": 100 * time.Millisecond},
+		}.Transform,
+	}
+
+	logger := zap.New(core)
+	logger.Named("ledger").Info("committed", zap.Duration("duration", 50*time.Millisecond))
+	logger.Named("ledger").Info("committed", zap.Duration("duration", 500*time.Millisecond))
+	logger.Named("gossip").Info("sent", zap.Duration("duration", time.Hour))
+	assert.Equal(t,
+		"[ledger] INFO committed duration=50ms\n"+
+			"[ledger] WARN committed duration=500ms slow=true\n"+
+			"[gossip] INFO sent duration=1h0m0s\n",
+		output.String(),
+	)
+}
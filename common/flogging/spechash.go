@@ -0,0 +1,25 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// SpecHashKey is the field key used to report the hash of the active logging
+// spec.
+const SpecHashKey = "spec_hash"
+
+// NewSpecHashTransformer creates an EntryTransformer that adds a spec_hash
+// field carrying the SpecHash of levels to each entry. Entries written by
+// processes with the same active spec carry the same hash.
+func NewSpecHashTransformer(levels *LoggerLevels) EntryTransformer {
+	return func(e zapcore.Entry, fields []zapcore.Field) (zapcore.Entry, []zapcore.Field) {
+		return e, append(fields[:len(fields):len(fields)], zap.String(SpecHashKey, levels.SpecHash()))
+	}
+}
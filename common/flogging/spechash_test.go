@@ -0,0 +1,45 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestSpecHashTransformer(t *testing.T) {
+	output := &sw{}
+	core := newTestConsoleCore(t, "info", output)
+	core.Transformers = []flogging.EntryTransformer{flogging.NewSpecHashTransformer(core.Levels)}
+	logger := zap.New(core).Named("hash")
+
+	logger.Info("first")
+	logger.Info("second")
+	hash := core.Levels.SpecHash()
+	assert.Equal(t, "[hash] INFO first spec_hash="+hash+"\n[hash] INFO second spec_hash="+hash+"\n", output.String())
+
+	output.Reset()
+	assert.NoError(t, core.Levels.ActivateSpec("debug"))
+	logger.Info("changed")
+	assert.NotEqual(t, hash, core.Levels.SpecHash())
+	assert.Equal(t, "[hash] INFO changed spec_hash="+core.Levels.SpecHash()+"\n", output.String())
+}
+
+func TestSpecHashTransformerFields(t *testing.T) {
+	levels := &flogging.LoggerLevels{}
+	assert.NoError(t, levels.ActivateSpec("info"))
+	transform := flogging.NewSpecHashTransformer(levels)
+
+	fields := []zapcore.Field{zap.String("key", "value")}
+	_, transformed := transform(zapcore.Entry{}, fields)
+	assert.Equal(t, []zapcore.Field{zap.String("key", "value"), zap.String("spec_hash", levels.SpecHash())}, transformed)
+	assert.Len(t, fields, 1, "input fields must not be modified")
+}
@@ -0,0 +1,152 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// StackRefKey is the field key used for the reference id of a stack
+	// trace written by a StackDeduplicator.
+	StackRefKey = "stack_ref"
+	// StackSuppressedKey is the field key used to report how many times a
+	// stack trace was omitted since it was last written in full.
+	StackSuppressedKey = "stack_suppressed"
+)
+
+// A StackDeduplicator omits repeated stack traces. The first entry with a
+// given stack trace is written with the full trace and a reference id. Later
+// entries with the same trace within the window are written with the
+// reference id only. Once the window has passed, the trace is written in full
+// again along with the number of times it was omitted.
+//
+// A trace that is not written again within two windows of being written in
+// full is forgotten. When Summary is set and the trace was omitted since it
+// was last written, a summary entry reporting the number of omitted traces is
+// checked and written to Summary before the trace is forgotten. Traces are
+// forgotten while entries with stack traces are transformed.
+//
+// Its Transform method is an EntryTransformer for use with Core.Transformers.
+type StackDeduplicator struct {
+	Window time.Duration
+	// Summary, when set, receives the summaries of omitted traces. It is
+	// usually the core that uses the deduplicator.
+	Summary zapcore.Core
+
+	mutex     sync.Mutex
+	stacks    map[uint64]*stackRecord
+	lastPrune time.Time
+}
+
+type stackRecord struct {
+	logger     string
+	written    time.Time
+	suppressed int
+}
+
+type stackSummary struct {
+	id         uint64
+	logger     string
+	suppressed int
+}
+
+// NewStackDeduplicator creates a StackDeduplicator that writes each distinct
+// stack trace in full at most once per window.
+func NewStackDeduplicator(window time.Duration) *StackDeduplicator {
+	return &StackDeduplicator{
+		Window: window,
+		stacks: map[uint64]*stackRecord{},
+	}
+}
+
+// Transform removes the stack trace from entries that repeat a trace written
+// within the window and adds the reference id of the trace to the fields.
+func (s *StackDeduplicator) Transform(e zapcore.Entry, fields []zapcore.Field) (zapcore.Entry, []zapcore.Field) {
+	if e.Stack == "" {
+		return e, fields
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(e.Stack))
+	id := h.Sum64()
+
+	now := e.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	s.mutex.Lock()
+	summaries := s.prune(now)
+	record, ok := s.stacks[id]
+	suppressed := 0
+	switch {
+	case !ok:
+		s.stacks[id] = &stackRecord{logger: e.LoggerName, written: now}
+	case now.Sub(record.written) < s.Window:
+		record.suppressed++
+		e.Stack = ""
+	default:
+		suppressed = record.suppressed
+		record.written = now
+		record.suppressed = 0
+	}
+	summary := s.Summary
+	s.mutex.Unlock()
+
+	if summary != nil {
+		for _, sum := range summaries {
+			s.writeSummary(summary, now, sum)
+		}
+	}
+
+	fields = append(fields[:len(fields):len(fields)], zap.String(StackRefKey, stackRef(id)))
+	if suppressed > 0 {
+		fields = append(fields, zap.Int(StackSuppressedKey, suppressed))
+	}
+	return e, fields
+}
+
+// prune discards the records of traces that have not been written within two
+// windows and returns the summaries of the discarded traces that were
+// omitted. It must be called with the mutex held.
+func (s *StackDeduplicator) prune(now time.Time) []stackSummary {
+	if now.Sub(s.lastPrune) < s.Window {
+		return nil
+	}
+	s.lastPrune = now
+	var summaries []stackSummary
+	for id, record := range s.stacks {
+		if now.Sub(record.written) >= 2*s.Window {
+			if record.suppressed > 0 {
+				summaries = append(summaries, stackSummary{id: id, logger: record.logger, suppressed: record.suppressed})
+			}
+			delete(s.stacks, id)
+		}
+	}
+	return summaries
+}
+
+// writeSummary checks and writes the summary of an omitted trace to the core.
+// The summary has no stack trace so it is not deduplicated.
+func (s *StackDeduplicator) writeSummary(core zapcore.Core, now time.Time, sum stackSummary) {
+	entry := zapcore.Entry{
+		Level:      zapcore.InfoLevel,
+		Time:       now,
+		LoggerName: sum.logger,
+		Message:    fmt.Sprintf("%d duplicate stacks suppressed", sum.suppressed),
+	}
+	writeChecked(core.Check(entry, nil), zap.String(StackRefKey, stackRef(sum.id)), zap.Int(StackSuppressedKey, sum.suppressed))
+}
+
+func stackRef(id uint64) string { return fmt.Sprintf("%016x", id) }
@@ -0,0 +1,136 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging_test
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestStackDeduplicator(t *testing.T) {
+	output := &sw{}
+	core := newTestConsoleCore(t, "info", output)
+	core.Encoders[flogging.JSON] = zapcore.NewJSONEncoder(zapcore.EncoderConfig{MessageKey: "msg", StacktraceKey: "stack"})
+	core.Sinks = []flogging.Sink{{Encoding: flogging.JSON, Output: output}}
+	dedup := flogging.NewStackDeduplicator(time.Minute)
+	core.Transformers = []flogging.EntryTransformer{dedup.Transform}
+
+	start := time.Unix(1000, 0)
+	write := func(offset time.Duration, message, stack string) map[string]interface{} {
+		output.Reset()
+		err := core.Write(zapcore.Entry{Level: zapcore.ErrorLevel, Time: start.Add(offset), Message: message, Stack: stack}, nil)
+		require.NoError(t, err)
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(output.Bytes(), &entry))
+		return entry
+	}
+
+	first := write(0, "first", "goroutine 1\nmain.f()")
+	assert.Equal(t, "goroutine 1\nmain.f()", first["stack"])
+	ref, ok := first["stack_ref"].(string)
+	require.True(t, ok)
+	assert.Regexp(t, `^[0-9a-f]{16}$`, ref)
+
+	// identical stacks within the window are replaced by the reference
+	second := write(time.Second, "second", "goroutine 1\nmain.f()")
+	assert.Equal(t, map[string]interface{}{"msg": "second", "stack_ref": ref}, second)
+	write(2*time.Second, "third", "goroutine 1\nmain.f()")
+
+	// different stacks are written in full
+	other := write(3*time.Second, "other", "goroutine 2\nmain.g()")
+	assert.Equal(t, "goroutine 2\nmain.g()", other["stack"])
+	assert.NotEqual(t, ref, other["stack_ref"])
+
+	// the full stack is written again with a summary after the window
+	summary := write(time.Minute+time.Second, "again", "goroutine 1\nmain.f()")
+	assert.Equal(t, map[string]interface{}{
+		"msg":              "again",
+		"stack":            "goroutine 1\nmain.f()",
+		"stack_ref":        ref,
+		"stack_suppressed": float64(2),
+	}, summary)
+
+	// entries without a stack are unchanged
+	assert.Equal(t, map[string]interface{}{"msg": "plain"}, write(time.Minute+2*time.Second, "plain", ""))
+}
+
+func TestStackDeduplicatorSummary(t *testing.T) {
+	output := &sw{}
+	core := newTestConsoleCore(t, "info", output)
+	core.Encoders[flogging.JSON] = zapcore.NewJSONEncoder(zapcore.EncoderConfig{MessageKey: "msg", NameKey: "name", StacktraceKey: "stack"})
+	core.Sinks = []flogging.Sink{{Encoding: flogging.JSON, Output: output}}
+	dedup := flogging.NewStackDeduplicator(time.Minute)
+	dedup.Summary = core
+	core.Transformers = []flogging.EntryTransformer{dedup.Transform}
+
+	start := time.Unix(1000, 0)
+	write := func(offset time.Duration, name, stack string) {
+		err := core.Write(zapcore.Entry{Level: zapcore.ErrorLevel, Time: start.Add(offset), LoggerName: name, Message: "failed", Stack: stack}, nil)
+		require.NoError(t, err)
+	}
+	write(0, "repeated", "goroutine 1\nmain.f()")
+	write(time.Second, "repeated", "goroutine 1\nmain.f()")
+	write(2*time.Second, "repeated", "goroutine 1\nmain.f()")
+	write(3*time.Second, "once", "goroutine 2\nmain.g()")
+
+	var ref string
+	for _, line := range strings.Split(strings.TrimSpace(output.String()), "\n") {
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		if entry["name"] == "repeated" {
+			ref = entry["stack_ref"].(string)
+			break
+		}
+	}
+
+	// the omitted traces are summarized when the trace is forgotten
+	output.Reset()
+	write(2*time.Minute+time.Second, "other", "goroutine 3\nmain.h()")
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	require.Len(t, lines, 2, "unexpected output: %s", output.String())
+	var summary map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &summary))
+	assert.Equal(t, map[string]interface{}{
+		"msg":              "2 duplicate stacks suppressed",
+		"name":             "repeated",
+		"stack_ref":        ref,
+		"stack_suppressed": float64(2),
+	}, summary)
+	assert.Contains(t, lines[1], `"name":"other"`)
+}
+
+func TestStackDeduplicatorConcurrent(t *testing.T) {
+	dedup := flogging.NewStackDeduplicator(time.Hour)
+	var mutex sync.Mutex
+	full := 0
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				e, _ := dedup.Transform(zapcore.Entry{Stack: "goroutine 1"}, nil)
+				if e.Stack != "" {
+					mutex.Lock()
+					full++
+					mutex.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, full)
+}
@@ -0,0 +1,97 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// DefaultStackTrimPrefixes are the function name prefixes of the runtime and
+// logging frames that a StackTrimmer removes when no prefixes are provided.
+var DefaultStackTrimPrefixes = []string{
+	"runtime.",
+	"go.uber.org/zap.",
+	"go.uber.org/zap/zapcore.",
+	"github.com/hyperledger/fabric/common/flogging.",
+}
+
+// stackElided ends a stack trace that was limited to MaxFrames. It matches
+// the marker used by the runtime for truncated tracebacks.
+const stackElided = "...additional frames elided..."
+
+// A StackTrimmer removes framework frames from the top of entry stack traces
+// and limits the number of frames that remain. Frames are removed from the
+// top of the trace while their function name starts with one of the trim
+// prefixes; the trace is left intact when every frame matches.
+//
+// Its Transform method is an EntryTransformer for use with Core.Transformers.
+type StackTrimmer struct {
+	// TrimPrefixes are the function name prefixes of the frames to remove.
+	// When TrimPrefixes is nil, DefaultStackTrimPrefixes is used.
+	TrimPrefixes []string
+	// MaxFrames, when greater than zero, limits the number of frames kept.
+	MaxFrames int
+}
+
+// Transform trims the stack trace of the entry.
+func (s StackTrimmer) Transform(e zapcore.Entry, fields []zapcore.Field) (zapcore.Entry, []zapcore.Field) {
+	if e.Stack == "" {
+		return e, fields
+	}
+
+	prefixes := s.TrimPrefixes
+	if prefixes == nil {
+		prefixes = DefaultStackTrimPrefixes
+	}
+
+	frames := stackFrames(e.Stack)
+	start := 0
+	for start < len(frames) && hasAnyPrefix(frames[start], prefixes) {
+		start++
+	}
+	if start == len(frames) {
+		start = 0
+	}
+	frames = frames[start:]
+
+	elided := s.MaxFrames > 0 && len(frames) > s.MaxFrames
+	if elided {
+		frames = frames[:s.MaxFrames]
+	}
+
+	stack := strings.Join(frames, "\n")
+	if elided {
+		stack += "\n" + stackElided
+	}
+	e.Stack = stack
+	return e, fields
+}
+
+// stackFrames splits a stack trace into frames. A frame starts with the
+// function name and continues with the indented lines that follow it.
+func stackFrames(stack string) []string {
+	var frames []string
+	for _, line := range strings.Split(stack, "\n") {
+		if strings.HasPrefix(line, "\t") && len(frames) > 0 {
+			frames[len(frames)-1] += "\n" + line
+			continue
+		}
+		frames = append(frames, line)
+	}
+	return frames
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
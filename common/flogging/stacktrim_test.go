@@ -0,0 +1,124 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const testStack = "github.com/hyperledger/fabric/common/flogging.(*FabricLogger).Errorf\n" +
+	"\t/fabric/common/flogging/zap.go:74\n" +
+	"github.com/hyperledger/fabric/core/peer.(*Peer).Start\n" +
+	"\t/fabric/core/peer/peer.go:100\n" +
+	"github.com/hyperledger/fabric/internal/peer/node.serve\n" +
+	"\t/fabric/internal/peer/node/start.go:200\n" +
+	"runtime.main\n" +
+	"\t/usr/local/go/src/runtime/proc.go:203"
+
+func TestStackTrimmer(t *testing.T) {
+	var tests = []struct {
+		name     string
+		trimmer  flogging.StackTrimmer
+		stack    string
+		expected string
+	}{
+		{name: "no stack", stack: "", expected: ""},
+		{
+			name:    "default prefixes",
+			stack:   testStack,
+			trimmer: flogging.StackTrimmer{},
+			expected: "github.com/hyperledger/fabric/core/peer.(*Peer).Start\n" +
+				"\t/fabric/core/peer/peer.go:100\n" +
+				"github.com/hyperledger/fabric/internal/peer/node.serve\n" +
+				"\t/fabric/internal/peer/node/start.go:200\n" +
+				"runtime.main\n" +
+				"\t/usr/local/go/src/runtime/proc.go:203",
+		},
+		{
+			name:    "max frames",
+			stack:   testStack,
+			trimmer: flogging.StackTrimmer{MaxFrames: 1},
+			expected: "github.com/hyperledger/fabric/core/peer.(*Peer).Start\n" +
+				"\t/fabric/core/peer/peer.go:100\n" +
+				"...additional frames elided...",
+		},
+		{
+			name:    "custom prefixes",
+			stack:   testStack,
+			trimmer: flogging.StackTrimmer{TrimPrefixes: []string{"github.com/hyperledger/fabric/common/", "github.com/hyperledger/fabric/core/"}, MaxFrames: 2},
+			expected: "github.com/hyperledger/fabric/internal/peer/node.serve\n" +
+				"\t/fabric/internal/peer/node/start.go:200\n" +
+				"runtime.main\n" +
+				"\t/usr/local/go/src/runtime/proc.go:203",
+		},
+		{
+			name:     "empty prefixes",
+			stack:    testStack,
+			trimmer:  flogging.StackTrimmer{TrimPrefixes: []string{}},
+			expected: testStack,
+		},
+		{
+			name:     "all frames match",
+			stack:    "runtime.main\n\t/usr/local/go/src/runtime/proc.go:203",
+			trimmer:  flogging.StackTrimmer{},
+			expected: "runtime.main\n\t/usr/local/go/src/runtime/proc.go:203",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fields := []zapcore.Field{zap.String("key", "value")}
+			e, transformed := tc.trimmer.Transform(zapcore.Entry{Stack: tc.stack}, fields)
+			assert.Equal(t, tc.expected, e.Stack)
+			assert.Equal(t, fields, transformed)
+		})
+	}
+}
+
+func TestStackTrimmerCore(t *testing.T) {
+	output := &sw{}
+	core := &flogging.Core{
+		LevelEnabler: zapcore.DebugLevel,
+		Levels:       &flogging.LoggerLevels{},
+		Encoders: map[flogging.Encoding]zapcore.Encoder{
+			flogging.JSON: zapcore.NewJSONEncoder(zapcore.EncoderConfig{MessageKey: "msg", StacktraceKey: "stacktrace"}),
+		},
+		Selector: fixedSelector(flogging.JSON),
+		Output:   output,
+		Transformers: []flogging.EntryTransformer{
+			flogging.StackTrimmer{TrimPrefixes: []string{"testing."}, MaxFrames: 2}.Transform,
+		},
+	}
+	require.NoError(t, core.Levels.ActivateSpec("debug"))
+
+	err := core.Write(zapcore.Entry{Level: zapcore.ErrorLevel, Message: "message", Stack: testStack}, nil)
+	require.NoError(t, err)
+
+	var entry map[string]string
+	require.NoError(t, json.Unmarshal(output.Bytes(), &entry))
+	assert.Equal(t, "github.com/hyperledger/fabric/common/flogging.(*FabricLogger).Errorf\n"+
+		"\t/fabric/common/flogging/zap.go:74\n"+
+		"github.com/hyperledger/fabric/core/peer.(*Peer).Start\n"+
+		"\t/fabric/core/peer/peer.go:100\n"+
+		"...additional frames elided...", entry["stacktrace"])
+
+	output.Reset()
+	core.Transformers = []flogging.EntryTransformer{flogging.StackTrimmer{MaxFrames: 1}.Transform}
+	zap.New(core, zap.AddStacktrace(zapcore.ErrorLevel)).Error("message")
+	require.NoError(t, json.Unmarshal(output.Bytes(), &entry))
+	lines := strings.Split(entry["stacktrace"], "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, "github.com/hyperledger/fabric/common/flogging_test.TestStackTrimmerCore", lines[0])
+}
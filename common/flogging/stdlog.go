@@ -0,0 +1,35 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"log"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// RedirectStdLog routes the output of the standard library's global logger to
+// core as entries of the named logger at the provided level. The standard
+// logger's flags and prefix are cleared so that each entry carries only the
+// message. The returned function restores the flags, prefix, and output that
+// were in place when RedirectStdLog was called.
+func RedirectStdLog(core zapcore.Core, name string, level zapcore.Level) (restore func(), err error) {
+	flags, prefix, output := log.Flags(), log.Prefix(), log.Writer()
+
+	undo, err := zap.RedirectStdLogAt(zap.New(core).Named(name), level)
+	if err != nil {
+		log.SetFlags(flags)
+		log.SetPrefix(prefix)
+		return nil, err
+	}
+
+	return func() {
+		undo()
+		log.SetOutput(output)
+	}, nil
+}
@@ -0,0 +1,70 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging_test
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRedirectStdLog(t *testing.T) {
+	original := &bytes.Buffer{}
+	log.SetOutput(original)
+	log.SetFlags(log.LstdFlags)
+	log.SetPrefix("prefix: ")
+	defer log.SetOutput(os.Stderr)
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	restore, err := flogging.RedirectStdLog(core, "stdlog", zapcore.WarnLevel)
+	require.NoError(t, err)
+
+	log.Printf("third party %s", "message")
+	entries := logs.AllUntimed()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "stdlog", entries[0].LoggerName)
+	assert.Equal(t, zapcore.WarnLevel, entries[0].Level)
+	assert.Equal(t, "third party message", entries[0].Message)
+	assert.Empty(t, original.String())
+
+	restore()
+	assert.Equal(t, log.LstdFlags, log.Flags())
+	assert.Equal(t, "prefix: ", log.Prefix())
+	log.Print("restored")
+	assert.Contains(t, original.String(), "prefix: ")
+	assert.Contains(t, original.String(), "restored\n")
+	assert.Len(t, logs.AllUntimed(), 1)
+}
+
+func TestRedirectStdLogConsoleCore(t *testing.T) {
+	defer log.SetOutput(os.Stderr)
+
+	output := &sw{}
+	restore, err := flogging.RedirectStdLog(newTestConsoleCore(t, "info", output), "thirdparty", zapcore.InfoLevel)
+	require.NoError(t, err)
+	defer restore()
+
+	log.Println("hello")
+	assert.Equal(t, "[thirdparty] INFO hello\n", output.String())
+}
+
+func TestRedirectStdLogInvalidLevel(t *testing.T) {
+	core, _ := observer.New(zapcore.DebugLevel)
+	log.SetFlags(log.Lshortfile)
+	defer log.SetFlags(log.LstdFlags)
+
+	_, err := flogging.RedirectStdLog(core, "stdlog", zapcore.Level(99))
+	assert.EqualError(t, err, "unrecognized level: \"Level(99)\"")
+	assert.Equal(t, log.Lshortfile, log.Flags())
+}
@@ -0,0 +1,133 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging/metrics"
+	"github.com/pkg/errors"
+)
+
+// SyslogPriority is the syslog facility and severity attached to messages.
+type SyslogPriority int
+
+const (
+	// SyslogUser is the user-level messages facility.
+	SyslogUser SyslogPriority = 1 << 3
+	// SyslogInfo is the informational severity.
+	SyslogInfo SyslogPriority = 6
+)
+
+// A SyslogSyncer is a zapcore.WriteSyncer that sends encoded log records to a
+// syslog daemon over the network. Each write is sent as a single message with
+// an RFC 3164 header.
+//
+// The connection is established on the first write. When a write fails, the
+// connection is re-established and the write is retried once. Connection state
+// changes are reported to the Tracker.
+type SyslogSyncer struct {
+	Network  string
+	Address  string
+	Tag      string
+	Priority SyslogPriority
+
+	// Dial establishes the connection to the syslog daemon. If Dial is nil,
+	// net.Dial is used.
+	Dial func(network, address string) (net.Conn, error)
+	// Tracker records connection metrics. It may be nil.
+	Tracker *metrics.ConnectionTracker
+
+	mutex    sync.Mutex
+	conn     net.Conn
+	hostname string
+}
+
+// NewSyslogSyncer creates a SyslogSyncer that sends user-level informational
+// messages to the syslog daemon at the specified address.
+func NewSyslogSyncer(network, address, tag string, tracker *metrics.ConnectionTracker) *SyslogSyncer {
+	return &SyslogSyncer{
+		Network:  network,
+		Address:  address,
+		Tag:      tag,
+		Priority: SyslogUser | SyslogInfo,
+		Tracker:  tracker,
+	}
+}
+
+// Write sends a syslog message containing b.
+func (s *SyslogSyncer) Write(b []byte) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.conn != nil {
+		err := s.send(b)
+		if err == nil {
+			return len(b), nil
+		}
+		s.conn.Close()
+		s.conn = nil
+		s.Tracker.Disconnected()
+	}
+
+	if err := s.connect(); err != nil {
+		return 0, err
+	}
+	if err := s.send(b); err != nil {
+		s.conn.Close()
+		s.conn = nil
+		s.Tracker.Disconnected()
+		return 0, errors.Wrap(err, "failed to write to syslog")
+	}
+	return len(b), nil
+}
+
+// Sync is a no-op as messages are not buffered.
+func (s *SyslogSyncer) Sync() error {
+	return nil
+}
+
+// Close closes the connection to the syslog daemon.
+func (s *SyslogSyncer) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+func (s *SyslogSyncer) connect() error {
+	dial := s.Dial
+	if dial == nil {
+		dial = net.Dial
+	}
+	conn, err := dial(s.Network, s.Address)
+	if err != nil {
+		s.Tracker.Disconnected()
+		return errors.Wrap(err, "failed to connect to syslog")
+	}
+	if s.hostname == "" {
+		s.hostname, _ = os.Hostname()
+	}
+	s.conn = conn
+	s.Tracker.Connected()
+	return nil
+}
+
+func (s *SyslogSyncer) send(b []byte) error {
+	timestamp := time.Now().Format(time.Stamp)
+	_, err := fmt.Fprintf(s.conn, "<%d>%s %s %s[%d]: %s", s.Priority, timestamp, s.hostname, s.Tag, os.Getpid(), b)
+	return err
+}
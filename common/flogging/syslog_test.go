@@ -0,0 +1,105 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging_test
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/common/flogging/metrics"
+	"github.com/hyperledger/fabric/common/metrics/metricsfakes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type syslogServer struct {
+	conns    []net.Conn
+	messages chan string
+	dialErr  error
+}
+
+func (s *syslogServer) dial(network, address string) (net.Conn, error) {
+	if s.dialErr != nil {
+		return nil, s.dialErr
+	}
+	client, server := net.Pipe()
+	s.conns = append(s.conns, server)
+	go func() {
+		r := bufio.NewReader(server)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			s.messages <- line
+		}
+	}()
+	return client, nil
+}
+
+func newConnectionMetrics() (*metrics.ConnectionMetrics, *metricsfakes.Counter, *metricsfakes.Gauge) {
+	reconnects := &metricsfakes.Counter{}
+	reconnects.WithReturns(reconnects)
+	connected := &metricsfakes.Gauge{}
+	connected.WithReturns(connected)
+	disconnected := &metricsfakes.Gauge{}
+	disconnected.WithReturns(disconnected)
+
+	return &metrics.ConnectionMetrics{
+		Reconnects:          reconnects,
+		Connected:           connected,
+		DisconnectedSeconds: disconnected,
+	}, reconnects, connected
+}
+
+func TestSyslogSyncerReconnect(t *testing.T) {
+	m, reconnects, connected := newConnectionMetrics()
+	server := &syslogServer{messages: make(chan string, 10)}
+	syncer := flogging.NewSyslogSyncer("tcp", "syslog:514", "peer", metrics.NewConnectionTracker("syslog", m))
+	syncer.Dial = server.dial
+	defer syncer.Close()
+
+	_, err := syncer.Write([]byte("first\n"))
+	require.NoError(t, err)
+	assert.Regexp(t, `^<14>\w{3} [ \d]\d \d{2}:\d{2}:\d{2} \S* peer\[\d+\]: first\n$`, <-server.messages)
+	assert.Equal(t, 0, reconnects.AddCallCount())
+	assert.Equal(t, float64(1), connected.SetArgsForCall(connected.SetCallCount()-1))
+
+	// simulate a dropped connection
+	server.conns[0].Close()
+
+	_, err = syncer.Write([]byte("second\n"))
+	require.NoError(t, err)
+	assert.Contains(t, <-server.messages, "peer")
+	assert.Len(t, server.conns, 2)
+	assert.Equal(t, 1, reconnects.AddCallCount())
+	assert.Equal(t, []string{"sink", "syslog"}, reconnects.WithArgsForCall(0))
+	assert.Equal(t, float64(1), connected.SetArgsForCall(connected.SetCallCount()-1))
+
+	assert.NoError(t, syncer.Sync())
+}
+
+func TestSyslogSyncerDialFailure(t *testing.T) {
+	m, reconnects, connected := newConnectionMetrics()
+	server := &syslogServer{messages: make(chan string, 10), dialErr: errors.New("connection refused")}
+	syncer := flogging.NewSyslogSyncer("tcp", "syslog:514", "peer", metrics.NewConnectionTracker("syslog", m))
+	syncer.Dial = server.dial
+
+	_, err := syncer.Write([]byte("message\n"))
+	assert.EqualError(t, err, "failed to connect to syslog: connection refused")
+	assert.Equal(t, float64(0), connected.SetArgsForCall(0))
+
+	server.dialErr = nil
+	_, err = syncer.Write([]byte("message\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, reconnects.AddCallCount(), "the first connection is not a reconnect")
+	assert.NoError(t, syncer.Close())
+	assert.NoError(t, syncer.Close())
+}
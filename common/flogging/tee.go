@@ -0,0 +1,69 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import "go.uber.org/zap/zapcore"
+
+// A TeeCore is a zapcore.Core that writes entries to a primary core and mirrors
+// them to a capture core. Unlike zapcore.NewTee, only the primary core is
+// consulted when an entry is checked, so the capture core receives exactly the
+// entries that are written to the primary core. This allows a capture core that
+// records structured entries to observe the output of a fully configured
+// logger.
+type TeeCore struct {
+	Primary zapcore.Core
+	Capture zapcore.Core
+}
+
+// NewTeeCore creates a TeeCore that mirrors the entries written to primary to
+// capture.
+func NewTeeCore(primary, capture zapcore.Core) *TeeCore {
+	return &TeeCore{
+		Primary: primary,
+		Capture: capture,
+	}
+}
+
+func (t *TeeCore) Enabled(l zapcore.Level) bool {
+	return t.Primary.Enabled(l)
+}
+
+func (t *TeeCore) With(fields []zapcore.Field) zapcore.Core {
+	return &TeeCore{
+		Primary: t.Primary.With(fields),
+		Capture: t.Capture.With(fields),
+	}
+}
+
+func (t *TeeCore) Check(e zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return checkWrapped(t.Primary, e, ce, t, t.write)
+}
+
+// Write writes the entry to both cores. The entry is written to the capture
+// core even when the primary core fails.
+func (t *TeeCore) Write(e zapcore.Entry, fields []zapcore.Field) error {
+	return t.write(e, fields, t.Primary.Write)
+}
+
+// write writes the entry to the primary core with next, which only writes to
+// the cores selected by the primary core when the entry was checked, and to
+// the capture core.
+func (t *TeeCore) write(e zapcore.Entry, fields []zapcore.Field, next writeFunc) error {
+	err := next(e, fields)
+	if cerr := t.Capture.Write(e, fields); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (t *TeeCore) Sync() error {
+	err := t.Primary.Sync()
+	if cerr := t.Capture.Sync(); err == nil {
+		err = cerr
+	}
+	return err
+}
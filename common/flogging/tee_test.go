@@ -0,0 +1,74 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestTeeCore(t *testing.T) {
+	output := &sw{}
+	primary := newTestConsoleCore(t, "info:tee.quiet=error", output)
+	capture, logs := observer.New(zapcore.DebugLevel)
+
+	tee := flogging.NewTeeCore(primary, capture)
+	logger := zap.New(tee).Named("tee").With(zap.String("phase", "test"))
+
+	logger.Debug("debug message")
+	logger.Info("info message", zap.Int("count", 1))
+	logger.Named("quiet").Warn("quiet warning")
+	logger.Named("quiet").Error("quiet error")
+
+	assert.Equal(t, "[tee] INFO info message phase=test count=1\n[tee.quiet] ERROR quiet error phase=test\n", output.String())
+
+	entries := logs.AllUntimed()
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, "info message", entries[0].Message)
+		assert.Equal(t, "tee", entries[0].LoggerName)
+		assert.Equal(t, []zapcore.Field{zap.String("phase", "test"), zap.Int("count", 1)}, entries[0].Context)
+		assert.Equal(t, "quiet error", entries[1].Message)
+		assert.Equal(t, "tee.quiet", entries[1].LoggerName)
+		assert.Equal(t, zapcore.ErrorLevel, entries[1].Level)
+	}
+
+	assert.True(t, tee.Enabled(zapcore.InfoLevel))
+	assert.False(t, tee.Enabled(zapcore.DebugLevel))
+}
+
+func TestTeeCoreErrors(t *testing.T) {
+	output := &sw{writeErr: errors.New("write failed"), syncErr: errors.New("sync failed")}
+	primary := newTestConsoleCore(t, "debug", output)
+	capture, logs := observer.New(zapcore.DebugLevel)
+	tee := flogging.NewTeeCore(primary, capture)
+
+	err := tee.Write(zapcore.Entry{Message: "message"}, nil)
+	assert.EqualError(t, err, "write failed")
+	assert.Equal(t, 1, logs.Len(), "capture must receive the entry when the primary fails")
+
+	err = tee.Sync()
+	assert.EqualError(t, err, "sync failed")
+	assert.True(t, output.syncCalled)
+}
+
+func TestTeeCorePrimaryTee(t *testing.T) {
+	info, warn := &sw{}, &sw{}
+	primary := zapcore.NewTee(newTestConsoleCore(t, "info", info), newTestConsoleCore(t, "warn", warn))
+	capture, logs := observer.New(zapcore.DebugLevel)
+	logger := zap.New(flogging.NewTeeCore(primary, capture))
+
+	logger.Info("info message")
+	assert.Equal(t, "[] INFO info message\n", info.String())
+	assert.Empty(t, warn.String(), "the primary's cores that did not enable the entry must not receive it")
+	assert.Equal(t, 1, logs.Len())
+}
@@ -0,0 +1,80 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"io"
+	"os"
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// IsTerminal returns true when w writes to a terminal. A writer, such as an
+// *os.File, is a terminal when its Stat method reports a character device.
+func IsTerminal(w io.Writer) bool {
+	f, ok := w.(interface{ Stat() (os.FileInfo, error) })
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// A LineFlushSyncer is a zapcore.WriteSyncer that syncs the underlying writer
+// after every write when the writer is a terminal. This ensures each record
+// is visible immediately during interactive debugging even when something
+// downstream buffers the output. Writes to other outputs, such as files, are
+// not synced so their throughput is preserved.
+type LineFlushSyncer struct {
+	mutex  sync.Mutex
+	writer io.Writer
+	flush  bool
+}
+
+// NewLineFlushSyncer creates a LineFlushSyncer that writes to w. The writer is
+// synced after every write when IsTerminal reports that it is a terminal.
+func NewLineFlushSyncer(w io.Writer) *LineFlushSyncer {
+	_, syncer := w.(zapcore.WriteSyncer)
+	return &LineFlushSyncer{
+		writer: w,
+		flush:  syncer && IsTerminal(w),
+	}
+}
+
+// Flushing returns true when the writer is synced after every write.
+func (l *LineFlushSyncer) Flushing() bool {
+	return l.flush
+}
+
+// Write writes b to the underlying writer and syncs the writer when it is a
+// terminal. The record has been written when the sync fails, so sync errors
+// are not returned; they are reported by Sync.
+func (l *LineFlushSyncer) Write(b []byte) (int, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	n, err := l.writer.Write(b)
+	if err == nil && l.flush {
+		l.writer.(zapcore.WriteSyncer).Sync()
+	}
+	return n, err
+}
+
+// Sync syncs the underlying writer when it is a zapcore.WriteSyncer.
+func (l *LineFlushSyncer) Sync() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if ws, ok := l.writer.(zapcore.WriteSyncer); ok {
+		return ws.Sync()
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeFileInfo struct{ mode os.FileMode }
+
+func (f fakeFileInfo) Name() string       { return "fake" }
+func (f fakeFileInfo) Size() int64        { return 0 }
+func (f fakeFileInfo) Mode() os.FileMode  { return f.mode }
+func (f fakeFileInfo) ModTime() time.Time { return time.Time{} }
+func (f fakeFileInfo) IsDir() bool        { return false }
+func (f fakeFileInfo) Sys() interface{}   { return nil }
+
+// ttySyncer is a WriteSyncer that reports the mode of a terminal and records
+// the output that had been written each time it was synced.
+type ttySyncer struct {
+	bytes.Buffer
+	mode    os.FileMode
+	statErr error
+	syncErr error
+	synced  []string
+}
+
+func (t *ttySyncer) Stat() (os.FileInfo, error) { return fakeFileInfo{mode: t.mode}, t.statErr }
+
+func (t *ttySyncer) Sync() error {
+	t.synced = append(t.synced, t.String())
+	return t.syncErr
+}
+
+func TestIsTerminal(t *testing.T) {
+	assert.True(t, flogging.IsTerminal(&ttySyncer{mode: os.ModeDevice | os.ModeCharDevice}))
+	assert.False(t, flogging.IsTerminal(&ttySyncer{}))
+	assert.False(t, flogging.IsTerminal(&ttySyncer{mode: os.ModeCharDevice, statErr: errors.New("boom")}))
+	assert.False(t, flogging.IsTerminal(&bytes.Buffer{}))
+
+	f, err := ioutil.TempFile("", "terminal")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	defer f.Close()
+	assert.False(t, flogging.IsTerminal(f))
+}
+
+func TestLineFlushSyncerTerminal(t *testing.T) {
+	tty := &ttySyncer{mode: os.ModeCharDevice}
+	syncer := flogging.NewLineFlushSyncer(tty)
+	assert.True(t, syncer.Flushing())
+
+	_, err := syncer.Write([]byte("first\n"))
+	assert.NoError(t, err)
+	_, err = syncer.Write([]byte("second\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"first\n", "first\nsecond\n"}, tty.synced, "every record should be flushed as it is written")
+
+	tty.syncErr = errors.New("invalid argument")
+	n, err := syncer.Write([]byte("third\n"))
+	assert.NoError(t, err, "sync errors should not fail the write")
+	assert.Equal(t, 6, n)
+	assert.EqualError(t, syncer.Sync(), "invalid argument")
+}
+
+func TestLineFlushSyncerFile(t *testing.T) {
+	regular := &ttySyncer{}
+	syncer := flogging.NewLineFlushSyncer(regular)
+	assert.False(t, syncer.Flushing())
+
+	_, err := syncer.Write([]byte("record\n"))
+	assert.NoError(t, err)
+	assert.Empty(t, regular.synced, "files should not be flushed after every record")
+	assert.Equal(t, "record\n", regular.String())
+
+	assert.NoError(t, syncer.Sync())
+	assert.Equal(t, []string{"record\n"}, regular.synced)
+
+	f, err := ioutil.TempFile("", "terminal")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	defer f.Close()
+	assert.False(t, flogging.NewLineFlushSyncer(f).Flushing())
+	assert.False(t, flogging.NewLineFlushSyncer(&bytes.Buffer{}).Flushing())
+	assert.NoError(t, flogging.NewLineFlushSyncer(&bytes.Buffer{}).Sync())
+}
+
+func TestLoggingFlushTerminal(t *testing.T) {
+	tty := &ttySyncer{mode: os.ModeCharDevice}
+	logging, err := flogging.New(flogging.Config{
+		Format:        "%{message}",
+		Writer:        tty,
+		FlushTerminal: true,
+	})
+	assert.NoError(t, err)
+
+	logging.Logger("tty").Info("first")
+	assert.Equal(t, []string{"first\n"}, tty.synced)
+
+	tty = &ttySyncer{mode: os.ModeCharDevice}
+	logging, err = flogging.New(flogging.Config{Format: "%{message}", Writer: tty})
+	assert.NoError(t, err)
+	logging.Logger("tty").Info("first")
+	assert.Empty(t, tty.synced)
+}
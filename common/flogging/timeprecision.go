@@ -0,0 +1,45 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap/zapcore"
+)
+
+var timePrecisions = map[string]time.Duration{
+	"ns": time.Nanosecond,
+	"us": time.Microsecond,
+	"ms": time.Millisecond,
+	"s":  time.Second,
+}
+
+// ParseTimePrecision returns the precision named by ns, us, ms, or s.
+func ParseTimePrecision(name string) (time.Duration, error) {
+	precision, ok := timePrecisions[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return 0, errors.Errorf("invalid time precision '%s'", name)
+	}
+	return precision, nil
+}
+
+// NewTimePrecisionTransformer creates an EntryTransformer that truncates the
+// time of each entry to a multiple of precision. The entry time is truncated
+// before encoding, rather than in the EncodeTime function of an encoder
+// configuration, so every encoding emits the same timestamp. A precision of
+// zero or less leaves the time unchanged.
+func NewTimePrecisionTransformer(precision time.Duration) EntryTransformer {
+	return func(e zapcore.Entry, fields []zapcore.Field) (zapcore.Entry, []zapcore.Field) {
+		if precision > 0 {
+			e.Time = e.Time.Truncate(precision)
+		}
+		return e, fields
+	}
+}
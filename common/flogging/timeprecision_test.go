@@ -0,0 +1,56 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestParseTimePrecision(t *testing.T) {
+	tests := map[string]time.Duration{
+		"ns":   time.Nanosecond,
+		"us":   time.Microsecond,
+		"ms":   time.Millisecond,
+		"s":    time.Second,
+		" MS ": time.Millisecond,
+	}
+	for name, expected := range tests {
+		precision, err := flogging.ParseTimePrecision(name)
+		assert.NoError(t, err, name)
+		assert.Equal(t, expected, precision, name)
+	}
+
+	_, err := flogging.ParseTimePrecision("minutes")
+	assert.EqualError(t, err, "invalid time precision 'minutes'")
+}
+
+func TestTimePrecisionTransformer(t *testing.T) {
+	ts := time.Date(2020, 1, 2, 3, 4, 5, 123456789, time.UTC)
+	tests := []struct {
+		precision time.Duration
+		expected  int
+	}{
+		{precision: 0, expected: 123456789},
+		{precision: time.Nanosecond, expected: 123456789},
+		{precision: time.Microsecond, expected: 123456000},
+		{precision: time.Millisecond, expected: 123000000},
+		{precision: time.Second, expected: 0},
+	}
+	for _, tt := range tests {
+		fields := []zapcore.Field{zap.String("key", "value")}
+		e, out := flogging.NewTimePrecisionTransformer(tt.precision)(zapcore.Entry{Time: ts, Message: "message"}, fields)
+		assert.Equal(t, time.Date(2020, 1, 2, 3, 4, 5, tt.expected, time.UTC), e.Time, "precision %s", tt.precision)
+		assert.Equal(t, "message", e.Message)
+		assert.Equal(t, fields, out)
+	}
+}
@@ -0,0 +1,160 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// OrigBytesKey is the field key used to report the size of a message before
+// it was truncated.
+const OrigBytesKey = "orig_bytes"
+
+// A MessageTruncator limits the size of entry messages. Its Transform method
+// is an EntryTransformer for use with Core.Transformers.
+type MessageTruncator struct {
+	// MaxBytes is the maximum size of a message. Longer messages are cut at
+	// a UTF-8 boundary and end with an ellipsis. A MaxBytes of zero or less
+	// disables truncation.
+	MaxBytes int
+	// ReportOriginalSize, when true, adds an OrigBytesKey field recording the
+	// size of the message before truncation to truncated entries.
+	ReportOriginalSize bool
+	// Annotate, when true, adds a ProcessedTruncated annotation to truncated
+	// entries.
+	Annotate bool
+}
+
+// Transform truncates the entry message when it exceeds MaxBytes.
+func (m MessageTruncator) Transform(e zapcore.Entry, fields []zapcore.Field) (zapcore.Entry, []zapcore.Field) {
+	if m.MaxBytes <= 0 || len(e.Message) <= m.MaxBytes {
+		return e, fields
+	}
+
+	size := len(e.Message)
+	e.Message = cutUTF8(e.Message, m.MaxBytes) + "..."
+
+	if m.ReportOriginalSize {
+		fields = append(fields[:len(fields):len(fields)], zap.Int(OrigBytesKey, size))
+	}
+	if m.Annotate {
+		fields = Annotate(fields, ProcessedTruncated)
+	}
+	return e, fields
+}
+
+// TruncatedFieldsKey is the field key used to report the keys of the fields
+// whose values were truncated.
+const TruncatedFieldsKey = "truncated_fields"
+
+// FieldValueTruncatedSuffix ends field values that have been truncated.
+const FieldValueTruncatedSuffix = "…(truncated)"
+
+// A FieldValueTruncator limits the size of individual field values so a single
+// large value, such as a long error string, does not dominate an entry. The
+// values of string, byte string, error, and fmt.Stringer fields are
+// truncated; other fields, and fmt.Stringer fields whose String method panics,
+// such as typed nil pointers, are preserved. Its Transform method is an
+// EntryTransformer for use with Core.Transformers and its TransformWith
+// method is a FieldsTransformer for use with Core.WithTransformers, which
+// truncates the values of the fields added with With.
+type FieldValueTruncator struct {
+	// MaxBytes is the maximum size of a field value. Longer values are cut at
+	// a UTF-8 boundary and end with FieldValueTruncatedSuffix. A MaxBytes of
+	// zero or less disables truncation.
+	MaxBytes int
+	// ReportTruncated, when true, adds a TruncatedFieldsKey field listing the
+	// keys of the truncated fields to entries with truncated values.
+	ReportTruncated bool
+	// Annotate, when true, adds a ProcessedTruncated annotation to entries
+	// with truncated values.
+	Annotate bool
+}
+
+// Transform truncates the field values that exceed MaxBytes. Error and
+// fmt.Stringer fields with oversized values are replaced by string fields
+// with the same key.
+func (f FieldValueTruncator) Transform(e zapcore.Entry, fields []zapcore.Field) (zapcore.Entry, []zapcore.Field) {
+	if f.MaxBytes <= 0 {
+		return e, fields
+	}
+
+	var result []zapcore.Field
+	var truncated []string
+	for i, field := range fields {
+		value, ok := f.oversized(field)
+		if !ok {
+			continue
+		}
+		if result == nil {
+			result = append(make([]zapcore.Field, 0, len(fields)+1), fields...)
+		}
+		result[i] = zap.String(field.Key, cutUTF8(value, f.MaxBytes)+FieldValueTruncatedSuffix)
+		truncated = append(truncated, field.Key)
+	}
+	if result == nil {
+		return e, fields
+	}
+
+	if f.ReportTruncated {
+		result = append(result, zap.Strings(TruncatedFieldsKey, truncated))
+	}
+	if f.Annotate {
+		result = Annotate(result, ProcessedTruncated)
+	}
+	return e, result
+}
+
+// TransformWith truncates the values of fields added with With that exceed
+// MaxBytes. The truncated fields are not reported or annotated as the report
+// is made per entry.
+func (f FieldValueTruncator) TransformWith(fields []zapcore.Field) []zapcore.Field {
+	_, fields = FieldValueTruncator{MaxBytes: f.MaxBytes}.Transform(zapcore.Entry{}, fields)
+	return fields
+}
+
+// oversized returns the value of the field when it can be truncated and its
+// size exceeds MaxBytes.
+func (f FieldValueTruncator) oversized(field zapcore.Field) (string, bool) {
+	var value string
+	switch field.Type {
+	case zapcore.StringType:
+		value = field.String
+	case zapcore.ByteStringType:
+		value = string(field.Interface.([]byte))
+	case zapcore.ErrorType:
+		err, ok := field.Interface.(error)
+		if !ok || err == nil {
+			return "", false
+		}
+		value = err.Error()
+	case zapcore.StringerType:
+		var ok bool
+		if value, ok = stringerValue(field.Interface); !ok {
+			return "", false
+		}
+	default:
+		return "", false
+	}
+	return value, len(value) > f.MaxBytes
+}
+
+// cutUTF8 returns the longest prefix of s that is at most max bytes and ends
+// at a UTF-8 boundary.
+func cutUTF8(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut]
+}
@@ -0,0 +1,178 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestMessageTruncator(t *testing.T) {
+	var tests = []struct {
+		name       string
+		truncator  flogging.MessageTruncator
+		message    string
+		expected   string
+		origFields []zapcore.Field
+	}{
+		{name: "short", truncator: flogging.MessageTruncator{MaxBytes: 8, ReportOriginalSize: true}, message: "short", expected: "short"},
+		{name: "exact", truncator: flogging.MessageTruncator{MaxBytes: 5, ReportOriginalSize: true}, message: "exact", expected: "exact"},
+		{name: "truncated", truncator: flogging.MessageTruncator{MaxBytes: 4}, message: "truncated", expected: "trun..."},
+		{
+			name:       "truncated with size",
+			truncator:  flogging.MessageTruncator{MaxBytes: 4, ReportOriginalSize: true},
+			message:    "truncated",
+			expected:   "trun...",
+			origFields: []zapcore.Field{zap.Int("orig_bytes", 9)},
+		},
+		{
+			name:       "utf-8 boundary",
+			truncator:  flogging.MessageTruncator{MaxBytes: 2, ReportOriginalSize: true},
+			message:    "aéb",
+			expected:   "a...",
+			origFields: []zapcore.Field{zap.Int("orig_bytes", 4)},
+		},
+		{name: "disabled", truncator: flogging.MessageTruncator{ReportOriginalSize: true}, message: "unlimited", expected: "unlimited"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fields := []zapcore.Field{zap.String("key", "value")}
+			e, transformed := tc.truncator.Transform(zapcore.Entry{Message: tc.message}, fields)
+			assert.Equal(t, tc.expected, e.Message)
+			assert.Equal(t, append([]zapcore.Field{zap.String("key", "value")}, tc.origFields...), transformed)
+			assert.Len(t, fields, 1, "input fields must not be modified")
+		})
+	}
+}
+
+func TestMessageTruncatorCore(t *testing.T) {
+	output := &sw{}
+	core := newTestConsoleCore(t, "info", output)
+	core.Transformers = []flogging.EntryTransformer{
+		flogging.MessageTruncator{MaxBytes: 10, ReportOriginalSize: true}.Transform,
+	}
+
+	err := core.Write(zapcore.Entry{Level: zapcore.InfoLevel, Message: "short"}, nil)
+	assert.NoError(t, err)
+	err = core.Write(zapcore.Entry{Level: zapcore.InfoLevel, Message: "a message that is too long"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "[] INFO short\n[] INFO a message ... orig_bytes=26\n", output.String())
+}
+
+type stringer string
+
+func (s stringer) String() string { return string(s) }
+
+type pointerStringer struct{ value string }
+
+func (p *pointerStringer) String() string { return p.value }
+
+func TestFieldValueTruncator(t *testing.T) {
+	truncator := flogging.FieldValueTruncator{MaxBytes: 5}
+	fields := []zapcore.Field{
+		zap.String("short", "value"),
+		zap.String("long", "a long value"),
+		zap.ByteString("bytes", []byte("long bytes")),
+		zap.Error(errors.New("a long error")),
+		zap.Stringer("stringer", stringer("a long stringer")),
+		zap.String("utf8", "abcdé"),
+		zap.Int("number", 1234567890),
+		zap.NamedError("nil", nil),
+		zap.Stringer("nil-stringer", (*pointerStringer)(nil)),
+	}
+	original := append([]zapcore.Field(nil), fields...)
+
+	_, transformed := truncator.Transform(zapcore.Entry{}, fields)
+	assert.Equal(t, []zapcore.Field{
+		zap.String("short", "value"),
+		zap.String("long", "a lon…(truncated)"),
+		zap.String("bytes", "long …(truncated)"),
+		zap.String("error", "a lon…(truncated)"),
+		zap.String("stringer", "a lon…(truncated)"),
+		zap.String("utf8", "abcd…(truncated)"),
+		zap.Int("number", 1234567890),
+		zap.NamedError("nil", nil),
+		zap.Stringer("nil-stringer", (*pointerStringer)(nil)),
+	}, transformed)
+	assert.Equal(t, original, fields, "input fields must not be modified")
+
+	short := []zapcore.Field{zap.String("key", "value")}
+	_, transformed = truncator.Transform(zapcore.Entry{}, short)
+	assert.Equal(t, short, transformed)
+
+	_, transformed = flogging.FieldValueTruncator{}.Transform(zapcore.Entry{}, fields)
+	assert.Equal(t, original, transformed, "a zero limit disables truncation")
+}
+
+func TestFieldValueTruncatorReport(t *testing.T) {
+	truncator := flogging.FieldValueTruncator{MaxBytes: 3, ReportTruncated: true}
+
+	_, transformed := truncator.Transform(zapcore.Entry{}, []zapcore.Field{zap.String("a", "abcd"), zap.String("b", "ab"), zap.String("c", "abcd")})
+	assert.Equal(t, []zapcore.Field{
+		zap.String("a", "abc…(truncated)"),
+		zap.String("b", "ab"),
+		zap.String("c", "abc…(truncated)"),
+		zap.Strings("truncated_fields", []string{"a", "c"}),
+	}, transformed)
+
+	_, transformed = truncator.Transform(zapcore.Entry{}, []zapcore.Field{zap.String("b", "ab")})
+	assert.Equal(t, []zapcore.Field{zap.String("b", "ab")}, transformed, "nothing is reported when no values are truncated")
+}
+
+func TestTruncatorsAnnotate(t *testing.T) {
+	output := &sw{}
+	core := newTestConsoleCore(t, "info", output)
+	core.Transformers = []flogging.EntryTransformer{
+		flogging.MessageTruncator{MaxBytes: 5, Annotate: true}.Transform,
+		flogging.FieldValueTruncator{MaxBytes: 5, Annotate: true}.Transform,
+	}
+
+	logger := zap.New(core)
+	logger.Info("long message")
+	logger.Info("short", zap.String("key", "long value"))
+	logger.Info("short", zap.String("key", "short"))
+	assert.Equal(t, "[] INFO long ... _processed=[truncated]\n"+
+		"[] INFO short key=\"long …(truncated)\" _processed=[truncated]\n"+
+		"[] INFO short key=short\n",
+		output.String(),
+	)
+}
+
+func TestFieldValueTruncatorCore(t *testing.T) {
+	output := &sw{}
+	core := newTestConsoleCore(t, "info", output)
+	core.Transformers = []flogging.EntryTransformer{
+		flogging.FieldValueTruncator{MaxBytes: 8}.Transform,
+	}
+
+	zap.New(core).Info("a message that is not truncated", zap.String("short", "value"), zap.String("long", "a very long value"))
+	assert.Equal(t, "[] INFO a message that is not truncated short=value long=\"a very l…(truncated)\"\n", output.String())
+}
+
+func TestFieldValueTruncatorWith(t *testing.T) {
+	output := &sw{}
+	core := newTestConsoleCore(t, "info", output)
+	truncator := flogging.FieldValueTruncator{MaxBytes: 8, ReportTruncated: true}
+	core.Transformers = []flogging.EntryTransformer{truncator.Transform}
+	core.WithTransformers = []flogging.FieldsTransformer{truncator.TransformWith}
+
+	fields := []zapcore.Field{zap.String("context", "a very long context"), zap.Error(errors.New("a long error"))}
+	original := append([]zapcore.Field(nil), fields...)
+	logger := zap.New(core).With(fields...).With(zap.String("short", "value"))
+	logger.Info("message", zap.String("long", "a very long value"))
+	assert.Equal(t, "[] INFO message context=\"a very l…(truncated)\" error=\"a long e…(truncated)\" short=value "+
+		"long=\"a very l…(truncated)\" truncated_fields=[long]\n",
+		output.String(),
+	)
+	assert.Equal(t, original, fields, "input fields must not be modified")
+}
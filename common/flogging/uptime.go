@@ -0,0 +1,30 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// UptimeKey is the field key used to report the time elapsed since the
+// process started logging.
+const UptimeKey = "uptime"
+
+// NewUptimeTransformer creates an EntryTransformer that adds an uptime field
+// recording the time between start and the time of each entry.
+func NewUptimeTransformer(start time.Time) EntryTransformer {
+	return func(e zapcore.Entry, fields []zapcore.Field) (zapcore.Entry, []zapcore.Field) {
+		t := e.Time
+		if t.IsZero() {
+			t = time.Now()
+		}
+		return e, append(fields[:len(fields):len(fields)], zap.Duration(UptimeKey, t.Sub(start)))
+	}
+}
@@ -0,0 +1,40 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestUptimeTransformer(t *testing.T) {
+	start := time.Unix(1000, 0)
+	transform := flogging.NewUptimeTransformer(start)
+
+	fields := []zapcore.Field{zap.String("key", "value")}
+	_, transformed := transform(zapcore.Entry{Time: start.Add(1500 * time.Millisecond)}, fields)
+	assert.Equal(t, []zapcore.Field{zap.String("key", "value"), zap.Duration("uptime", 1500*time.Millisecond)}, transformed)
+	assert.Len(t, fields, 1)
+
+	// the current time is used when the entry time is not set
+	_, transformed = transform(zapcore.Entry{}, nil)
+	assert.True(t, time.Duration(transformed[0].Integer) >= time.Since(start)-time.Second)
+
+	output := &sw{}
+	core := newTestConsoleCore(t, "info", output)
+	core.Transformers = []flogging.EntryTransformer{transform}
+	for i := 1; i <= 3; i++ {
+		err := core.Write(zapcore.Entry{Level: zapcore.InfoLevel, Time: start.Add(time.Duration(i) * time.Second)}, nil)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, "[] INFO  uptime=1s\n[] INFO  uptime=2s\n[] INFO  uptime=3s\n", output.String())
+}
@@ -102,4 +102,16 @@ func (f *FabricLogger) WithOptions(opts ...zap.Option) *FabricLogger {
 	return &FabricLogger{s: l.Sugar()}
 }
 
+// Freeze returns a logger with a core that is independent of the logger's
+// core. When the core is a *Core, the logger's fields are materialized as
+// described by Core.Freeze; other cores are derived with With.
+func (f *FabricLogger) Freeze() *FabricLogger {
+	return f.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		if c, ok := core.(*Core); ok {
+			return c.Freeze()
+		}
+		return core.With(nil)
+	}))
+}
+
 func formatArgs(args []interface{}) string { return strings.TrimSuffix(fmt.Sprintln(args...), "\n") }
@@ -336,3 +336,23 @@ func TestEnabledLevelCheck(t *testing.T) {
 	fabricLogger.Info("info message")
 	assert.Equal(t, 1, fakeObserver.CheckCallCount(), "Check should have been called")
 }
+
+func TestFabricLoggerFreeze(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	parent := flogging.NewFabricLogger(zap.New(core)).With("scope", "parent")
+	frozen := parent.Freeze()
+
+	parent.With("parent", "only").Info("from-parent")
+	frozen.With("child", "only").Info("from-child")
+
+	entries := logs.AllUntimed()
+	assert.Len(t, entries, 2)
+	assert.Equal(t, map[string]interface{}{"scope": "parent", "parent": "only"}, entries[0].ContextMap())
+	assert.Equal(t, map[string]interface{}{"scope": "parent", "child": "only"}, entries[1].ContextMap())
+
+	buf := &bytes.Buffer{}
+	logging, err := flogging.New(flogging.Config{Format: "%{message}", Writer: buf})
+	assert.NoError(t, err)
+	logging.Logger("frozen").With("key", "value").Freeze().Info("message")
+	assert.Equal(t, "message key=value\n", buf.String())
+}